@@ -0,0 +1,232 @@
+package difflint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ruleCache caches the directives lexed from each file of a root directory
+// across runs. Entries are invalidated when the file or the template
+// configuration changes.
+type ruleCache struct {
+	// path is the file in which the cache is stored.
+	path string
+
+	// entries are the entries loaded from the cache file.
+	entries map[string]cacheEntry
+
+	// visited are the entries of the files visited in this run, which
+	// replace entries when the cache is saved.
+	visited map[string]cacheEntry
+
+	// hits and misses count the cache lookups.
+	hits, misses int
+}
+
+// cacheEntry is the cached result of lexing a single file.
+type cacheEntry struct {
+	ModTime  int64         `json:"mtime"`
+	Size     int64         `json:"size"`
+	Hash     string        `json:"hash"`
+	Config   string        `json:"config"`
+	Tokens   []cachedToken `json:"tokens,omitempty"`
+	Warnings []Warning     `json:"warnings,omitempty"`
+	Mentions bool          `json:"mentions,omitempty"`
+}
+
+// cachedToken is the serialized form of a token.
+type cachedToken struct {
+	Directive string   `json:"directive"`
+	Args      []string `json:"args,omitempty"`
+	Note      string   `json:"note,omitempty"`
+	Line      int      `json:"line"`
+	NonBlank  int      `json:"non_blank,omitempty"`
+	Symbol    *Range   `json:"symbol,omitempty"`
+}
+
+// loadRuleCache loads the cache of the given root directory from dir. It
+// returns nil if dir is empty, which disables caching. A missing or corrupt
+// cache file is treated as empty.
+func loadRuleCache(dir, root string) *ruleCache {
+	if dir == "" {
+		return nil
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(absRoot))
+	c := &ruleCache{
+		path:    filepath.Join(dir, hex.EncodeToString(sum[:8])+".json"),
+		entries: make(map[string]cacheEntry),
+		visited: make(map[string]cacheEntry),
+	}
+
+	if b, err := os.ReadFile(c.path); err == nil {
+		if err := json.Unmarshal(b, &c.entries); err != nil {
+			c.entries = make(map[string]cacheEntry)
+		}
+	}
+
+	return c
+}
+
+// cacheFormat is the version of the cached tokens, bumped whenever the lexer
+// changes the tokens it produces.
+const cacheFormat = "6"
+
+// cacheConfig returns the key of the configuration with which a file with
+// the given templates is lexed.
+func cacheConfig(templates []string, options LintOptions) string {
+	sum := sha256.Sum256([]byte(cacheFormat + "\x00" + strings.Join(templates, "\x00") + "\x00" +
+		strconv.FormatBool(options.StrictDirectives) + "\x00" +
+		strconv.FormatBool(options.WarnMismatchedTemplates) + "\x00" +
+		options.directiveWord() + "\x00" +
+		strings.Join(options.CommentsOnly, ",")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// contentHash returns the hash of the given file content.
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// lookup returns the cached lexing of the given file if its modification
+// time, size, and configuration are unchanged.
+func (c *ruleCache) lookup(file string, info fs.FileInfo, config string) (lexedFile, bool) {
+	if c == nil {
+		return lexedFile{}, false
+	}
+
+	entry, ok := c.entries[file]
+	if !ok || entry.Config != config || entry.ModTime != info.ModTime().UnixNano() || entry.Size != info.Size() {
+		c.misses++
+		return lexedFile{}, false
+	}
+
+	c.hits++
+	c.visited[file] = entry
+	return entry.lexed(), true
+}
+
+// lookupHash returns the cached lexing of the given file if its content and
+// configuration are unchanged, e.g. after the file was touched.
+func (c *ruleCache) lookupHash(file string, info fs.FileInfo, hash, config string) (lexedFile, bool) {
+	if c == nil {
+		return lexedFile{}, false
+	}
+
+	entry, ok := c.entries[file]
+	if !ok || entry.Config != config || entry.Hash != hash {
+		return lexedFile{}, false
+	}
+
+	c.misses--
+	c.hits++
+	entry.ModTime = info.ModTime().UnixNano()
+	entry.Size = info.Size()
+	c.visited[file] = entry
+	return entry.lexed(), true
+}
+
+// store caches the lexing of the given file.
+func (c *ruleCache) store(file string, info fs.FileInfo, hash, config string, lexed lexedFile) {
+	if c == nil {
+		return
+	}
+
+	entry := cacheEntry{
+		ModTime:  info.ModTime().UnixNano(),
+		Size:     info.Size(),
+		Hash:     hash,
+		Config:   config,
+		Warnings: lexed.warnings,
+		Mentions: lexed.mentions,
+	}
+
+	for _, t := range lexed.tokens {
+		entry.Tokens = append(entry.Tokens, cachedToken{
+			Directive: string(t.directive),
+			Args:      t.args,
+			Note:      t.note,
+			Line:      t.line,
+			NonBlank:  t.lastNonBlank,
+			Symbol:    t.symbol,
+		})
+	}
+
+	c.visited[file] = entry
+}
+
+// save writes the entries of the visited files to the cache file.
+func (c *ruleCache) save() error {
+	if c == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(c.visited)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode rule cache")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create rule cache directory")
+	}
+
+	// Write to a temporary file first so that readers never see a partial
+	// cache.
+	f, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return errors.Wrap(err, "failed to write rule cache")
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return errors.Wrap(err, "failed to write rule cache")
+	}
+
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "failed to write rule cache")
+	}
+
+	return errors.Wrap(os.Rename(f.Name(), c.path), "failed to write rule cache")
+}
+
+// lexed returns the lexing of the entry.
+func (e cacheEntry) lexed() lexedFile {
+	return lexedFile{tokens: e.tokens(), warnings: e.Warnings, mentions: e.Mentions}
+}
+
+// tokens returns the tokens of the entry.
+func (e cacheEntry) tokens() []token {
+	tokens := make([]token, 0, len(e.Tokens))
+	for _, t := range e.Tokens {
+		tokens = append(tokens, token{
+			directive:    directive(t.Directive),
+			args:         t.Args,
+			note:         t.Note,
+			line:         t.Line,
+			lastNonBlank: t.NonBlank,
+			symbol:       t.Symbol,
+		})
+	}
+
+	return tokens
+}
+
+// ClearCache removes the rule cache stored in the given directory.
+func ClearCache(dir string) error {
+	return errors.Wrap(os.RemoveAll(dir), "failed to clear rule cache")
+}
@@ -0,0 +1,61 @@
+package difflint
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLintCaseInsensitivePaths(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":      "package a\n//LINT.IF ./Readme.md\nvar X = 1\n//LINT.END\n",
+		"b.go":      "package b\n//LINT.IF Docs/*.md\nvar Y = 1\n//LINT.END\n",
+		"README.md": "# Readme\n",
+		"docs/x.md": "# X\n",
+	})
+
+	const diff = "diff --git a/README.md b/README.md\n--- a/README.md\n+++ b/README.md\n@@ -1,1 +1,1 @@\n-# Read me\n+# Readme\n" +
+		"diff --git a/docs/x.md b/docs/x.md\n--- a/docs/x.md\n+++ b/docs/x.md\n@@ -1,1 +1,1 @@\n-# Y\n+# X\n"
+
+	tests := []struct {
+		name            string
+		caseInsensitive bool
+		want            []string
+		wantWarnings    int
+	}{
+		{name: "case-sensitive"},
+		{name: "case-insensitive", caseInsensitive: true, want: []string{"a.go", "b.go"}, wantWarnings: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Lint(context.Background(), LintOptions{
+				Root:                 root,
+				Reader:               strings.NewReader(diff),
+				Templates:            DefaultTemplates,
+				FileExtMap:           DefaultFileExtMap,
+				CaseInsensitivePaths: test.caseInsensitive,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, rule := range result.UnsatisfiedRules {
+				got = append(got, rule.Hunk.File)
+			}
+
+			if strings.Join(got, " ") != strings.Join(test.want, " ") {
+				t.Errorf("Lint() unsatisfied rules of %v, want %v", got, test.want)
+			}
+
+			if len(result.Warnings) != test.wantWarnings {
+				t.Fatalf("Lint() warnings = %v, want %d", result.Warnings, test.wantWarnings)
+			}
+
+			if test.wantWarnings > 0 && !strings.Contains(result.Warnings[0].Message, `target "./Readme.md" matches "README.md" only when ignoring case`) {
+				t.Errorf("Lint() warning = %q, want the case of ./Readme.md", result.Warnings[0].Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+// formatBitbucket is the --format value for Bitbucket Code Insights output.
+const formatBitbucket = "bitbucket"
+
+const (
+	// bitbucketMaxAnnotations is the maximum number of annotations that
+	// Bitbucket accepts per report.
+	bitbucketMaxAnnotations = 1000
+
+	// bitbucketAnnotationBatch is the maximum number of annotations that
+	// Bitbucket accepts per request.
+	bitbucketAnnotationBatch = 100
+
+	// bitbucketMaxSummary is the maximum length of an annotation's summary.
+	bitbucketMaxSummary = 450
+)
+
+// bitbucketOutput is a Bitbucket Code Insights report along with its
+// annotations.
+// See https://developer.atlassian.com/cloud/bitbucket/rest/api-group-reports/.
+type bitbucketOutput struct {
+	Report      bitbucketReport       `json:"report"`
+	Annotations []bitbucketAnnotation `json:"annotations"`
+}
+
+// bitbucketReport is the summary of a lint attached to a commit.
+type bitbucketReport struct {
+	Title      string               `json:"title"`
+	Details    string               `json:"details"`
+	ReportType string               `json:"report_type"`
+	Reporter   string               `json:"reporter"`
+	Result     string               `json:"result"`
+	Data       []bitbucketDataPoint `json:"data"`
+}
+
+// bitbucketDataPoint is a count shown on a report.
+type bitbucketDataPoint struct {
+	Title string `json:"title"`
+	Type  string `json:"type"`
+	Value int    `json:"value"`
+}
+
+// bitbucketAnnotation is a finding attached to a line of a report's commit.
+type bitbucketAnnotation struct {
+	ExternalID     string `json:"external_id"`
+	AnnotationType string `json:"annotation_type"`
+	Summary        string `json:"summary"`
+	Path           string `json:"path"`
+	Line           int    `json:"line,omitempty"`
+	Severity       string `json:"severity"`
+}
+
+// newBitbucketOutput returns the report of the unsatisfied, expired, and
+// empty rules among the given findings, failed if the lint failed. Annotations
+// beyond Bitbucket's limit are dropped and counted in the report details.
+func newBitbucketOutput(findings []difflint.Finding, failed bool) bitbucketOutput {
+	out := bitbucketOutput{
+		Report: bitbucketReport{
+			Title:      "difflint",
+			ReportType: "BUG",
+			Reporter:   "difflint",
+			Result:     "PASSED",
+		},
+		Annotations: []bitbucketAnnotation{},
+	}
+
+	if failed {
+		out.Report.Result = "FAILED"
+	}
+
+	var unsatisfied, expired, empty int
+	for _, f := range findings {
+		switch f.Kind {
+		case difflint.FindingUnsatisfied:
+			unsatisfied++
+		case difflint.FindingExpired:
+			expired++
+		case difflint.FindingEmpty:
+			empty++
+		default:
+			continue
+		}
+
+		summary := diagnosticMessage(f)
+		if len(summary) > bitbucketMaxSummary {
+			summary = summary[:bitbucketMaxSummary-3] + "..."
+		}
+
+		a := bitbucketAnnotation{
+			ExternalID:     fmt.Sprintf("difflint-%d", unsatisfied+expired+empty),
+			AnnotationType: "BUG",
+			Summary:        summary,
+			Path:           f.RuleFile,
+			Severity:       bitbucketSeverity(f.Severity),
+		}
+
+		if !f.Config {
+			a.Line = f.StartLine
+		}
+
+		out.Annotations = append(out.Annotations, a)
+	}
+
+	out.Report.Details = fmt.Sprintf("%d unsatisfied rules, %d expired rules, %d empty rules", unsatisfied, expired, empty)
+	if n := len(out.Annotations); n > bitbucketMaxAnnotations {
+		out.Annotations = out.Annotations[:bitbucketMaxAnnotations]
+		out.Report.Details += fmt.Sprintf("; only the first %d of %d annotations are attached", bitbucketMaxAnnotations, n)
+	}
+
+	out.Report.Data = []bitbucketDataPoint{
+		{Title: "Unsatisfied rules", Type: "NUMBER", Value: unsatisfied},
+		{Title: "Expired rules", Type: "NUMBER", Value: expired},
+		{Title: "Empty rules", Type: "NUMBER", Value: empty},
+	}
+
+	return out
+}
+
+// renderBitbucket writes the report of the given findings to w as JSON.
+func renderBitbucket(w io.Writer, findings []difflint.Finding, failed bool) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newBitbucketOutput(findings, failed))
+}
+
+// bitbucketSeverity returns the Bitbucket severity of the given severity.
+func bitbucketSeverity(severity difflint.Severity) string {
+	switch severity {
+	case difflint.SeverityError:
+		return "HIGH"
+	case difflint.SeverityWarn:
+		return "MEDIUM"
+	default:
+		return "LOW"
+	}
+}
+
+// bitbucketUploader uploads reports to the Bitbucket Cloud API.
+type bitbucketUploader struct {
+	// baseURL is the base URL of the API, e.g. https://api.bitbucket.org/2.0.
+	baseURL string
+
+	// repo is the "workspace/slug" of the repository.
+	repo string
+
+	// token is the bearer token of the requests.
+	token string
+
+	// client sends the requests.
+	client *http.Client
+}
+
+// upload replaces the report with the given ID on the given commit with out
+// and attaches its annotations in batches.
+func (u bitbucketUploader) upload(ctx context.Context, commit, reportID string, out bitbucketOutput) error {
+	reportURL := fmt.Sprintf("%s/repositories/%s/commit/%s/reports/%s",
+		strings.TrimSuffix(u.baseURL, "/"), u.repo, url.PathEscape(commit), url.PathEscape(reportID))
+	if err := u.send(ctx, http.MethodPut, reportURL, out.Report); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(out.Annotations); start += bitbucketAnnotationBatch {
+		end := start + bitbucketAnnotationBatch
+		if end > len(out.Annotations) {
+			end = len(out.Annotations)
+		}
+
+		if err := u.send(ctx, http.MethodPost, reportURL+"/annotations", out.Annotations[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// send sends v as JSON to the given URL and returns an error unless the
+// response is successful.
+func (u bitbucketUploader) send(ctx context.Context, method, url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode report")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create request for %s", url)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if u.token != "" {
+		req.Header.Set("Authorization", "Bearer "+u.token)
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to %s %s", method, url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("failed to %s %s: unexpected status %s: %s", method, url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// newReportCommand returns the report subcommand, which uploads the lint
+// results to code review tools.
+func newReportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "report",
+		Usage: "lint the diff and upload the results to a code review tool",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "bitbucket",
+				Usage:     "attach the results to a commit as a Bitbucket Code Insights report",
+				ArgsUsage: "[patch...]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "repo",
+						Usage:    "repository as workspace/slug",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "commit",
+						Usage:    "commit to attach the report to",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "url",
+						Usage: "base URL of the Bitbucket API",
+						Value: "https://api.bitbucket.org/2.0",
+					},
+					&cli.StringFlag{
+						Name:    "token",
+						Usage:   "access token with the repository:write scope",
+						EnvVars: []string{"BITBUCKET_TOKEN"},
+					},
+					&cli.StringFlag{
+						Name:  "report-id",
+						Usage: "ID of the report, replaced on each upload",
+						Value: "difflint",
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					l, err := newLinter(ctx)
+					if err != nil {
+						return err
+					}
+
+					r, err := diffReader(ctx, l.logger)
+					if err != nil {
+						return err
+					}
+
+					options := l.options
+					options.Reader = r
+					result, err := difflint.DoWith(ctx.Context, options)
+					if err != nil {
+						return err
+					}
+
+					for _, warning := range result.Warnings {
+						fmt.Fprintf(l.stderr, "warning: %s\n", warning)
+					}
+
+					findings := difflint.BuildFindings(result)
+					l.codeOwners.Annotate(findings)
+					out := newBitbucketOutput(findings, l.blocks(result))
+					u := bitbucketUploader{
+						baseURL: ctx.String("url"),
+						repo:    ctx.String("repo"),
+						token:   ctx.String("token"),
+						client:  &http.Client{Timeout: 30 * time.Second},
+					}
+
+					if err := u.upload(ctx.Context, ctx.String("commit"), ctx.String("report-id"), out); err != nil {
+						return err
+					}
+
+					fmt.Fprintf(l.stderr, "difflint: attached report %q with %d annotations to %s\n", ctx.String("report-id"), len(out.Annotations), ctx.String("commit"))
+					if l.blocks(result) {
+						return cli.Exit("", 1)
+					}
+
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/urfave/cli/v2"
+)
+
+// bitbucketServer records the reports and annotations uploaded to it.
+type bitbucketServer struct {
+	mu          sync.Mutex
+	reports     []bitbucketReport
+	batches     [][]bitbucketAnnotation
+	requestURLs []string
+}
+
+func (s *bitbucketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer s3cret" {
+		http.Error(w, `{"error":{"message":"Unauthorized"}}`, http.StatusUnauthorized)
+		return
+	}
+
+	s.requestURLs = append(s.requestURLs, r.Method+" "+r.URL.Path)
+	switch {
+	case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/reports/difflint"):
+		var report bitbucketReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.reports = append(s.reports, report)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/reports/difflint/annotations"):
+		var batch []bitbucketAnnotation
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.batches = append(s.batches, batch)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestReportBitbucket(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.go": "package a\n\n//LINT.IF b.go\nvar X = 1\n//LINT.END\n",
+		"b.go": "package b\n\nvar Y = 2\n",
+	})
+	diff := "diff --git a/b.go b/b.go\n--- a/b.go\n+++ b/b.go\n@@ -1,3 +1,3 @@\n package b\n \n-var Y = 1\n+var Y = 2\n"
+
+	s := &bitbucketServer{}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	_, stderr, err := runApp(t, strings.NewReader(diff), "--root", dir, "--no-cache",
+		"report", "bitbucket", "--repo", "ws/repo", "--commit", "abc123", "--url", server.URL, "--token", "s3cret")
+	if exit, ok := err.(cli.ExitCoder); !ok || exit.ExitCode() != 1 {
+		t.Fatalf("report bitbucket error = %v, want exit status 1", err)
+	}
+
+	want := []string{
+		"PUT /repositories/ws/repo/commit/abc123/reports/difflint",
+		"POST /repositories/ws/repo/commit/abc123/reports/difflint/annotations",
+	}
+	if strings.Join(s.requestURLs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests = %v, want %v", s.requestURLs, want)
+	}
+
+	if len(s.reports) != 1 || s.reports[0].Result != "FAILED" {
+		t.Errorf("reports = %+v, want one failed report", s.reports)
+	}
+
+	if len(s.batches) != 1 || len(s.batches[0]) != 1 || s.batches[0][0].Path != "a.go" || s.batches[0][0].Line != 3 {
+		t.Errorf("annotations = %+v, want one of a.go:3", s.batches)
+	}
+
+	if !strings.Contains(stderr, `difflint: attached report "difflint" with 1 annotations to abc123`) {
+		t.Errorf("stderr = %q, want the attached report", stderr)
+	}
+}
+
+func TestBitbucketUploadTruncated(t *testing.T) {
+	var findings []difflint.Finding
+	for i := 0; i < bitbucketMaxAnnotations+50; i++ {
+		findings = append(findings, difflint.Finding{
+			Kind:      difflint.FindingUnsatisfied,
+			RuleFile:  fmt.Sprintf("f%d.go", i),
+			StartLine: 1,
+			EndLine:   2,
+			Severity:  difflint.SeverityError,
+		})
+	}
+
+	out := newBitbucketOutput(findings, true)
+	if !strings.HasSuffix(out.Report.Details, "; only the first 1000 of 1050 annotations are attached") {
+		t.Errorf("report details = %q, want the truncation note", out.Report.Details)
+	}
+
+	s := &bitbucketServer{}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	u := bitbucketUploader{baseURL: server.URL + "/", repo: "ws/repo", token: "s3cret", client: server.Client()}
+	if err := u.upload(context.Background(), "abc123", "difflint", out); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.reports) != 1 || s.reports[0].Details != out.Report.Details {
+		t.Errorf("reports = %+v, want the report with the truncation note", s.reports)
+	}
+
+	var total int
+	for _, batch := range s.batches {
+		if len(batch) > bitbucketAnnotationBatch {
+			t.Errorf("batch of %d annotations, want at most %d", len(batch), bitbucketAnnotationBatch)
+		}
+
+		total += len(batch)
+	}
+
+	if len(s.batches) != 10 || total != bitbucketMaxAnnotations {
+		t.Errorf("uploaded %d annotations in %d batches, want %d in 10", total, len(s.batches), bitbucketMaxAnnotations)
+	}
+
+	u.token = "wrong"
+	err := u.upload(context.Background(), "abc123", "difflint", out)
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("upload() with a wrong token error = %v, want the status and message", err)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/urfave/cli/v2"
+)
+
+// defaultCacheDir returns the default directory of the rule cache, or an
+// empty string if the user cache directory is unknown.
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "difflint")
+}
+
+// cacheDir returns the rule cache directory given on the command line, or an
+// empty string if caching is disabled.
+func cacheDir(ctx *cli.Context) string {
+	if ctx.Bool("no-cache") {
+		return ""
+	}
+
+	if dir := ctx.String("cache-dir"); dir != "" {
+		return dir
+	}
+
+	return defaultCacheDir()
+}
+
+// newCacheCommand returns the cache subcommand.
+func newCacheCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "cache",
+		Usage: "manage the rule cache",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "clear",
+				Usage: "remove the rule cache",
+				Action: func(ctx *cli.Context) error {
+					dir := ctx.String("cache-dir")
+					if dir == "" {
+						dir = defaultCacheDir()
+					}
+
+					if dir == "" {
+						return fmt.Errorf("no cache directory; use --cache-dir")
+					}
+
+					return difflint.ClearCache(dir)
+				},
+			},
+		},
+	}
+}
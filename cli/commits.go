@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/urfave/cli/v2"
+)
+
+// lintCommits lints each commit in the given revision range separately,
+// parsing the rules from the tree as of that commit, and labels the results
+// with the commit hash and subject.
+func (l *linter) lintCommits(ctx context.Context, revisionRange string) error {
+	commits, err := difflint.GitCommits(ctx, l.options.Root, revisionRange)
+	if err != nil {
+		return err
+	}
+
+	l.collectCommits()
+	var exitErr error
+	for _, commit := range commits {
+		err := l.lintCommit(ctx, commit)
+		if _, ok := err.(cli.ExitCoder); ok {
+			exitErr = err
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := l.renderCommits(); err != nil {
+		return err
+	}
+
+	return exitErr
+}
+
+// lintCommit lints the diff of the given commit against the tree of the
+// commit, which is extracted into a temporary directory. The rules listed in
+// the DIFFLINT-SKIP trailers of the commit message are skipped in addition
+// to those of --skip-rule.
+func (l *linter) lintCommit(ctx context.Context, commit difflint.GitCommit) error {
+	r, err := difflint.GitCommitDiff(ctx, l.options.Root, commit.SHA)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "difflint-"+commit.SHA[:12]+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := difflint.GitArchive(ctx, l.options.Root, commit.SHA, dir); err != nil {
+		return err
+	}
+
+	cl := *l
+	cl.options.Root = dir
+	if trailers := difflint.ParseSkipTrailers(commit.Message); len(trailers) > 0 {
+		l.logger.Printf("commit %s skips rules %s by trailer", commit.SHA[:12], strings.Join(trailers, ","))
+		cl.options.SkipRules = append(append([]string(nil), l.options.SkipRules...), trailers...)
+	}
+	return cl.lint(ctx, r, &commit)
+}
+
+// commitFindings are the findings of one commit of a lint by commit.
+type commitFindings struct {
+	// commit is the linted commit.
+	commit difflint.GitCommit
+
+	// findings are the findings of the commit, the first of total findings.
+	findings []difflint.Finding
+
+	// total is the number of findings before truncation.
+	total int
+
+	// comparison is the comparison of the commit's result to the previous
+	// run, if any.
+	comparison *difflint.Comparison
+
+	// failed is true if the commit fails the lint.
+	failed bool
+}
+
+// commitLabel returns the hash and subject of the given commit.
+func commitLabel(commit difflint.GitCommit) string {
+	if commit.Subject == "" {
+		return commit.SHA
+	}
+
+	return commit.SHA + " " + commit.Subject
+}
+
+// shortSHA returns the abbreviated hash of the given commit.
+func shortSHA(commit difflint.GitCommit) string {
+	if len(commit.SHA) > 12 {
+		return commit.SHA[:12]
+	}
+
+	return commit.SHA
+}
+
+// collectCommits makes the structured formats collect the findings of each
+// commit, to be printed as one document by renderCommits. The text format
+// prints each commit as it is linted.
+func (l *linter) collectCommits() {
+	if l.format != formatText {
+		l.commits = &[]commitFindings{}
+	}
+}
+
+// renderCommits writes the collected findings of every commit to standard
+// output as one document in the configured format.
+func (l *linter) renderCommits() error {
+	if l.commits == nil {
+		return nil
+	}
+
+	commits := *l.commits
+	switch l.format {
+	case formatJSON:
+		if err := renderJSONCommits(l.stdout, commits); err != nil {
+			return err
+		}
+
+		// JSON records the total of each commit itself.
+		return nil
+	case formatJUnit:
+		if err := renderJUnitCommits(l.stdout, commits); err != nil {
+			return err
+		}
+	case formatRDJSON:
+		if err := renderRDJSONCommits(l.stdout, commits); err != nil {
+			return err
+		}
+	case formatMarkdown:
+		for i, c := range commits {
+			if i > 0 {
+				fmt.Fprintln(l.stdout)
+			}
+
+			fmt.Fprintf(l.stdout, "#### %s\n\n", markdownEscape(commitLabel(c.commit)))
+			if err := l.markdown.render(l.stdout, c.findings, c.comparison); err != nil {
+				return err
+			}
+
+			if truncated := c.total - len(c.findings); truncated > 0 {
+				fmt.Fprintf(l.stdout, "\u2026 and %d more findings truncated\n", truncated)
+			}
+		}
+
+		return nil
+	case formatBitbucket:
+		// A report belongs to a single commit, so it lists the findings of
+		// every commit and fails if any of them does.
+		var findings []difflint.Finding
+		var failed bool
+		for _, c := range commits {
+			findings = append(findings, c.findings...)
+			failed = failed || c.failed
+		}
+
+		if err := renderBitbucket(l.stdout, findings, failed); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range commits {
+		if truncated := c.total - len(c.findings); truncated > 0 {
+			fmt.Fprintf(l.stderr, "\u2026 and %d more findings truncated in commit %s\n", truncated, shortSHA(c.commit))
+		}
+	}
+
+	return nil
+}
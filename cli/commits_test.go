@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethanthatonekid/difflint"
+)
+
+// goldenCommits returns the findings of two commits, the first without
+// findings and the second with every kind of finding.
+func goldenCommits() []commitFindings {
+	findings := goldenFindings()
+	return []commitFindings{
+		{commit: difflint.GitCommit{SHA: "1111111111111111111111111111111111111111", Subject: "Reformat"}},
+		{commit: difflint.GitCommit{SHA: "2222222222222222222222222222222222222222", Subject: "Change the schema"}, findings: findings, total: len(findings) + 2, failed: true},
+	}
+}
+
+func TestRenderJSONCommits(t *testing.T) {
+	var b strings.Builder
+	if err := renderJSONCommits(&b, goldenCommits()); err != nil {
+		t.Fatal(err)
+	}
+
+	var out jsonCommitsResult
+	decoder := json.NewDecoder(strings.NewReader(b.String()))
+	if err := decoder.Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoder.More() {
+		t.Fatalf("renderJSONCommits() wrote more than one document:\n%s", b.String())
+	}
+
+	if len(out.Commits) != 2 {
+		t.Fatalf("renderJSONCommits() = %d commits, want 2", len(out.Commits))
+	}
+
+	first, second := out.Commits[0], out.Commits[1]
+	if first.SHA != "1111111111111111111111111111111111111111" || first.Subject != "Reformat" || first.Findings == nil || len(first.Findings) != 0 {
+		t.Errorf("renderJSONCommits() first commit = %+v, want no findings", first)
+	}
+
+	if second.Subject != "Change the schema" || len(second.Findings) != 5 || second.Summary.Total != 7 || second.Summary.Truncated != 2 {
+		t.Errorf("renderJSONCommits() second commit = %+v, want 5 of 7 findings", second)
+	}
+}
+
+func TestRenderJUnitCommits(t *testing.T) {
+	var b strings.Builder
+	if err := renderJUnitCommits(&b, goldenCommits()); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "testdata/junit_commits.golden", b.String())
+}
+
+func TestRenderRDJSONCommits(t *testing.T) {
+	var b strings.Builder
+	if err := renderRDJSONCommits(&b, goldenCommits()); err != nil {
+		t.Fatal(err)
+	}
+
+	var out rdjsonResult
+	if err := json.Unmarshal([]byte(b.String()), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(out.Diagnostics) != 4 {
+		t.Fatalf("renderRDJSONCommits() = %d diagnostics, want 4", len(out.Diagnostics))
+	}
+
+	for _, d := range out.Diagnostics {
+		if !strings.HasPrefix(d.Message, "222222222222: ") {
+			t.Errorf("renderRDJSONCommits() message = %q, want the commit first", d.Message)
+		}
+	}
+}
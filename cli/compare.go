@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethanthatonekid/difflint"
+)
+
+// previousOutput is a previous JSON output read by --compare-to, grouped by
+// owner or not.
+type previousOutput struct {
+	Difflint *jsonMeta          `json:"difflint"`
+	Findings []difflint.Finding `json:"findings"`
+	Owners   []jsonOwnerGroup   `json:"owners"`
+}
+
+// loadPreviousFindings returns the findings of the JSON output in the given
+// file, as written with --format=json.
+func loadPreviousFindings(path string) ([]difflint.Finding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var out previousOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if out.Difflint == nil {
+		return nil, fmt.Errorf("%s is not a difflint JSON output; write one with --format=%s", path, formatJSON)
+	}
+
+	if out.Difflint.Schema > jsonSchemaVersion {
+		return nil, fmt.Errorf("%s has schema %d, but this version of difflint reads up to schema %d", path, out.Difflint.Schema, jsonSchemaVersion)
+	}
+
+	findings := out.Findings
+	for _, group := range out.Owners {
+		findings = append(findings, group.Findings...)
+	}
+
+	return findings, nil
+}
+
+// comparisonSummary returns the counts of the given comparison to the
+// previous output in the given file, for the summary line.
+func comparisonSummary(c *difflint.Comparison, path string) string {
+	return fmt.Sprintf("compared to %s: %d new, %d fixed, %d unchanged", path, c.New, len(c.Fixed), c.Unchanged)
+}
+
+// writeFixed writes the fixed findings of the given comparison to b, one
+// rule per line.
+func (r renderer) writeFixed(b *strings.Builder, c *difflint.Comparison) {
+	if c == nil || len(c.Fixed) == 0 {
+		return
+	}
+
+	b.WriteString(r.paint(ansiGreen, "fixed"))
+	b.WriteString(" since the previous run:\n")
+	for _, f := range c.Fixed {
+		b.WriteString("  ")
+		r.writeRule(b, f)
+		fmt.Fprintf(b, ": %s\n", f.Message)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/urfave/cli/v2"
+)
+
+// newDirsCommand returns the dirs subcommand.
+func newDirsCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "dirs",
+		Usage:     "lint the changes from one directory to another without git, parsing rules from the new directory",
+		ArgsUsage: "OLD NEW",
+		Action:    dirsAction,
+	}
+}
+
+func dirsAction(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("expected two directories, got %d arguments", ctx.NArg())
+	}
+
+	oldDir, newDir := ctx.Args().Get(0), ctx.Args().Get(1)
+	l, err := newLinter(ctx)
+	if err != nil {
+		return err
+	}
+
+	r, err := difflint.DiffDirs(oldDir, newDir)
+	if err != nil {
+		return err
+	}
+
+	l.options.Root = newDir
+	return l.lint(ctx.Context, r, nil)
+}
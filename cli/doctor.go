@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/urfave/cli/v2"
+)
+
+// doctorStatus is the outcome of a doctor check.
+type doctorStatus string
+
+const (
+	doctorPass doctorStatus = "pass"
+	doctorWarn doctorStatus = "warn"
+	doctorFail doctorStatus = "fail"
+)
+
+// doctorResult is the result of a doctor check.
+type doctorResult struct {
+	// status is the outcome of the check.
+	status doctorStatus
+
+	// message describes what the check found.
+	message string
+
+	// hint suggests how to fix a warning or failure, if any.
+	hint string
+}
+
+// doctorCheck diagnoses one kind of misconfiguration.
+type doctorCheck struct {
+	// name is the name shown with the check's result.
+	name string
+
+	// run runs the check.
+	run func(ctx context.Context, env *doctorEnv) doctorResult
+}
+
+// doctorChecks are the checks run by the doctor subcommand, in order.
+var doctorChecks = []doctorCheck{
+	{name: "git repository", run: checkGitRepository},
+	{name: "extension map", run: checkExtMap},
+	{name: "diff", run: checkDiff},
+	{name: "directives", run: checkDirectives},
+	{name: "diff paths", run: checkDiffPaths},
+}
+
+// doctorEnv is the configuration under diagnosis, shared by the checks.
+type doctorEnv struct {
+	// options are the options that difflint would lint with.
+	options difflint.DoOptions
+
+	// hunks are the hunks of git diff HEAD, read once by diffHunks.
+	hunks []difflint.Hunk
+
+	// diffErr is the error of reading or parsing diff, if any.
+	diffErr error
+
+	// diffRead is true once diffHunks has run.
+	diffRead bool
+}
+
+// diffHunks returns the hunks of git diff HEAD in the root, which are read
+// on first use.
+func (env *doctorEnv) diffHunks(ctx context.Context) ([]difflint.Hunk, error) {
+	if env.diffRead {
+		return env.hunks, env.diffErr
+	}
+
+	env.diffRead = true
+	r, err := difflint.GitDiff(ctx, env.options.Root, "HEAD")
+	if err != nil {
+		env.diffErr = err
+		return nil, err
+	}
+
+	r, err = difflint.NormalizeDiff(r, env.options.VCS)
+	if err != nil {
+		env.diffErr = err
+		return nil, err
+	}
+
+	env.hunks, env.diffErr = difflint.ParseHunks(r, nil, nil, env.options.StripPrefixes, env.options.MaxHunkLines, nil)
+	return env.hunks, env.diffErr
+}
+
+// newDoctorCommand returns the doctor subcommand.
+func newDoctorCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "doctor",
+		Usage:  "diagnose common setup problems, such as running from the wrong directory or templates that match no directives",
+		Action: doctorAction,
+	}
+}
+
+func doctorAction(ctx *cli.Context) error {
+	l, err := newLinter(ctx)
+	if err != nil {
+		return err
+	}
+
+	env := &doctorEnv{options: l.options}
+	var failed bool
+	for _, check := range doctorChecks {
+		result := check.run(ctx.Context, env)
+		fmt.Fprintf(ctx.App.Writer, "%s  %s: %s\n", result.status, check.name, result.message)
+		if result.hint != "" {
+			fmt.Fprintf(ctx.App.Writer, "      hint: %s\n", result.hint)
+		}
+
+		failed = failed || result.status == doctorFail
+	}
+
+	if failed {
+		return cli.Exit("", 1)
+	}
+
+	return nil
+}
+
+// checkGitRepository checks that the root is the top of a git repository,
+// to which the paths of git diffs are relative.
+func checkGitRepository(ctx context.Context, env *doctorEnv) doctorResult {
+	gitRoot, err := difflint.GitRoot()
+	if err != nil {
+		return doctorResult{
+			status:  doctorWarn,
+			message: "not inside a git repository",
+			hint:    "run difflint from inside the repository whose diffs it lints, or pass --root",
+		}
+	}
+
+	if !samePath(gitRoot, env.options.Root) {
+		return doctorResult{
+			status:  doctorWarn,
+			message: fmt.Sprintf("root %s is not the top of the git repository %s", env.options.Root, gitRoot),
+			hint:    "git diff paths are relative to the top of the repository; omit --root or pass --strip-prefix",
+		}
+	}
+
+	return doctorResult{status: doctorPass, message: gitRoot}
+}
+
+// samePath returns true if the given paths name the same directory.
+func samePath(a, b string) bool {
+	resolve := func(p string) string {
+		if abs, err := filepath.Abs(p); err == nil {
+			p = abs
+		}
+
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			p = real
+		}
+
+		return p
+	}
+
+	return resolve(a) == resolve(b)
+}
+
+// checkExtMap checks that the extension map loads.
+func checkExtMap(ctx context.Context, env *doctorEnv) doctorResult {
+	word := env.options.DirectiveWord
+	if word == "" {
+		word = difflint.DefaultDirectiveWord
+	}
+
+	extMap, err := difflint.NewExtMapWithWord(env.options.ExtMapPath, word)
+	if err != nil {
+		return doctorResult{
+			status:  doctorFail,
+			message: err.Error(),
+			hint:    "fix the file passed with --ext_map; see \"Custom file extensions\" in the README",
+		}
+	}
+
+	source := "default templates"
+	if env.options.ExtMapPath != "" {
+		source = env.options.ExtMapPath
+	}
+
+	return doctorResult{
+		status:  doctorPass,
+		message: fmt.Sprintf("%s: %d templates for %d file extensions", source, len(extMap.Templates), len(extMap.FileExtMap)),
+	}
+}
+
+// checkDiff checks that the diff of the working tree against HEAD parses.
+func checkDiff(ctx context.Context, env *doctorEnv) doctorResult {
+	hunks, err := env.diffHunks(ctx)
+	if err != nil {
+		return doctorResult{
+			status:  doctorWarn,
+			message: fmt.Sprintf("git diff HEAD could not be read or parsed: %v", err),
+			hint:    "make sure git is installed and the repository has a commit, or check --vcs",
+		}
+	}
+
+	files := make(map[string]struct{}, len(hunks))
+	for _, hunk := range hunks {
+		files[hunk.File] = struct{}{}
+	}
+
+	return doctorResult{
+		status:  doctorPass,
+		message: fmt.Sprintf("git diff HEAD has %d hunks in %d files", len(hunks), len(files)),
+	}
+}
+
+// doctorMaxExamples is the maximum number of files named by a check.
+const doctorMaxExamples = 3
+
+// checkDirectives checks that the files that mention the directive word,
+// e.g. "LINT.", produce rules, which they do not if their templates do not
+// match the comment style of the file.
+func checkDirectives(ctx context.Context, env *doctorEnv) doctorResult {
+	word := env.options.DirectiveWord
+	if word == "" {
+		word = difflint.DefaultDirectiveWord
+	}
+
+	needle := []byte(word + ".")
+	fsys := os.DirFS(env.options.Root)
+	var mentions []string
+	err := difflint.WalkFS(ctx, fsys, env.options.Include, env.options.Exclude, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info, err := d.Info(); err != nil || !info.Mode().IsRegular() || info.Size() > difflint.DefaultMaxFileSize {
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+
+		if bytes.Contains(content, needle) {
+			mentions = append(mentions, file)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return doctorResult{status: doctorFail, message: err.Error()}
+	}
+
+	rulesMap, err := difflint.RulesWith(ctx, env.options)
+	if err != nil {
+		return doctorResult{
+			status:  doctorFail,
+			message: err.Error(),
+			hint:    "fix the directive named in the error",
+		}
+	}
+
+	var silent []string
+	for _, file := range mentions {
+		if len(rulesMap.Rules[file]) == 0 {
+			silent = append(silent, file)
+		}
+	}
+
+	message := fmt.Sprintf("%d files mention %s, %d files have rules", len(mentions), needle, len(rulesMap.Rules))
+	if len(silent) == 0 {
+		return doctorResult{status: doctorPass, message: message}
+	}
+
+	sort.Strings(silent)
+	return doctorResult{
+		status:  doctorWarn,
+		message: fmt.Sprintf("%s; %d files mention %s without rules, e.g. %s", message, len(silent), needle, examples(silent)),
+		hint:    "if these files hold directives, add templates for their comment style with --ext_map, or run with --warn-mismatched-templates",
+	}
+}
+
+// checkDiffPaths checks that the changed files of the diff exist under the
+// root, which they do not if the root or the stripped prefixes are wrong.
+func checkDiffPaths(ctx context.Context, env *doctorEnv) doctorResult {
+	hunks, err := env.diffHunks(ctx)
+	if err != nil {
+		return doctorResult{status: doctorWarn, message: "skipped, since the diff could not be read"}
+	}
+
+	fsys := os.DirFS(env.options.Root)
+	seen := make(map[string]struct{}, len(hunks))
+	var missing []string
+	for _, hunk := range hunks {
+		file := difflint.NormalizeKey(hunk.File)
+		if _, ok := seen[file]; ok || hunk.Deleted {
+			continue
+		}
+
+		seen[file] = struct{}{}
+		if _, err := fs.Stat(fsys, file); err != nil {
+			missing = append(missing, file)
+		}
+	}
+
+	if len(missing) == 0 {
+		return doctorResult{status: doctorPass, message: fmt.Sprintf("%d changed files exist under %s", len(seen), env.options.Root)}
+	}
+
+	sort.Strings(missing)
+	return doctorResult{
+		status:  doctorFail,
+		message: fmt.Sprintf("%d of %d changed files do not exist under %s, e.g. %s", len(missing), len(seen), env.options.Root, examples(missing)),
+		hint:    "run difflint from the repository root, pass --root, or check --strip-prefix",
+	}
+}
+
+// examples returns the first few of the given files, joined by commas.
+func examples(files []string) string {
+	if len(files) > doctorMaxExamples {
+		return strings.Join(files[:doctorMaxExamples], ", ") + ", ..."
+	}
+
+	return strings.Join(files, ", ")
+}
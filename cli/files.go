@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// newFilesCommand returns the files subcommand.
+func newFilesCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "files",
+		Usage:     "lint the given files, relative to the root, as if every line changed",
+		ArgsUsage: "[file...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "files-from",
+				Usage: "read newline-separated file names from the given file, or - for standard input",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			files := ctx.Args().Slice()
+			if from := ctx.String("files-from"); from != "" {
+				listed, err := readFileList(ctx.App.Reader, from)
+				if err != nil {
+					return err
+				}
+
+				files = append(files, listed...)
+			}
+
+			if len(files) == 0 {
+				return cli.Exit("difflint: no files given; pass file names or --files-from", 2)
+			}
+
+			l, err := newLinter(ctx)
+			if err != nil {
+				return err
+			}
+
+			l.options.Files = files
+			return l.lint(ctx.Context, nil, nil)
+		},
+	}
+}
+
+// readFileList reads the non-empty lines of the given file, or of stdin if
+// it is "-".
+func readFileList(stdin io.Reader, name string) ([]string, error) {
+	r := stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	var files []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if file := strings.TrimSpace(scanner.Text()); file != "" {
+			files = append(files, file)
+		}
+	}
+
+	return files, scanner.Err()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/pkg/errors"
+)
+
+// githubCommenter posts lint results as comments on a GitHub pull request.
+type githubCommenter struct {
+	// baseURL is the base URL of the GitHub API.
+	baseURL string
+
+	// pr is the pull request to comment on.
+	pr difflint.GitHubPullRequest
+
+	// token is the bearer token of the requests.
+	token string
+
+	// client sends the requests.
+	client *http.Client
+}
+
+// post posts the given Markdown as a comment on the pull request.
+func (c githubCommenter) post(ctx context.Context, markdown string) error {
+	body, err := json.Marshal(struct {
+		Body string `json:"body"`
+	}{markdown})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode comment")
+	}
+
+	commentsURL := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments", strings.TrimSuffix(c.baseURL, "/"), c.pr.Owner, c.pr.Repo, c.pr.Number)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, commentsURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create request for %s", commentsURL)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to POST %s", commentsURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("failed to comment on %s: unexpected status %s: %s", c.pr, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// comment posts the given findings, and those fixed since the previous run
+// if the result was compared to one, on the pull request as a Markdown
+// report.
+func (l *linter) comment(ctx context.Context, findings []difflint.Finding, comparison *difflint.Comparison) error {
+	var b strings.Builder
+	b.WriteString("### difflint\n\n")
+	if err := l.markdown.render(&b, findings, comparison); err != nil {
+		return err
+	}
+
+	if err := l.commenter.post(ctx, b.String()); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(l.stderr, "difflint: commented on %s\n", l.commenter.pr)
+	return nil
+}
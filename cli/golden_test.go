@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/ethanthatonekid/difflint"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares got to the golden file at the given path, or rewrites
+// the file with -update.
+func checkGolden(t *testing.T, path, got string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != string(want) {
+		t.Errorf("output differs from %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// goldenFindings returns findings of every kind, with and without rule IDs,
+// notes, owners, and target owners, for the golden tests of the formats.
+func goldenFindings() []difflint.Finding {
+	return []difflint.Finding{
+		{
+			Kind:      difflint.FindingUnsatisfied,
+			RuleFile:  "api/schema.go",
+			RuleID:    "user_schema",
+			StartLine: 3,
+			EndLine:   9,
+			Severity:  difflint.SeverityError,
+			Message:   "rule not satisfied",
+			Note:      "keep the client in sync",
+			Owner:     "@api-team",
+			MissingTargets: []difflint.TargetRef{
+				{Key: "web/client.ts", File: "web/client.ts", Owners: []string{"@web", "@ops"}},
+				{Key: "docs/api.md:users", File: "docs/api.md", Block: true, Range: &difflint.Range{Start: 10, End: 20}},
+			},
+		},
+		{
+			Kind:      difflint.FindingUnsatisfied,
+			RuleFile:  "config.yaml",
+			StartLine: 1,
+			EndLine:   4,
+			Severity:  difflint.SeverityWarn,
+			Message:   "rule not satisfied",
+			MissingTargets: []difflint.TargetRef{
+				{Key: "config.go", File: "config.go"},
+			},
+		},
+		{
+			Kind:      difflint.FindingSatisfied,
+			RuleFile:  "a.go",
+			RuleID:    "a",
+			StartLine: 2,
+			EndLine:   5,
+			Severity:  difflint.SeverityError,
+			Message:   "rule satisfied",
+			SatisfiedTargets: []difflint.TargetRef{
+				{Key: "b.go", File: "b.go"},
+			},
+		},
+		{
+			Kind:      difflint.FindingExpired,
+			RuleFile:  "migration.go",
+			RuleID:    "v1",
+			StartLine: 7,
+			EndLine:   12,
+			Severity:  difflint.SeverityInfo,
+			Message:   "rule expired on 2025-06-30, please remove it",
+			Owner:     "@db",
+		},
+		{
+			Kind:      difflint.FindingEmpty,
+			RuleFile:  "empty.go",
+			StartLine: 4,
+			EndLine:   5,
+			Severity:  difflint.SeverityWarn,
+			Message:   "empty LINT block",
+		},
+	}
+}
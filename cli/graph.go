@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// graphFormatDOT is the Graphviz DOT graph format.
+	graphFormatDOT = "dot"
+
+	// graphFormatJSON is the JSON adjacency graph format.
+	graphFormatJSON = "json"
+)
+
+// graphJSON is the JSON form of the rule dependency graph.
+type graphJSON struct {
+	Difflint  jsonMeta             `json:"difflint"`
+	Nodes     []difflint.GraphNode `json:"nodes"`
+	Edges     []difflint.GraphEdge `json:"edges"`
+	Adjacency map[string][]string  `json:"adjacency"`
+}
+
+// newGraphCommand returns the graph subcommand.
+func newGraphCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "graph",
+		Usage: "print the dependency graph of the rules under the root",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: fmt.Sprintf("output format: %q or %q", graphFormatDOT, graphFormatJSON),
+				Value: graphFormatDOT,
+			},
+			&cli.StringFlag{
+				Name:  "focus",
+				Usage: "only show the nodes reachable from the given file",
+			},
+		},
+		Action: graphAction,
+	}
+}
+
+func graphAction(ctx *cli.Context) error {
+	format := ctx.String("format")
+	if format != graphFormatDOT && format != graphFormatJSON {
+		return fmt.Errorf("invalid graph format %q, expected %q or %q", format, graphFormatDOT, graphFormatJSON)
+	}
+
+	// The graph's --format flag shadows the global one, so configure the
+	// linter from the parent command.
+	l, err := newLinter(ctx.Lineage()[1])
+	if err != nil {
+		return err
+	}
+
+	rulesMap, err := difflint.RulesWith(ctx.Context, l.options)
+	if err != nil {
+		return err
+	}
+
+	graph := difflint.BuildGraph(rulesMap.Rules)
+	if focus := ctx.String("focus"); focus != "" {
+		graph = graph.Focus(focus)
+	}
+
+	if format == graphFormatDOT {
+		return graph.WriteDOT(ctx.App.Writer)
+	}
+
+	enc := json.NewEncoder(ctx.App.Writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(graphJSON{
+		Difflint:  newJSONMeta(),
+		Nodes:     graph.Nodes,
+		Edges:     graph.Edges,
+		Adjacency: graph.Adjacency(),
+	})
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+// graphFixtures are files whose rules form two separate components: the api
+// and web files, which depend on each other's blocks, and the config files.
+var graphFixtures = map[string]string{
+	"api/schema.go": "package api\n\n//LINT.IF web/client.ts:user\ntype User struct{}\n//LINT.END schema\n",
+	"web/client.ts": "//LINT.IF api/schema.go:schema docs/api.md\ntype User = {}\n//LINT.END user\n",
+	"docs/api.md":   "# API\n",
+	"config.go":     "package config\n\n//LINT.IF config.yaml\nvar Port = 8080\n//LINT.END\n",
+	"config.yaml":   "port: 8080\n",
+	"unrelated.go":  "package unrelated\n",
+}
+
+func TestGraph(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, graphFixtures)
+
+	tests := []struct {
+		name   string
+		args   []string
+		golden string
+	}{
+		{name: "dot", golden: "testdata/graph.golden"},
+		{name: "focus", args: []string{"--focus", "web/client.ts"}, golden: "testdata/graph_focus.golden"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stdout, _, err := runApp(t, nil, append([]string{"--root", dir, "--no-cache", "graph"}, test.args...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			checkGolden(t, test.golden, stdout)
+		})
+	}
+}
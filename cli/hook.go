@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/urfave/cli/v2"
+)
+
+// hookMarker identifies hook scripts written by difflint.
+const hookMarker = "# Installed by difflint install-hook."
+
+// hookScripts are the hook scripts by hook name.
+var hookScripts = map[string]string{
+	"pre-commit": `#!/bin/sh
+` + hookMarker + `
+# Blocks the commit if the staged changes do not satisfy the LINT rules.
+git diff --cached | difflint
+`,
+	"pre-push": `#!/bin/sh
+` + hookMarker + `
+# Blocks the push if the unpushed commits do not satisfy the LINT rules.
+upstream=$(git rev-parse --abbrev-ref --symbolic-full-name '@{upstream}' 2>/dev/null) || exit 0
+git diff "$upstream...HEAD" | difflint
+`,
+}
+
+// newInstallHookCommand returns the install-hook subcommand.
+func newInstallHookCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "install-hook",
+		Usage: "install a git hook that runs difflint",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "hook",
+				Usage:    "hook to install: pre-commit or pre-push",
+				Value:    "pre-commit",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "print",
+				Usage:    "print the hook script instead of installing it (e.g. for husky or pre-commit)",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "force",
+				Usage:    "overwrite or remove an existing hook that was not installed by difflint",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "uninstall",
+				Usage:    "remove the hook instead of installing it",
+				Required: false,
+			},
+		},
+		Action: installHookAction,
+	}
+}
+
+func installHookAction(ctx *cli.Context) error {
+	hook := ctx.String("hook")
+	script, ok := hookScripts[hook]
+	if !ok {
+		return fmt.Errorf("unknown hook %q, expected \"pre-commit\" or \"pre-push\"", hook)
+	}
+
+	if ctx.Bool("print") {
+		_, err := fmt.Fprint(ctx.App.Writer, script)
+		return err
+	}
+
+	dir, err := difflint.GitHooksDir()
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, hook)
+	if ctx.Bool("uninstall") {
+		return uninstallHook(path, ctx.Bool("force"))
+	}
+
+	return installHook(path, script, ctx.Bool("force"))
+}
+
+// installHook writes the hook script to the given path. An existing hook that
+// was not installed by difflint is only overwritten if force is set.
+func installHook(path, script string, force bool) error {
+	mode := os.FileMode(0o755)
+	existing, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if !force && !bytes.Contains(existing, []byte(hookMarker)) {
+			return fmt.Errorf("hook %s already exists; use --force to overwrite it", path)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		// Preserve the existing permissions, ensuring the hook is executable.
+		mode = info.Mode().Perm() | 0o111
+
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, []byte(script), mode); err != nil {
+		return err
+	}
+
+	// WriteFile does not change the permissions of an existing file.
+	return os.Chmod(path, mode)
+}
+
+// uninstallHook removes the hook at the given path. A hook that was not
+// installed by difflint is only removed if force is set.
+func uninstallHook(path string, force bool) error {
+	existing, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !force && !bytes.Contains(existing, []byte(hookMarker)) {
+		return fmt.Errorf("hook %s was not installed by difflint; use --force to remove it", path)
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// chdir changes the working directory to dir for the rest of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestInstallHook(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	git(t, dir, "init", "-q")
+	chdir(t, dir)
+	hook := filepath.Join(dir, ".git", "hooks", "pre-commit")
+
+	t.Run("install", func(t *testing.T) {
+		if _, _, err := runApp(t, nil, "install-hook"); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := os.ReadFile(hook)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != hookScripts["pre-commit"] {
+			t.Errorf("hook = %q, want %q", got, hookScripts["pre-commit"])
+		}
+
+		info, err := os.Stat(hook)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info.Mode().Perm() != 0o755 {
+			t.Errorf("hook mode = %v, want 0755", info.Mode().Perm())
+		}
+	})
+
+	t.Run("refuse to overwrite", func(t *testing.T) {
+		const existing = "#!/bin/sh\nmake lint\n"
+		if err := os.Remove(hook); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(hook, []byte(existing), 0o700); err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err := runApp(t, nil, "install-hook")
+		if err == nil || !strings.Contains(err.Error(), "--force") {
+			t.Fatalf("install-hook error = %v, want to be told to use --force", err)
+		}
+
+		if got, _ := os.ReadFile(hook); string(got) != existing {
+			t.Errorf("hook = %q, want the existing hook %q", got, existing)
+		}
+
+		if _, _, err := runApp(t, nil, "install-hook", "--uninstall"); err == nil {
+			t.Errorf("install-hook --uninstall removed a hook not installed by difflint")
+		}
+	})
+
+	t.Run("force keeps the mode", func(t *testing.T) {
+		if _, _, err := runApp(t, nil, "install-hook", "--force"); err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := os.Stat(hook)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info.Mode().Perm() != 0o711 {
+			t.Errorf("hook mode = %v, want the existing 0700 and executable bits", info.Mode().Perm())
+		}
+	})
+
+	t.Run("uninstall", func(t *testing.T) {
+		if _, _, err := runApp(t, nil, "install-hook", "--uninstall"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(hook); !os.IsNotExist(err) {
+			t.Errorf("hook still exists after --uninstall: %v", err)
+		}
+
+		if _, _, err := runApp(t, nil, "install-hook", "--uninstall"); err != nil {
+			t.Errorf("install-hook --uninstall without a hook: %v", err)
+		}
+	})
+
+	t.Run("core.hooksPath", func(t *testing.T) {
+		git(t, dir, "config", "core.hooksPath", "custom-hooks")
+		if _, _, err := runApp(t, nil, "install-hook", "--hook", "pre-push"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "custom-hooks", "pre-push")); err != nil {
+			t.Errorf("pre-push hook not installed in core.hooksPath: %v", err)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/ethanthatonekid/difflint"
+	"github.com/urfave/cli/v2"
+)
+
+// newIndexCommand returns the index subcommand.
+func newIndexCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "index",
+		Usage: "print an index of every rule under the root, for use with --use-index",
+		Action: func(ctx *cli.Context) error {
+			l, err := newLinter(ctx.Lineage()[1])
+			if err != nil {
+				return err
+			}
+
+			// The index is built from the files themselves, never from a
+			// previous index.
+			l.options.IndexPath = ""
+			rulesMap, err := difflint.RulesWith(ctx.Context, l.options)
+			if err != nil {
+				return err
+			}
+
+			return difflint.NewIndexFile(rulesMap.Rules).Write(ctx.App.Writer)
+		},
+	}
+}
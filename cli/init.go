@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/urfave/cli/v2"
+)
+
+// initExtMapFile is the extension map written by the init subcommand.
+const initExtMapFile = ".difflint.json"
+
+// initFallbackExt is the extension of the starter rule when the tree has no
+// files with known templates.
+const initFallbackExt = "go"
+
+// initTemplates are the templates of common file extensions that have no
+// default templates.
+var initTemplates = map[string][]string{
+	"cs": {"//LINT.?"},
+}
+
+// newInitCommand returns the init subcommand.
+func newInitCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "init",
+		Usage: "write a " + initExtMapFile + " extension map for the file types under the root and print a starter rule",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:     "force",
+				Usage:    "overwrite an existing " + initExtMapFile,
+				Required: false,
+			},
+		},
+		Action: initAction,
+	}
+}
+
+func initAction(ctx *cli.Context) error {
+	root := ctx.String("root")
+	if root == "" {
+		root = "."
+		if gitRoot, err := difflint.GitRoot(); err == nil {
+			root = gitRoot
+		}
+	}
+
+	counts, err := countExtensions(ctx, root)
+	if err != nil {
+		return err
+	}
+
+	extMap := initExtMap(counts)
+	file := filepath.Join(root, initExtMapFile)
+	if err := writeInitExtMap(file, extMap, ctx.Bool("force")); err != nil {
+		return err
+	}
+
+	// The extension map must load as it would with --ext_map.
+	if _, err := difflint.NewExtMap(file); err != nil {
+		return err
+	}
+
+	return printStarterRule(ctx.App.Writer, extMap, counts)
+}
+
+// countExtensions returns the number of files under root by extension,
+// without the leading dot.
+func countExtensions(ctx *cli.Context, root string) (map[string]int, error) {
+	counts := make(map[string]int)
+	err := difflint.WalkFS(ctx.Context, os.DirFS(root), nil, nil, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if ext := strings.TrimPrefix(path.Ext(file), "."); ext != "" {
+			counts[ext]++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// initExtMap returns the templates of every counted extension that has
+// default templates or templates in initTemplates.
+func initExtMap(counts map[string]int) difflint.ExtFileJSON {
+	extMap := make(difflint.ExtFileJSON)
+	for ext := range counts {
+		if templates, ok := extTemplates(ext); ok {
+			extMap[ext] = templates
+		}
+	}
+
+	return extMap
+}
+
+// extTemplates returns the default templates of the given extension, or
+// those of initTemplates.
+func extTemplates(ext string) ([]string, bool) {
+	if indices, ok := difflint.DefaultFileExtMap[ext]; ok {
+		templates := make([]string, len(indices))
+		for i, index := range indices {
+			templates[i] = difflint.DefaultTemplates[index]
+		}
+
+		return templates, true
+	}
+
+	templates, ok := initTemplates[ext]
+	return templates, ok
+}
+
+// writeInitExtMap writes the given extension map to file as indented JSON.
+// An existing file is only overwritten if force is set.
+func writeInitExtMap(file string, extMap difflint.ExtFileJSON, force bool) error {
+	if _, err := os.Stat(file); err == nil && !force {
+		return fmt.Errorf("%s already exists; use --force to overwrite it", file)
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Keep templates such as "<!--LINT.?" readable.
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(extMap); err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, b.Bytes(), 0o644)
+}
+
+// printStarterRule writes to w how to add a first rule to a file of the
+// primary extension, the one with the most files, and how to lint with the
+// extension map.
+func printStarterRule(w io.Writer, extMap difflint.ExtFileJSON, counts map[string]int) error {
+	exts := make([]string, 0, len(extMap))
+	for ext := range extMap {
+		exts = append(exts, ext)
+	}
+
+	sort.Slice(exts, func(i, j int) bool {
+		if counts[exts[i]] != counts[exts[j]] {
+			return counts[exts[i]] > counts[exts[j]]
+		}
+
+		return exts[i] < exts[j]
+	})
+
+	ext := initFallbackExt
+	if len(exts) > 0 {
+		ext = exts[0]
+	}
+
+	templates, _ := extTemplates(ext)
+	template := templates[0]
+	var b strings.Builder
+	fmt.Fprintf(&b, "Wrote %s with the templates of %d file extensions.\n\n", initExtMapFile, len(exts))
+	fmt.Fprintf(&b, "Add a first rule to a .%s file, so that changes to the block require changes to docs/example.md:\n\n", ext)
+	fmt.Fprintf(&b, "    %s\n", directiveLine(template, "IF docs/example.md"))
+	fmt.Fprintf(&b, "    ...\n")
+	fmt.Fprintf(&b, "    %s\n\n", directiveLine(template, "END"))
+	fmt.Fprintf(&b, "Then lint your changes, optionally limited to some files:\n\n")
+	fmt.Fprintf(&b, "    git diff | difflint --ext_map=%s\n", initExtMapFile)
+	fmt.Fprintf(&b, "    git diff | difflint --ext_map=%s --include='src/**' --exclude='vendor/**'\n", initExtMapFile)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// directiveLine returns the line of the given directive and arguments, e.g.
+// "IF a.go", written with the given template.
+func directiveLine(template, directive string) string {
+	if !strings.Contains(template, "{directive}") {
+		return strings.Replace(template, "?", directive, 1)
+	}
+
+	name, args, _ := strings.Cut(directive, " ")
+	return strings.NewReplacer("{directive}", name, "{args}", args).Replace(template)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ethanthatonekid/difflint"
+)
+
+// starterRule returns the rule printed by init, with the block in between.
+func starterRule(t *testing.T, stdout string) string {
+	t.Helper()
+
+	var lines []string
+	for _, line := range strings.Split(stdout, "\n") {
+		if line = strings.TrimPrefix(line, "    "); strings.Contains(line, "LINT.") {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) != 2 {
+		t.Fatalf("init printed %q, want an IF and an END directive", stdout)
+	}
+
+	return lines[0] + "\nvar X = 1\n" + lines[1] + "\n"
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		wantExts []string
+		wantExt  string
+	}{
+		{
+			name: "tree",
+			files: map[string]string{
+				"a.py":      "x = 1\n",
+				"b.py":      "y = 1\n",
+				"c.go":      "package c\n",
+				"d.cs":      "class D {}\n",
+				"README.md": "# Readme\n",
+				"LICENSE":   "MIT\n",
+			},
+			wantExts: []string{"cs", "go", "md", "py"},
+			wantExt:  "py",
+		},
+		{
+			name:    "empty directory",
+			wantExt: "go",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, test.files)
+
+			stdout, _, err := runApp(t, nil, "--root", dir, "init")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			file := filepath.Join(dir, initExtMapFile)
+			extMap, err := difflint.NewExtMap(file)
+			if err != nil {
+				t.Fatalf("NewExtMap() of the generated %s: %v", initExtMapFile, err)
+			}
+
+			content, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var extFile difflint.ExtFileJSON
+			if err := json.Unmarshal(content, &extFile); err != nil {
+				t.Fatal(err)
+			}
+
+			var exts []string
+			for ext := range extFile {
+				exts = append(exts, ext)
+			}
+			sort.Strings(exts)
+
+			if strings.Join(exts, " ") != strings.Join(test.wantExts, " ") {
+				t.Errorf("%s has the templates of %q, want %q", initExtMapFile, exts, test.wantExts)
+			}
+
+			rules, err := difflint.ParseFileRules("starter."+test.wantExt, strings.NewReader(starterRule(t, stdout)), difflint.LintOptions{
+				Templates:  extMap.Templates,
+				FileExtMap: extMap.FileExtMap,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(rules) != 1 || len(rules[0].Targets) != 1 || *rules[0].Targets[0].File != "docs/example.md" {
+				t.Errorf("ParseFileRules() of the starter rule = %+v, want one rule targeting docs/example.md", rules)
+			}
+
+			if _, _, err := runApp(t, nil, "--root", dir, "init"); err == nil || !strings.Contains(err.Error(), "--force") {
+				t.Errorf("init over an existing %s error = %v, want to be told to use --force", initExtMapFile, err)
+			}
+
+			if _, _, err := runApp(t, nil, "--root", dir, "init", "--force"); err != nil {
+				t.Errorf("init --force: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/ethanthatonekid/difflint"
+)
+
+// formatJSON is the JSON output format.
+const formatJSON = "json"
+
+// jsonSchemaVersion is the version of the JSON output's schema. It is bumped
+// on breaking changes.
+const jsonSchemaVersion = 2
+
+// jsonMeta identifies the producer and schema of a JSON output.
+type jsonMeta struct {
+	// Version is the version of difflint.
+	Version string `json:"version"`
+
+	// Schema is the version of the output's schema.
+	Schema int `json:"schema"`
+}
+
+// newJSONMeta returns the metadata of the JSON outputs of this build.
+func newJSONMeta() jsonMeta {
+	return jsonMeta{Version: currentBuild().Version, Schema: jsonSchemaVersion}
+}
+
+// jsonResult is the top-level object of the JSON output.
+type jsonResult struct {
+	Difflint jsonMeta           `json:"difflint"`
+	Summary  jsonSummary        `json:"summary"`
+	Findings []difflint.Finding `json:"findings"`
+	Fixed    []difflint.Finding `json:"fixed,omitempty"`
+}
+
+// jsonSummary counts the findings of a JSON output.
+type jsonSummary struct {
+	// Total is the number of findings before truncation.
+	Total int `json:"total"`
+
+	// Truncated is the number of findings left out by --max-findings.
+	Truncated int `json:"truncated"`
+
+	// Comparison counts the findings compared to a previous run with
+	// --compare-to, if any.
+	Comparison *jsonComparison `json:"comparison,omitempty"`
+}
+
+// jsonComparison counts the findings of a run compared to a previous run.
+type jsonComparison struct {
+	// New is the number of failing findings that the previous run did not
+	// have, which are the only ones listed.
+	New int `json:"new"`
+
+	// Fixed is the number of failing findings of the previous run that are
+	// gone.
+	Fixed int `json:"fixed"`
+
+	// Unchanged is the number of failing findings that the previous run also
+	// had, which are left out.
+	Unchanged int `json:"unchanged"`
+}
+
+// newJSONSummary returns the summary of the given findings, the first of
+// total findings, compared to a previous run if comparison is set.
+func newJSONSummary(findings []difflint.Finding, total int, comparison *difflint.Comparison) jsonSummary {
+	s := jsonSummary{Total: total, Truncated: total - len(findings)}
+	if comparison != nil {
+		s.Comparison = &jsonComparison{
+			New:       comparison.New,
+			Fixed:     len(comparison.Fixed),
+			Unchanged: comparison.Unchanged,
+		}
+	}
+
+	return s
+}
+
+// fixedFindings returns the findings fixed since the previous run, if any.
+func fixedFindings(comparison *difflint.Comparison) []difflint.Finding {
+	if comparison == nil {
+		return nil
+	}
+
+	return comparison.Fixed
+}
+
+// renderJSON writes the given findings, the first of total findings, to w in
+// an envelope that records the version of difflint and of the schema, and
+// the findings fixed since the previous run if the result was compared.
+func renderJSON(w io.Writer, findings []difflint.Finding, total int, comparison *difflint.Comparison) error {
+	if findings == nil {
+		findings = []difflint.Finding{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jsonResult{
+		Difflint: newJSONMeta(),
+		Summary:  newJSONSummary(findings, total, comparison),
+		Findings: findings,
+		Fixed:    fixedFindings(comparison),
+	})
+}
+
+// jsonCommitsResult is the top-level object of the JSON output of a lint by
+// commit.
+type jsonCommitsResult struct {
+	Difflint jsonMeta     `json:"difflint"`
+	Commits  []jsonCommit `json:"commits"`
+}
+
+// jsonCommit is the findings of one commit of a lint by commit.
+type jsonCommit struct {
+	// SHA is the hash of the commit.
+	SHA string `json:"sha"`
+
+	// Subject is the first line of the commit message, if known.
+	Subject string `json:"subject,omitempty"`
+
+	// Summary counts the findings of the commit.
+	Summary jsonSummary `json:"summary"`
+
+	// Findings are the findings of the commit.
+	Findings []difflint.Finding `json:"findings"`
+
+	// Fixed are the findings of the commit fixed since the previous run.
+	Fixed []difflint.Finding `json:"fixed,omitempty"`
+}
+
+// renderJSONCommits writes the findings of the given commits to w as one
+// document with an entry per commit, in order.
+func renderJSONCommits(w io.Writer, commits []commitFindings) error {
+	out := jsonCommitsResult{
+		Difflint: newJSONMeta(),
+		Commits:  make([]jsonCommit, 0, len(commits)),
+	}
+
+	for _, c := range commits {
+		findings := c.findings
+		if findings == nil {
+			findings = []difflint.Finding{}
+		}
+
+		out.Commits = append(out.Commits, jsonCommit{
+			SHA:      c.commit.SHA,
+			Subject:  c.commit.Subject,
+			Summary:  newJSONSummary(findings, c.total, c.comparison),
+			Findings: findings,
+			Fixed:    fixedFindings(c.comparison),
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// jsonOwnerResult is the top-level object of the JSON output grouped by
+// owner.
+type jsonOwnerResult struct {
+	Difflint jsonMeta         `json:"difflint"`
+	Summary  jsonSummary      `json:"summary"`
+	Owners   []jsonOwnerGroup `json:"owners"`
+
+	// Fixed are the findings fixed since the previous run, which are not
+	// grouped by owner.
+	Fixed []difflint.Finding `json:"fixed,omitempty"`
+}
+
+// jsonOwnerGroup is the findings of the rules of one owner.
+type jsonOwnerGroup struct {
+	// Owner is the owner of the rules, or empty for rules without one.
+	Owner string `json:"owner"`
+
+	// Findings are the findings of the owner's rules.
+	Findings []difflint.Finding `json:"findings"`
+}
+
+// renderJSONByOwner writes the given findings, the first of total findings,
+// to w grouped by the owner of their rule. Groups are sorted by owner, with
+// the rules without an owner last.
+func renderJSONByOwner(w io.Writer, findings []difflint.Finding, total int, comparison *difflint.Comparison) error {
+	groups := make(map[string][]difflint.Finding)
+	var owners []string
+	for _, f := range findings {
+		if _, ok := groups[f.Owner]; !ok {
+			owners = append(owners, f.Owner)
+		}
+
+		groups[f.Owner] = append(groups[f.Owner], f)
+	}
+
+	sort.Slice(owners, func(i, j int) bool {
+		if (owners[i] == "") != (owners[j] == "") {
+			return owners[j] == ""
+		}
+
+		return owners[i] < owners[j]
+	})
+
+	out := jsonOwnerResult{
+		Difflint: newJSONMeta(),
+		Summary:  newJSONSummary(findings, total, comparison),
+		Owners:   make([]jsonOwnerGroup, 0, len(owners)),
+		Fixed:    fixedFindings(comparison),
+	}
+
+	for _, owner := range owners {
+		out.Owners = append(out.Owners, jsonOwnerGroup{Owner: owner, Findings: groups[owner]})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
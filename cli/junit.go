@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/ethanthatonekid/difflint"
+)
+
+// formatJUnit is the --format value for JUnit XML output.
+const formatJUnit = "junit"
+
+// junitTestSuites is the root element of a JUnit XML report with a test
+// suite per commit.
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite is the root element of a JUnit XML report.
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is a single evaluated rule.
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+// junitFailure describes why a rule is not satisfied.
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// renderJUnit writes the satisfied and unsatisfied rules among the given
+// findings to w as a JUnit XML test suite with one test case per rule.
+func renderJUnit(w io.Writer, findings []difflint.Finding) error {
+	return writeJUnit(w, newJUnitTestSuite("difflint", findings))
+}
+
+// renderJUnitCommits writes the findings of the given commits to w as a
+// JUnit XML report with one test suite per commit, named after the commit.
+func renderJUnitCommits(w io.Writer, commits []commitFindings) error {
+	suites := junitTestSuites{Name: "difflint"}
+	for _, c := range commits {
+		suite := newJUnitTestSuite(commitLabel(c.commit), c.findings)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.TestSuites = append(suites.TestSuites, suite)
+	}
+
+	return writeJUnit(w, suites)
+}
+
+// newJUnitTestSuite returns the test suite with the given name of the
+// satisfied and unsatisfied rules among the given findings.
+func newJUnitTestSuite(name string, findings []difflint.Finding) junitTestSuite {
+	suite := junitTestSuite{Name: name}
+	for _, f := range findings {
+		testCase := junitTestCase{
+			Name:      junitTestCaseName(f),
+			ClassName: "difflint",
+		}
+
+		switch f.Kind {
+		case difflint.FindingUnsatisfied:
+			testCase.Failure = &junitFailure{
+				Message: "rule not satisfied",
+				Type:    string(f.Severity),
+				Text:    "missing changes to targets:\n" + strings.Join(targetKeys(f.MissingTargets), "\n"),
+			}
+			if f.Note != "" {
+				testCase.Failure.Text += "\nnote: " + f.Note
+			}
+			if f.Owner != "" {
+				testCase.Failure.Text += "\nowner: " + f.Owner
+			}
+			suite.Failures++
+		case difflint.FindingExpired, difflint.FindingEmpty:
+			continue
+		}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	suite.Tests = len(suite.TestCases)
+	return suite
+}
+
+// writeJUnit writes the given JUnit XML report to w.
+func writeJUnit(w io.Writer, report any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitTestCaseName returns file:ID for rules with an ID, or file:start-end.
+func junitTestCaseName(f difflint.Finding) string {
+	if f.RuleID != "" {
+		return f.RuleFile + ":" + f.RuleID
+	}
+
+	return f.Location()
+}
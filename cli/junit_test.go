@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestRenderJUnit(t *testing.T) {
+	var b strings.Builder
+	if err := renderJUnit(&b, goldenFindings()); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "testdata/junit.golden", b.String())
+
+	var suite junitTestSuite
+	if err := xml.Unmarshal([]byte(b.String()), &suite); err != nil {
+		t.Fatal(err)
+	}
+
+	if suite.Tests != 3 || suite.Failures != 2 || len(suite.TestCases) != 3 {
+		t.Errorf("renderJUnit() = %d tests and %d failures, want 3 tests and 2 failures", suite.Tests, suite.Failures)
+	}
+}
+
+func TestRenderJUnitEmpty(t *testing.T) {
+	var b strings.Builder
+	if err := renderJUnit(&b, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "testdata/junit_empty.golden", b.String())
+}
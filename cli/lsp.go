@@ -0,0 +1,651 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/urfave/cli/v2"
+)
+
+// lspDebounce is how long the language server waits after a save before
+// linting, so that saving several files at once lints once.
+const lspDebounce = 300 * time.Millisecond
+
+// LSP diagnostic severities.
+const (
+	lspSeverityError       = 1
+	lspSeverityWarning     = 2
+	lspSeverityInformation = 3
+)
+
+// JSON-RPC error codes.
+const (
+	lspMethodNotFound = -32601
+	lspInternalError  = -32603
+)
+
+// lspRequest is an incoming JSON-RPC request or notification.
+type lspRequest struct {
+	ID     *json.RawMessage `json:"id,omitempty"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params,omitempty"`
+}
+
+// lspResponse is an outgoing JSON-RPC response.
+type lspResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+// lspErrorResponse is an outgoing JSON-RPC response to a failed request.
+type lspErrorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   lspError         `json:"error"`
+}
+
+// lspError is a JSON-RPC error.
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// lspNotification is an outgoing JSON-RPC notification.
+type lspNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// lspPosition is a zero-based position in a text document.
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// lspRange is a range in a text document.
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+// lspLocation is a range in a given document.
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+// lspRelatedInformation points a diagnostic at another location.
+type lspRelatedInformation struct {
+	Location lspLocation `json:"location"`
+	Message  string      `json:"message"`
+}
+
+// lspDiagnostic is a problem reported in a document.
+type lspDiagnostic struct {
+	Range              lspRange                `json:"range"`
+	Severity           int                     `json:"severity"`
+	Source             string                  `json:"source"`
+	Code               string                  `json:"code,omitempty"`
+	Message            string                  `json:"message"`
+	RelatedInformation []lspRelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+// lspDocumentLink links a range of a document to another document.
+type lspDocumentLink struct {
+	Range   lspRange `json:"range"`
+	Target  string   `json:"target"`
+	Tooltip string   `json:"tooltip,omitempty"`
+}
+
+// lspTextDocument identifies a document in the params of a request.
+type lspTextDocument struct {
+	TextDocument struct {
+		URI  string `json:"uri"`
+		Text string `json:"text"`
+	} `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+// newLSPCommand returns the lsp subcommand.
+func newLSPCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "lsp",
+		Usage: "run a language server over standard input and output that reports unsatisfied rules as diagnostics",
+		Action: func(ctx *cli.Context) error {
+			l, err := newLinter(ctx)
+			if err != nil {
+				return err
+			}
+
+			// Document URIs are absolute, and so must be the root.
+			if l.options.Root, err = filepath.Abs(l.options.Root); err != nil {
+				return err
+			}
+
+			s := &lspServer{
+				linter:    l,
+				w:         ctx.App.Writer,
+				published: make(map[string]struct{}),
+				documents: make(map[string]string),
+			}
+
+			return s.serve(ctx.Context, ctx.App.Reader)
+		},
+	}
+}
+
+// lspServer is a minimal language server that lints the working tree
+// against HEAD whenever a document is saved.
+type lspServer struct {
+	// linter holds the options of each lint.
+	linter *linter
+
+	// w is the writer to which messages are written.
+	w io.Writer
+
+	// writeMu serializes the messages written to w.
+	writeMu sync.Mutex
+
+	// lintMu serializes lints and guards published and timer.
+	lintMu sync.Mutex
+
+	// published are the URIs of the documents with diagnostics.
+	published map[string]struct{}
+
+	// timer debounces lints.
+	timer *time.Timer
+
+	// documents is the text of the open documents by URI.
+	documents map[string]string
+}
+
+// serve reads requests from r until the client exits or r ends.
+func (s *lspServer) serve(ctx context.Context, r io.Reader) error {
+	reader := textproto.NewReader(bufio.NewReader(r))
+	for {
+		req, err := readLSPMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if req.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.handle(ctx, req)
+		if req.ID == nil {
+			if err != nil {
+				s.linter.logger.Printf("lsp: %s: %v", req.Method, err)
+			}
+
+			continue
+		}
+
+		var resp interface{} = lspResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+		if err != nil {
+			code := lspInternalError
+			if _, ok := err.(lspMethodError); ok {
+				code = lspMethodNotFound
+			}
+
+			resp = lspErrorResponse{JSONRPC: "2.0", ID: req.ID, Error: lspError{Code: code, Message: err.Error()}}
+		}
+
+		if err := s.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// lspMethodError is returned for methods the server does not implement.
+type lspMethodError string
+
+func (e lspMethodError) Error() string {
+	return fmt.Sprintf("method %q not found", string(e))
+}
+
+// handle handles a request or notification and returns its result.
+func (s *lspServer) handle(ctx context.Context, req *lspRequest) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			RootURI string `json:"rootUri"`
+		}
+
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		if root := uriPath(params.RootURI); root != "" {
+			s.linter.options.Root = root
+		}
+
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": map[string]interface{}{
+					"openClose": true,
+					"change":    1,
+					"save":      true,
+				},
+				"documentLinkProvider": map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{
+				"name":    "difflint",
+				"version": currentBuild().Version,
+			},
+		}, nil
+
+	case "initialized", "textDocument/didSave":
+		s.scheduleLint(ctx)
+		return nil, nil
+
+	case "textDocument/didOpen", "textDocument/didChange", "textDocument/didClose":
+		var params lspTextDocument
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		uri := params.TextDocument.URI
+		switch {
+		case req.Method == "textDocument/didClose":
+			delete(s.documents, uri)
+		case len(params.ContentChanges) > 0:
+			s.documents[uri] = params.ContentChanges[len(params.ContentChanges)-1].Text
+		default:
+			s.documents[uri] = params.TextDocument.Text
+		}
+
+		return nil, nil
+
+	case "textDocument/documentLink":
+		var params lspTextDocument
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+
+		return s.documentLinks(params.TextDocument.URI)
+
+	case "shutdown":
+		return nil, nil
+	}
+
+	if req.ID == nil {
+		return nil, nil
+	}
+
+	return nil, lspMethodError(req.Method)
+}
+
+// scheduleLint lints the working tree once no save has happened for
+// lspDebounce.
+func (s *lspServer) scheduleLint(ctx context.Context) {
+	s.lintMu.Lock()
+	defer s.lintMu.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+
+	s.timer = time.AfterFunc(lspDebounce, func() {
+		if err := s.lint(ctx); err != nil && ctx.Err() == nil {
+			s.linter.logger.Printf("lsp: %v", err)
+		}
+	})
+}
+
+// lint lints the working tree against HEAD and publishes the diagnostics of
+// every document with unsatisfied or expired rules, clearing those of the
+// documents that no longer have any.
+func (s *lspServer) lint(ctx context.Context) error {
+	s.lintMu.Lock()
+	defer s.lintMu.Unlock()
+
+	root := s.linter.options.Root
+	r, err := difflint.GitDiff(ctx, root, "HEAD")
+	if err != nil {
+		return err
+	}
+
+	options := s.linter.options
+	options.Reader = r
+	result, err := difflint.DoWith(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	findings := difflint.BuildFindings(result)
+	s.linter.codeOwners.Annotate(findings)
+	diagnostics := make(map[string][]lspDiagnostic)
+	for _, f := range findings {
+		if f.Config || f.Kind == difflint.FindingSatisfied {
+			continue
+		}
+
+		uri := fileURI(root, f.RuleFile)
+		diagnostics[uri] = append(diagnostics[uri], lspFindingDiagnostic(root, f))
+	}
+
+	for uri := range s.published {
+		if _, ok := diagnostics[uri]; !ok {
+			diagnostics[uri] = []lspDiagnostic{}
+		}
+	}
+
+	s.published = make(map[string]struct{}, len(diagnostics))
+	for uri, list := range diagnostics {
+		if len(list) > 0 {
+			s.published[uri] = struct{}{}
+		}
+
+		err := s.write(lspNotification{
+			JSONRPC: "2.0",
+			Method:  "textDocument/publishDiagnostics",
+			Params: map[string]interface{}{
+				"uri":         uri,
+				"diagnostics": list,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lspFindingDiagnostic returns the diagnostic of the given finding at the
+// line of its rule's IF directive, related to the targets that changed.
+func lspFindingDiagnostic(root string, f difflint.Finding) lspDiagnostic {
+	line := f.StartLine - 1
+	if line < 0 {
+		line = 0
+	}
+
+	d := lspDiagnostic{
+		Range: lspRange{
+			Start: lspPosition{Line: line},
+			End:   lspPosition{Line: line, Character: lineLength(filepath.Join(root, filepath.FromSlash(f.RuleFile)), line)},
+		},
+		Severity: lspSeverity(f.Severity),
+		Source:   "difflint",
+		Code:     f.RuleID,
+		Message:  f.Message,
+	}
+
+	if f.Kind == difflint.FindingUnsatisfied {
+		labels := make([]string, 0, len(f.MissingTargets))
+		for _, target := range f.MissingTargets {
+			labels = append(labels, target.Label())
+			var start int
+			if target.Range != nil {
+				start = target.Range.Start - 1
+			}
+
+			d.RelatedInformation = append(d.RelatedInformation, lspRelatedInformation{
+				Location: lspLocation{
+					URI:   fileURI(root, target.File),
+					Range: lspRange{Start: lspPosition{Line: start}, End: lspPosition{Line: start}},
+				},
+				Message: "changed: " + target.Label(),
+			})
+		}
+
+		d.Message = fmt.Sprintf("rule not satisfied: %s changed without this block", strings.Join(labels, ", "))
+	}
+
+	if f.Note != "" {
+		d.Message += "\nnote: " + f.Note
+	}
+
+	if f.Owner != "" {
+		d.Message += "\nowner: " + f.Owner
+	}
+
+	return d
+}
+
+// lspSeverity returns the LSP severity of the given rule severity.
+func lspSeverity(severity difflint.Severity) int {
+	switch severity {
+	case difflint.SeverityError:
+		return lspSeverityError
+	case difflint.SeverityWarn:
+		return lspSeverityWarning
+	}
+
+	return lspSeverityInformation
+}
+
+// documentLinks returns a link from each target written in the directives of
+// the given document to the target's file, at the target's block or range
+// if it has one. Directives use the default templates.
+func (s *lspServer) documentLinks(uri string) ([]lspDocumentLink, error) {
+	root := s.linter.options.Root
+	file, err := filepath.Rel(root, uriPath(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	file = filepath.ToSlash(file)
+	text, ok := s.documents[uri]
+	if !ok {
+		b, err := os.ReadFile(uriPath(uri))
+		if err != nil {
+			return nil, err
+		}
+
+		text = string(b)
+	}
+
+	rules, err := difflint.ParseFileRules(file, strings.NewReader(text), s.parseOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(text, "\n")
+	links := []lspDocumentLink{}
+	for _, rule := range rules {
+		// The targets are on the directive's line, which precedes the line
+		// of a LINE rule.
+		line := rule.Hunk.Range.Start - 1
+		if line < len(lines) && !containsTargets(lines[line], rule.Targets) && line > 0 {
+			line--
+		}
+
+		if line >= len(lines) {
+			continue
+		}
+
+		var offset int
+		for _, target := range rule.Targets {
+			written := target.Raw
+			if target.Macro != "" {
+				written = target.Macro
+			}
+
+			i := strings.Index(lines[line][offset:], written)
+			if i < 0 || written == "" {
+				continue
+			}
+
+			start := offset + i
+			offset = start + len(written)
+			if target.File != nil && strings.ContainsAny(*target.File, "*?[") {
+				continue
+			}
+
+			key := difflint.TargetKey(file, difflint.Target{File: target.File})
+			link := lspDocumentLink{
+				Range: lspRange{
+					Start: lspPosition{Line: line, Character: start},
+					End:   lspPosition{Line: line, Character: offset},
+				},
+				Target:  fileURI(root, key),
+				Tooltip: difflint.TargetKey(file, target),
+			}
+
+			if targetLine := s.targetLine(root, key, target); targetLine > 0 {
+				link.Target += "#L" + strconv.Itoa(targetLine)
+			}
+
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
+// containsTargets returns true if the given line contains the first of the
+// given targets as written.
+func containsTargets(line string, targets []difflint.Target) bool {
+	if len(targets) == 0 {
+		return true
+	}
+
+	written := targets[0].Raw
+	if targets[0].Macro != "" {
+		written = targets[0].Macro
+	}
+
+	return strings.Contains(line, written)
+}
+
+// targetLine returns the first line of the given target in the file with
+// the given key, or 0 if the target is the whole file or was not found.
+func (s *lspServer) targetLine(root, key string, target difflint.Target) int {
+	if target.Range != nil {
+		return target.Range.Start
+	}
+
+	if target.ID == nil || target.Wildcard() {
+		return 0
+	}
+
+	f, err := os.Open(filepath.Join(root, filepath.FromSlash(key)))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	rules, err := difflint.ParseFileRules(key, f, s.parseOptions())
+	if err != nil {
+		return 0
+	}
+
+	for _, rule := range rules {
+		if rule.ID != nil && *rule.ID == *target.ID {
+			return rule.Hunk.Range.Start
+		}
+	}
+
+	return 0
+}
+
+// parseOptions returns the options with which single files are parsed.
+func (s *lspServer) parseOptions() difflint.LintOptions {
+	return difflint.LintOptions{
+		DirectiveWord: s.linter.options.DirectiveWord,
+		TargetMacros:  s.linter.options.TargetMacros,
+		TargetAliases: s.linter.options.TargetAliases,
+	}
+}
+
+// write writes the given message with its Content-Length header.
+func (s *lspServer) write(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err = fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return err
+}
+
+// readLSPMessage reads a message framed by a Content-Length header.
+func readLSPMessage(r *textproto.Reader) (*lspRequest, error) {
+	header, err := r.ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(header) == 0 {
+			return nil, io.EOF
+		}
+
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %v", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r.R, body); err != nil {
+		return nil, err
+	}
+
+	var req lspRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+// fileURI returns the file URI of the given file relative to root.
+func fileURI(root, file string) string {
+	abs, err := filepath.Abs(filepath.Join(root, filepath.FromSlash(file)))
+	if err != nil {
+		abs = filepath.Join(root, filepath.FromSlash(file))
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
+
+// uriPath returns the path of the given file URI, or "" if it is not one.
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+
+	return filepath.FromSlash(u.Path)
+}
+
+// lineLength returns the length of the given zero-based line of the given
+// file, or 0 if it cannot be read.
+func lineLength(file string, line int) int {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for i := 0; scanner.Scan(); i++ {
+		if i == line {
+			return len(scanner.Text())
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lspMessage is any message written by the language server.
+type lspMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+}
+
+// lspClient talks to a language server over a pair of pipes.
+type lspClient struct {
+	t        *testing.T
+	w        io.Writer
+	messages chan lspMessage
+}
+
+// send writes a request, or a notification if id is zero.
+func (c *lspClient) send(id int, method string, params interface{}) {
+	c.t.Helper()
+	msg := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+	if id != 0 {
+		msg["id"] = id
+	}
+
+	b, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(b), b); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// await returns the first message for which match returns true, skipping
+// the others.
+func (c *lspClient) await(what string, match func(lspMessage) bool) lspMessage {
+	c.t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				c.t.Fatalf("server closed before %s", what)
+			}
+
+			if match(msg) {
+				return msg
+			}
+		case <-timeout:
+			c.t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+// awaitDiagnostics returns the next diagnostics published for the given URI.
+func (c *lspClient) awaitDiagnostics(uri string) []lspDiagnostic {
+	c.t.Helper()
+	var params struct {
+		URI         string          `json:"uri"`
+		Diagnostics []lspDiagnostic `json:"diagnostics"`
+	}
+
+	c.await("diagnostics of "+uri, func(msg lspMessage) bool {
+		if msg.Method != "textDocument/publishDiagnostics" {
+			return false
+		}
+
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			c.t.Fatal(err)
+		}
+
+		return params.URI == uri
+	})
+
+	return params.Diagnostics
+}
+
+// git runs git with the given arguments in dir.
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v: %s", args[0], err, out)
+	}
+}
+
+func TestLSP(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go": "package a\n\n//LINT.IF t.go\nvar X = 1\n//LINT.END schema\n",
+		"t.go": "package t\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git(t, dir, "init", "-q")
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-q", "-m", "init")
+	if err := os.WriteFile(filepath.Join(dir, "t.go"), []byte("package t\n\nvar Y = 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	clientR, clientW := io.Pipe()
+	serverR, serverW := io.Pipe()
+	app := NewApp()
+	app.Reader = clientR
+	app.Writer = serverW
+	app.ErrWriter = io.Discard
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- app.RunContext(ctx, []string{"difflint", "--root", dir, "--no-cache", "lsp"})
+		serverW.Close()
+	}()
+
+	client := &lspClient{t: t, w: clientW, messages: make(chan lspMessage)}
+	go func() {
+		defer close(client.messages)
+		reader := textproto.NewReader(bufio.NewReader(serverR))
+		for {
+			header, err := reader.ReadMIMEHeader()
+			if err != nil {
+				return
+			}
+
+			length, err := strconv.Atoi(header.Get("Content-Length"))
+			if err != nil {
+				return
+			}
+
+			body := make([]byte, length)
+			if _, err := io.ReadFull(reader.R, body); err != nil {
+				return
+			}
+
+			var msg lspMessage
+			if err := json.Unmarshal(body, &msg); err == nil {
+				client.messages <- msg
+			}
+		}
+	}()
+
+	rootURI := fileURI(dir, "")
+	uri := fileURI(dir, "a.go")
+	client.send(1, "initialize", map[string]interface{}{"rootUri": rootURI})
+	initialized := client.await("initialize response", func(msg lspMessage) bool {
+		return msg.ID != nil && *msg.ID == 1
+	})
+	if !strings.Contains(string(initialized.Result), `"textDocumentSync"`) {
+		t.Errorf("initialize = %s, want the capabilities", initialized.Result)
+	}
+
+	client.send(0, "initialized", map[string]interface{}{})
+	client.send(0, "textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "go", "version": 1, "text": files["a.go"]},
+	})
+
+	diagnostics := client.awaitDiagnostics(uri)
+	if len(diagnostics) != 1 {
+		t.Fatalf("diagnostics of a.go = %+v, want one", diagnostics)
+	}
+
+	if d := diagnostics[0]; d.Range.Start.Line != 2 || d.Code != "schema" || !strings.Contains(d.Message, "t.go") {
+		t.Errorf("diagnostic = %+v, want the IF line of schema with t.go", d)
+	}
+
+	// Changing the block along with its target clears the diagnostics.
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\n//LINT.IF t.go\nvar X = 2\n//LINT.END schema\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	client.send(0, "textDocument/didSave", map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri}})
+	if diagnostics := client.awaitDiagnostics(uri); len(diagnostics) != 0 {
+		t.Errorf("diagnostics of a.go after the fix = %+v, want none", diagnostics)
+	}
+
+	client.send(2, "shutdown", nil)
+	client.await("shutdown response", func(msg lspMessage) bool {
+		return msg.ID != nil && *msg.ID == 2
+	})
+	client.send(0, "exit", nil)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("lsp = %v, want nil", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("lsp did not exit")
+	}
+}
@@ -4,9 +4,17 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/ethanthatonekid/difflint"
 	"github.com/urfave/cli/v2"
@@ -15,8 +23,11 @@ import (
 func main() {
 	app := NewApp()
 
+	// Unsatisfied rules exit with status 1 via cli.Exit; any other error is
+	// operational and exits with status 2.
 	if err := app.Run(os.Args); err != nil {
-		log.Fatal(err)
+		log.Println(err)
+		os.Exit(2)
 	}
 }
 
@@ -26,11 +37,19 @@ type App struct {
 
 func NewApp() *App {
 	app := &App{}
+	cli.VersionPrinter = printVersion
 
 	app.App = &cli.App{
-		Name:  "difflint",
-		Usage: "lint diffs from standard input",
+		Name:      "difflint",
+		Usage:     "lint diffs from standard input or patch files",
+		Version:   currentBuild().Version,
+		ArgsUsage: "[patch files...]",
 		Flags: []cli.Flag{
+			&cli.PathFlag{
+				Name:     "root",
+				Usage:    "directory to which diff paths are relative (default: git repository root or current directory)",
+				Required: false,
+			},
 			&cli.StringSliceFlag{
 				Name:     "include",
 				Usage:    "include files matching the given glob",
@@ -41,29 +60,322 @@ func NewApp() *App {
 				Usage:    "exclude files matching the given glob",
 				Required: false,
 			},
+			&cli.StringFlag{
+				Name:     "filter-scope",
+				Usage:    "what --include and --exclude apply to: rules (which rules are enforced), changes (which changes count), or all",
+				Value:    string(difflint.FilterScopeRules),
+				Required: false,
+			},
+			&cli.PathFlag{
+				Name:     "codeowners",
+				Usage:    "path to a CODEOWNERS file whose owners are shown with the targets (default: .github/CODEOWNERS, CODEOWNERS, or docs/CODEOWNERS under the root)",
+				Required: false,
+			},
+			&cli.PathFlag{
+				Name:     "rules",
+				Usage:    "path to a JSON file of rules for files that cannot hold directives (see README.md for format)",
+				Required: false,
+			},
 			&cli.PathFlag{
 				Name:     "ext_map",
 				Usage:    "path to file extension map[string][]string (see README.md for format)",
 				Required: false,
 			},
+			&cli.StringFlag{
+				Name:     "diff-url",
+				Usage:    "fetch the diff from the given URL instead of standard input",
+				Required: false,
+			},
+			&cli.StringSliceFlag{
+				Name:     "header",
+				Usage:    "add a \"Name: Value\" header to the --diff-url request",
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "github-pr",
+				Usage:    "fetch the diff of the given GitHub pull request, as owner/repo#number, instead of reading standard input",
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "github-token",
+				Usage:    "token of the GitHub API requests of --github-pr and --comment",
+				EnvVars:  []string{"GITHUB_TOKEN"},
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "github-api-url",
+				Usage:    "base URL of the GitHub API, e.g. of GitHub Enterprise Server",
+				Value:    difflint.DefaultGitHubAPIURL,
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "comment",
+				Usage:    "post the results as a Markdown comment on the --github-pr pull request",
+				Required: false,
+			},
+			&cli.DurationFlag{
+				Name:     "diff-url-timeout",
+				Usage:    "timeout for the --diff-url and GitHub API requests",
+				Value:    30 * time.Second,
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "vcs",
+				Usage:    "version control system flavor of the diff: auto, git, hg, or jj",
+				Value:    string(difflint.VCSAuto),
+				Required: false,
+			},
+			&cli.StringSliceFlag{
+				Name:     "strip-prefix",
+				Usage:    "strip the given prefix from file names in the diff (default: auto-detect a/ and b/)",
+				Required: false,
+			},
+			&cli.StringSliceFlag{
+				Name:     "target-macro",
+				Usage:    "define a target macro as @name=target or @name.ext=target for files with extension ext, e.g. @tests.ts=./*.test.ts",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "strict-directives",
+				Usage:    "fail on unknown LINT directives and unresolved LINT.FUNC symbols instead of warning",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "warn-mismatched-templates",
+				Usage:    "warn on directive-like lines that match no template for the file type",
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "directive-word",
+				Usage:    "word that introduces directives in the default templates, e.g. DIFF for //DIFF.IF",
+				Value:    difflint.DefaultDirectiveWord,
+				Required: false,
+			},
+			&cli.StringSliceFlag{
+				Name:     "comments-only",
+				Usage:    "file extension (go, js, ts, py, md, ...) in whose string literals and code blocks directive-like lines are ignored",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "relative-targets",
+				Usage:    "resolve bare target file names relative to the rule's directory instead of the root",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "strict-presence",
+				Usage:    "also require every target of a changed rule to change",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "blank-blocks-empty",
+				Usage:    "report blocks that guard only blank lines as empty",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "exclusive-markers",
+				Usage:    "ignore changes to the IF and END lines of a block when deciding whether it changed",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "content-only",
+				Usage:    "ignore changes of file mode and of binary files, which otherwise change the whole file",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "use-index",
+				Usage:    "read the rules of unchanged files from the index file instead of parsing them",
+				Required: false,
+			},
+			&cli.PathFlag{
+				Name:     "index-file",
+				Usage:    fmt.Sprintf("path to the index file written by the index command (default: %s under the root)", difflint.DefaultIndexFile),
+				Required: false,
+			},
+			&cli.StringSliceFlag{
+				Name:     "skip-rule",
+				Usage:    "skip the rules with the given ID, ID glob pattern, file, or file:id",
+				Required: false,
+			},
+			&cli.StringSliceFlag{
+				Name:     "only-rule",
+				Usage:    "check only the rules whose ID matches the given ID or glob pattern, e.g. sec-*",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "skip-unnamed",
+				Usage:    "skip the rules without an ID",
+				Required: false,
+			},
+			&cli.StringSliceFlag{
+				Name:     "only-tags",
+				Usage:    "check only the rules with at least one of the given tags",
+				Required: false,
+			},
+			&cli.StringSliceFlag{
+				Name:     "skip-tags",
+				Usage:    "do not check the rules with any of the given tags",
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "fail-on",
+				Usage:    "exit non-zero only for unsatisfied rules at or above this severity: info, warn, or error",
+				Value:    string(difflint.SeverityError),
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "fail-on-expired",
+				Usage:    "exit non-zero if any rule has passed its LINT.EXPIRES date",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "no-fail",
+				Aliases:  []string{"exit-zero"},
+				Usage:    "print the findings but always exit zero, e.g. while rolling difflint out",
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "format",
+				Usage:    "output format: text, json, rdjson (reviewdog), junit, markdown, or bitbucket (Code Insights)",
+				Value:    formatText,
+				Required: false,
+			},
+			&cli.PathFlag{
+				Name:     "compare-to",
+				Usage:    "report only the findings that are not in the given --format=json output of a previous run, and those that were fixed since",
+				Required: false,
+			},
+			&cli.PathFlag{
+				Name:     "metrics-out",
+				Usage:    "also write to the given file how often each rule was triggered, satisfied, unsatisfied, or skipped for lack of changed targets (see the metrics merge command)",
+				Required: false,
+			},
+			&cli.IntFlag{
+				Name:     "max-findings",
+				Usage:    "print at most the given number of findings, noting how many more were truncated (0 for no limit)",
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "group-by",
+				Usage:    "group text output by rule or by target, or JSON output by the owner of the rule",
+				Value:    groupByRule,
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "link-template",
+				Usage:    "link files in markdown output, e.g. https://github.com/org/repo/blob/{sha}/{path}#L{line}",
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "sha",
+				Usage:    "commit that replaces {sha} in --link-template",
+				EnvVars:  []string{"GITHUB_SHA"},
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "color",
+				Usage:    "colorize output: auto, always, or never",
+				Value:    colorAuto,
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "show-satisfied",
+				Usage:    "also print the rules that are satisfied (implied by --verbose)",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "no-summary",
+				Usage:    "do not print the summary line to standard error",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "split-by-commit",
+				Usage:    "lint each commit of concatenated diffs (e.g. git log -p) separately",
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "commits",
+				Usage:    "lint the changes of the given git revision range (e.g. v1.2.0..HEAD) instead of reading a diff",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "per-commit",
+				Usage:    "with --commits, lint each commit separately against the tree as of that commit",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "watch",
+				Usage:    "re-lint the working tree against HEAD whenever files change",
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "explain",
+				Usage:    "explain how the rule with the given file:id key is evaluated instead of linting",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "follow-symlinks",
+				Usage:    "follow symbolic links while looking for rules instead of skipping them",
+				Required: false,
+			},
+			&cli.StringSliceFlag{
+				Name:     "allow-hidden",
+				Usage:    "glob pattern of hidden files and directories to search for rules, where ** matches any number of directories (default: .github/**)",
+				Required: false,
+			},
+			&cli.PathFlag{
+				Name:     "cache-dir",
+				Usage:    "directory in which parsed directives are cached (default: user cache directory)",
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "no-cache",
+				Usage:    "do not read or write the rule cache",
+				Required: false,
+			},
+			&cli.IntFlag{
+				Name:     "max-hunk-lines",
+				Usage:    "maximum number of changed lines stored per hunk (-1 for no limit)",
+				Value:    difflint.DefaultMaxHunkLines,
+				Required: false,
+			},
+			&cli.Int64Flag{
+				Name:     "max-diff-bytes",
+				Usage:    "fail if the diff is larger than the given number of bytes (0 for no limit)",
+				Required: false,
+			},
+			&cli.Int64Flag{
+				Name:     "max-file-size",
+				Usage:    "skip files larger than the given number of bytes while looking for rules (-1 for no limit)",
+				Value:    difflint.DefaultMaxFileSize,
+				Required: false,
+			},
+			&cli.BoolFlag{
+				Name:     "case-insensitive-paths",
+				Usage:    "match target paths to files regardless of case, as is the default on macOS and Windows",
+				Value:    runtime.GOOS == "darwin" || runtime.GOOS == "windows",
+				Required: false,
+			},
 			&cli.BoolFlag{
 				Name:     "verbose",
 				Usage:    "enable verbose logging",
 				Required: false,
 			},
 		},
-		Before: func(ctx *cli.Context) error {
-			if ctx.Bool("verbose") {
-				log.SetOutput(ctx.App.ErrWriter)
-				log.SetFlags(log.Ltime)
-			} else {
-				log.SetOutput(io.Discard)
-			}
-			return nil
-		},
-		After: func(ctx *cli.Context) error {
-			log.SetOutput(ctx.App.ErrWriter)
-			return nil
+		Commands: []*cli.Command{
+			newInitCommand(),
+			newDoctorCommand(),
+			newInstallHookCommand(),
+			newDirsCommand(),
+			newCacheCommand(),
+			newGraphCommand(),
+			newStatsCommand(),
+			newMetricsCommand(),
+			newIndexCommand(),
+			newLSPCommand(),
+			newServeCommand(),
+			newFilesCommand(),
+			newReportCommand(),
+			newVersionCommand(),
 		},
 		Action: action,
 	}
@@ -72,18 +384,588 @@ func NewApp() *App {
 }
 
 func action(ctx *cli.Context) error {
-	include := ctx.StringSlice("include")
-	exclude := ctx.StringSlice("exclude")
-	extMapPath := ctx.String("ext_map")
+	l, err := newLinter(ctx)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Bool("watch") {
+		return l.watch(ctx.Context)
+	}
+
+	if revisionRange := ctx.String("commits"); revisionRange != "" {
+		if ctx.Bool("per-commit") {
+			return l.lintCommits(ctx.Context, revisionRange)
+		}
 
-	unsatisfiedRules, err := difflint.Do(ctx.App.Reader, include, exclude, extMapPath)
+		r, err := difflint.GitDiff(ctx.Context, l.options.Root, revisionRange)
+		if err != nil {
+			return err
+		}
+
+		return l.lint(ctx.Context, r, nil)
+	}
+
+	r, err := diffReader(ctx, l.logger)
 	if err != nil {
 		return err
 	}
 
-	if len(unsatisfiedRules) > 0 {
-		return cli.Exit(unsatisfiedRules.String(), 1)
+	if ctx.Bool("split-by-commit") {
+		return l.lintByCommit(ctx.Context, r)
+	}
+
+	return l.lint(ctx.Context, r, nil)
+}
+
+// Groupings accepted by the --group-by flag.
+const (
+	groupByRule   = "rule"
+	groupByTarget = "target"
+	groupByOwner  = "owner"
+)
+
+// verboseChangeLines is the number of changed lines shown under each target
+// in verbose mode.
+const verboseChangeLines = 3
+
+// linter lints diffs with the options given on the command line.
+type linter struct {
+	// options is the template for the options of each lint; its Reader is
+	// set per lint.
+	options difflint.DoOptions
+
+	// logger receives verbose output.
+	logger *log.Logger
+
+	// color enables colorized output.
+	color bool
+
+	// format is the output format.
+	format string
+
+	// groupByTarget groups text output by target instead of by rule.
+	groupByTarget bool
+
+	// groupByOwner groups JSON output by the owner of the rule.
+	groupByOwner bool
+
+	// markdown renders the markdown format.
+	markdown markdownRenderer
+
+	// failOn is the minimum severity of unsatisfied rules that fails a lint.
+	failOn difflint.Severity
+
+	// failOnExpired fails a lint if any rule has expired.
+	failOnExpired bool
+
+	// noFail reports failing lints without failing them.
+	noFail bool
+
+	// maxFindings is the maximum number of findings that are printed, or
+	// zero for no limit.
+	maxFindings int
+
+	// comparePath is the previous JSON output given with --compare-to, if
+	// any.
+	comparePath string
+
+	// previous are the findings of the previous JSON output, against which
+	// results are compared if comparePath is set.
+	previous []difflint.Finding
+
+	// commits collects the findings of each commit of a lint by commit in
+	// the structured formats, which print them as one document once every
+	// commit is linted. It is shared by the copies of the linter.
+	commits *[]commitFindings
+
+	// commenter posts the results on a GitHub pull request, if set.
+	commenter *githubCommenter
+
+	// metricsOut is the file to which rule metrics are written, if any.
+	metricsOut string
+
+	// metrics are the rule metrics of the lints so far, written to
+	// metricsOut after each lint.
+	metrics *metrics
+
+	// showSatisfied enables the output of satisfied rules.
+	showSatisfied bool
+
+	// summary enables the summary line.
+	summary bool
+
+	// changeLines is the number of changed lines to show under each target.
+	changeLines int
+
+	// codeOwners annotates the missing targets with their owners, if set.
+	codeOwners *difflint.CodeOwners
+
+	// stdout and stderr are the writers to which output is written.
+	stdout, stderr io.Writer
+}
+
+// newLinter returns a new linter configured from the given command line.
+func newLinter(ctx *cli.Context) (*linter, error) {
+	logger := log.New(io.Discard, "", 0)
+	var changeLines int
+	if ctx.Bool("verbose") {
+		logger = log.New(ctx.App.ErrWriter, "", log.Ltime)
+		changeLines = verboseChangeLines
+	}
+
+	color, err := useColor(ctx.String("color"), ctx.App.Writer)
+	if err != nil {
+		return nil, err
+	}
+
+	format := ctx.String("format")
+	switch format {
+	case formatText, formatJSON, formatRDJSON, formatJUnit, formatMarkdown, formatBitbucket:
+	default:
+		return nil, fmt.Errorf("invalid format %q, expected %q, %q, %q, %q, %q, or %q", format, formatText, formatJSON, formatRDJSON, formatJUnit, formatMarkdown, formatBitbucket)
+	}
+
+	groupBy := ctx.String("group-by")
+	switch {
+	case groupBy != groupByRule && groupBy != groupByTarget && groupBy != groupByOwner:
+		return nil, fmt.Errorf("invalid group-by %q, expected %q, %q, or %q", groupBy, groupByRule, groupByTarget, groupByOwner)
+	case groupBy == groupByTarget && format != formatText:
+		return nil, fmt.Errorf("--group-by=%s is only supported with --format=%s", groupByTarget, formatText)
+	case groupBy == groupByOwner && format != formatJSON:
+		return nil, fmt.Errorf("--group-by=%s is only supported with --format=%s", groupByOwner, formatJSON)
+	}
+
+	failOn, err := difflint.ParseSeverity(ctx.String("fail-on"))
+	if err != nil {
+		return nil, err
+	}
+
+	filterScope, err := difflint.ParseFilterScope(ctx.String("filter-scope"))
+	if err != nil {
+		return nil, err
+	}
+
+	vcs, err := difflint.ParseVCS(ctx.String("vcs"))
+	if err != nil {
+		return nil, err
+	}
+
+	macros, err := targetMacros(ctx.StringSlice("target-macro"))
+	if err != nil {
+		return nil, err
+	}
+
+	root := ctx.String("root")
+	if root == "" {
+		if gitRoot, err := difflint.GitRoot(); err == nil {
+			root = gitRoot
+		} else {
+			logger.Printf("using current directory as root: %v", err)
+			root = "."
+		}
+	}
+
+	codeOwnersPath := ctx.String("codeowners")
+	if codeOwnersPath == "" {
+		codeOwnersPath = difflint.FindCodeOwners(root)
+	}
+
+	var codeOwners *difflint.CodeOwners
+	if codeOwnersPath != "" {
+		codeOwners, err = difflint.LoadCodeOwners(codeOwnersPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var commenter *githubCommenter
+	if ctx.Bool("comment") {
+		if ctx.String("github-pr") == "" {
+			return nil, fmt.Errorf("--comment requires --github-pr")
+		}
+
+		pr, err := difflint.ParseGitHubPullRequest(ctx.String("github-pr"))
+		if err != nil {
+			return nil, err
+		}
+
+		commenter = &githubCommenter{
+			baseURL: ctx.String("github-api-url"),
+			pr:      pr,
+			token:   ctx.String("github-token"),
+			client:  &http.Client{Timeout: ctx.Duration("diff-url-timeout")},
+		}
+	}
+
+	comparePath := ctx.Path("compare-to")
+	var previous []difflint.Finding
+	if comparePath != "" {
+		previous, err = loadPreviousFindings(comparePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var indexPath string
+	if ctx.Bool("use-index") {
+		indexPath = ctx.Path("index-file")
+		if indexPath == "" {
+			indexPath = filepath.Join(root, difflint.DefaultIndexFile)
+		}
+	}
+
+	return &linter{
+		options: difflint.DoOptions{
+			LintOptions: difflint.LintOptions{
+				Root:                    root,
+				Include:                 ctx.StringSlice("include"),
+				Exclude:                 ctx.StringSlice("exclude"),
+				FilterScope:             filterScope,
+				StrictDirectives:        ctx.Bool("strict-directives"),
+				WarnMismatchedTemplates: ctx.Bool("warn-mismatched-templates"),
+				DirectiveWord:           ctx.String("directive-word"),
+				CommentsOnly:            ctx.StringSlice("comments-only"),
+				TargetMacros:            macros,
+				StripPrefixes:           ctx.StringSlice("strip-prefix"),
+				Logger:                  logger,
+				SkipRules:               ctx.StringSlice("skip-rule"),
+				OnlyRules:               ctx.StringSlice("only-rule"),
+				SkipUnnamed:             ctx.Bool("skip-unnamed"),
+				OnlyTags:                ctx.StringSlice("only-tags"),
+				SkipTags:                ctx.StringSlice("skip-tags"),
+				Explain:                 ctx.String("explain"),
+				RelativeTargets:         ctx.Bool("relative-targets"),
+				StrictPresence:          ctx.Bool("strict-presence"),
+				ContentOnly:             ctx.Bool("content-only"),
+				ExclusiveMarkers:        ctx.Bool("exclusive-markers"),
+				BlankBlocksEmpty:        ctx.Bool("blank-blocks-empty"),
+				VCS:                     vcs,
+				CacheDir:                cacheDir(ctx),
+				FollowSymlinks:          ctx.Bool("follow-symlinks"),
+				AllowHidden:             ctx.StringSlice("allow-hidden"),
+				MaxHunkLines:            ctx.Int("max-hunk-lines"),
+				MaxDiffBytes:            ctx.Int64("max-diff-bytes"),
+				MaxFileSize:             ctx.Int64("max-file-size"),
+				CaseInsensitivePaths:    ctx.Bool("case-insensitive-paths"),
+			},
+			ExtMapPath: ctx.String("ext_map"),
+			RulesPath:  ctx.String("rules"),
+			IndexPath:  indexPath,
+		},
+		logger:        logger,
+		color:         color,
+		format:        format,
+		groupByTarget: groupBy == groupByTarget,
+		groupByOwner:  groupBy == groupByOwner,
+		markdown: markdownRenderer{
+			linkTemplate: ctx.String("link-template"),
+			sha:          ctx.String("sha"),
+		},
+		failOn:        failOn,
+		failOnExpired: ctx.Bool("fail-on-expired"),
+		noFail:        ctx.Bool("no-fail"),
+		maxFindings:   ctx.Int("max-findings"),
+		comparePath:   comparePath,
+		previous:      previous,
+		commenter:     commenter,
+		metricsOut:    ctx.Path("metrics-out"),
+		metrics:       &metrics{},
+		showSatisfied: ctx.Bool("show-satisfied") || ctx.Bool("verbose"),
+		summary:       !ctx.Bool("no-summary"),
+		changeLines:   changeLines,
+		codeOwners:    codeOwners,
+		stdout:        ctx.App.Writer,
+		stderr:        ctx.App.ErrWriter,
+	}, nil
+}
+
+// targetMacros returns the default target macros extended with the given
+// --target-macro definitions.
+func targetMacros(specs []string) (map[string]map[string]string, error) {
+	macros := make(map[string]map[string]string, len(difflint.DefaultTargetMacros)+len(specs))
+	for macro, byExt := range difflint.DefaultTargetMacros {
+		macros[macro] = make(map[string]string, len(byExt))
+		for ext, target := range byExt {
+			macros[macro][ext] = target
+		}
+	}
+
+	for _, spec := range specs {
+		name, target, found := strings.Cut(spec, "=")
+		if !found || !strings.HasPrefix(name, "@") || len(name) == 1 || target == "" {
+			return nil, fmt.Errorf("invalid target macro %q, expected @name=target or @name.ext=target", spec)
+		}
+
+		macro, ext, _ := strings.Cut(name, ".")
+		if macros[macro] == nil {
+			macros[macro] = make(map[string]string)
+		}
+
+		macros[macro][ext] = target
+	}
+
+	return macros, nil
+}
+
+// diffReader returns a reader over the diff given on the command line: a
+// URL, a GitHub pull request, patch files, or standard input. The given
+// logger receives verbose output.
+func diffReader(ctx *cli.Context, logger *log.Logger) (io.Reader, error) {
+	if pr := ctx.String("github-pr"); pr != "" {
+		pullRequest, err := difflint.ParseGitHubPullRequest(pr)
+		if err != nil {
+			return nil, err
+		}
+
+		return difflint.FetchGitHubDiff(ctx.Context, difflint.FetchGitHubDiffOptions{
+			PullRequest: pullRequest,
+			BaseURL:     ctx.String("github-api-url"),
+			Token:       ctx.String("github-token"),
+			Timeout:     ctx.Duration("diff-url-timeout"),
+			Logger:      logger,
+		})
+	}
+
+	if diffURL := ctx.String("diff-url"); diffURL != "" {
+		return difflint.FetchDiff(ctx.Context, difflint.FetchDiffOptions{
+			URL:     diffURL,
+			Headers: ctx.StringSlice("header"),
+			Timeout: ctx.Duration("diff-url-timeout"),
+		})
+	}
+
+	if ctx.NArg() > 0 {
+		if stdinHasData(ctx.App.Reader) {
+			fmt.Fprintln(ctx.App.ErrWriter, "warning: ignoring standard input in favor of patch files")
+		}
+
+		return difflint.OpenPatches(ctx.Args().Slice())
+	}
+
+	if isTerminal(ctx.App.Reader) {
+		return nil, cli.Exit("difflint: no diff on standard input; pipe one in (e.g. git diff | difflint) or pass patch files", 2)
+	}
+
+	return ctx.App.Reader, nil
+}
+
+// lintByCommit splits the concatenated diffs read from r by commit and lints
+// each one separately, labeling the results with the commit.
+func (l *linter) lintByCommit(ctx context.Context, r io.Reader) error {
+	segments, err := difflint.SplitDiffs(r)
+	if err != nil {
+		return err
+	}
+
+	// Input without commit headers is a single diff.
+	if len(segments) == 1 && segments[0].Commit == "" {
+		return l.lint(ctx, bytes.NewReader(segments[0].Diff), nil)
+	}
+
+	l.collectCommits()
+	var exitErr error
+	for _, segment := range segments {
+		err := l.lint(ctx, bytes.NewReader(segment.Diff), &difflint.GitCommit{SHA: segment.Commit})
+		if _, ok := err.(cli.ExitCoder); ok {
+			exitErr = err
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := l.renderCommits(); err != nil {
+		return err
+	}
+
+	return exitErr
+}
+
+// lint lints the diff read from r and prints the results, under the given
+// commit if any. It returns an exit error if any unsatisfied rule is at or
+// above the fail-on severity.
+func (l *linter) lint(ctx context.Context, r io.Reader, commit *difflint.GitCommit) error {
+	options := l.options
+	options.Reader = r
+	result, err := difflint.DoWith(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(l.stderr, "warning: %s\n", warning)
+	}
+
+	if result.Explanation != nil {
+		return (renderer{color: l.color}).renderExplanation(l.stdout, result.Explanation)
+	}
+
+	// Each lint, e.g. of a commit with --split-by-commit, counts as a run.
+	if l.metricsOut != "" {
+		l.metrics.add(result)
+		if err := l.metrics.writeFile(l.metricsOut); err != nil {
+			return err
+		}
+	}
+
+	// Only the findings that are new since the previous run remain in the
+	// result, so that they alone fail the lint.
+	var comparison *difflint.Comparison
+	if l.comparePath != "" {
+		var c difflint.Comparison
+		result, c = difflint.CompareResult(result, l.previous)
+		comparison = &c
+	}
+
+	if err := l.render(result, commit, comparison); err != nil {
+		return err
+	}
+
+	if l.commenter != nil {
+		findings := difflint.BuildFindings(result)
+		l.codeOwners.Annotate(findings)
+		if err := l.comment(ctx, findings, comparison); err != nil {
+			return err
+		}
+	}
+
+	if l.summary {
+		s := summary(result)
+		if comparison != nil {
+			s += ", " + comparisonSummary(comparison, l.comparePath)
+		}
+
+		if l.noFail && l.fails(result) {
+			s += " (not failing with --no-fail)"
+		}
+
+		fmt.Fprintln(l.stderr, s)
+	}
+
+	if l.blocks(result) {
+		return cli.Exit("", 1)
 	}
 
 	return nil
 }
+
+// fails returns true if the given result fails the lint: an unsatisfied
+// rule is at or above the fail-on severity, or a rule expired and expired
+// rules fail the lint.
+func (l *linter) fails(result *difflint.LintResult) bool {
+	for _, rule := range result.UnsatisfiedRules {
+		if rule.Rule.Severity.AtLeast(l.failOn) {
+			return true
+		}
+	}
+
+	if len(result.EmptyRules) > 0 && difflint.SeverityWarn.AtLeast(l.failOn) {
+		return true
+	}
+
+	return l.failOnExpired && len(result.ExpiredRules) > 0
+}
+
+// blocks returns true if the given result fails the lint and failures are
+// not ignored with --no-fail, so that difflint exits with status 1.
+func (l *linter) blocks(result *difflint.LintResult) bool {
+	return !l.noFail && l.fails(result)
+}
+
+// render writes the results to standard output in the configured format,
+// under the given commit if any, along with the findings fixed since the
+// previous run if the result was compared to one. The structured formats
+// collect the findings of a commit instead if commits are collected.
+func (l *linter) render(result *difflint.LintResult, commit *difflint.GitCommit, comparison *difflint.Comparison) error {
+	findings := difflint.BuildFindings(result)
+	l.codeOwners.Annotate(findings)
+	total := len(findings)
+	findings = truncateFindings(findings, l.maxFindings)
+	truncated := total - len(findings)
+
+	if commit != nil && l.commits != nil {
+		*l.commits = append(*l.commits, commitFindings{
+			commit:     *commit,
+			findings:   findings,
+			total:      total,
+			comparison: comparison,
+			failed:     l.blocks(result),
+		})
+		return nil
+	}
+
+	// Structured formats record the truncation on standard error, except
+	// JSON, which records the total itself.
+	notice := l.stderr
+	var err error
+	switch l.format {
+	case formatJSON:
+		if l.groupByOwner {
+			err = renderJSONByOwner(l.stdout, findings, total, comparison)
+		} else {
+			err = renderJSON(l.stdout, findings, total, comparison)
+		}
+		notice = nil
+	case formatRDJSON:
+		err = renderRDJSON(l.stdout, findings)
+	case formatJUnit:
+		err = renderJUnit(l.stdout, findings)
+	case formatMarkdown:
+		err = l.markdown.render(l.stdout, findings, comparison)
+		notice = l.stdout
+	case formatBitbucket:
+		err = renderBitbucket(l.stdout, findings, l.blocks(result))
+	default:
+		if commit != nil && (len(result.UnsatisfiedRules) > 0 || len(result.ExpiredRules) > 0 || len(result.EmptyRules) > 0) {
+			fmt.Fprintf(l.stdout, "commit %s\n", commitLabel(*commit))
+		}
+
+		r := renderer{color: l.color, changeLines: l.changeLines}
+		err = r.renderFindings(l.stdout, findings, l.groupByTarget, l.showSatisfied, comparison)
+		notice = l.stdout
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if truncated > 0 && notice != nil {
+		fmt.Fprintf(notice, "\u2026 and %d more findings truncated\n", truncated)
+	}
+
+	return nil
+}
+
+// truncateFindings returns the first max of the given findings, which are
+// sorted so that the same ones are kept across runs, or all of them if max
+// is not positive.
+func truncateFindings(findings []difflint.Finding, max int) []difflint.Finding {
+	if max <= 0 || len(findings) <= max {
+		return findings
+	}
+
+	return findings[:max]
+}
+
+// stdinHasData returns true if the given reader is a pipe or a non-empty
+// regular file.
+func stdinHasData(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	if info.Mode()&os.ModeNamedPipe != 0 {
+		return true
+	}
+
+	return info.Mode().IsRegular() && info.Size() > 0
+}
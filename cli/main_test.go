@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+// runApp runs difflint with the given standard input and arguments and
+// returns what it wrote to standard output and standard error. Exit errors
+// are returned rather than exiting the test binary.
+func runApp(t *testing.T, stdin io.Reader, args ...string) (string, string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	app := NewApp()
+	app.Reader = stdin
+	app.Writer = &stdout
+	app.ErrWriter = &stderr
+	app.ExitErrHandler = func(*cli.Context, error) {}
+	err := app.Run(append([]string{"difflint"}, args...))
+	return stdout.String(), stderr.String(), err
+}
+
+// writeFiles writes files relative to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPatchFilesIgnoreStdin(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.go":  "package a\n\n//LINT.IF b.go\nvar X = 2\n//LINT.END\n",
+		"b.go":  "package b\n\nvar Y = 2\n",
+		"stdin": "diff --git a/c.go b/c.go\n--- a/c.go\n+++ b/c.go\n@@ -1,1 +1,1 @@\n-var Z = 1\n+var Z = 2\n",
+	})
+
+	patch, err := filepath.Abs("../testdata/two-commits.patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdin, err := os.Open(filepath.Join(dir, "stdin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+
+	_, stderr, err := runApp(t, stdin, "--root", dir, "--no-cache", patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(stderr, "warning: ignoring standard input in favor of patch files") {
+		t.Errorf("stderr = %q, want the warning about standard input", stderr)
+	}
+}
+
+func TestNoFailExitCode(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.go": "package a\n\n//LINT.IF b.go\nvar X = 1\n//LINT.END\n",
+		"b.go": "package b\n\nvar Y = 2\n",
+	})
+	diff := "diff --git a/b.go b/b.go\n--- a/b.go\n+++ b/b.go\n@@ -1,3 +1,3 @@\n package b\n \n-var Y = 1\n+var Y = 2\n"
+
+	for _, format := range []string{formatText, formatRDJSON} {
+		for _, flags := range [][]string{nil, {"--no-fail"}, {"--exit-zero"}} {
+			noFail := len(flags) > 0
+			args := append([]string{"--root", dir, "--no-cache", "--format", format}, flags...)
+			stdout, stderr, err := runApp(t, strings.NewReader(diff), args...)
+
+			code := 0
+			if exit, ok := err.(cli.ExitCoder); ok {
+				code = exit.ExitCode()
+			} else if err != nil {
+				t.Fatalf("difflint %v: %v", args, err)
+			}
+
+			if want := map[bool]int{false: 1, true: 0}[noFail]; code != want {
+				t.Errorf("difflint %v exited with %d, want %d", args, code, want)
+			}
+
+			if !strings.Contains(stdout, "a.go") {
+				t.Errorf("difflint %v = %q, want the finding of a.go", args, stdout)
+			}
+
+			summary := "difflint: 2 files scanned, 1 rules, 1 unsatisfied (1 files)"
+			if noFail {
+				summary += " (not failing with --no-fail)"
+			}
+
+			if !strings.Contains(stderr, summary+"\n") {
+				t.Errorf("difflint %v stderr = %q, want %q", args, stderr, summary)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ethanthatonekid/difflint"
+)
+
+// formatMarkdown is the --format value for Markdown output.
+const formatMarkdown = "markdown"
+
+// markdownMaxInlineTargets is the number of missing targets above which the
+// targets of a rule are collapsed into a details block.
+const markdownMaxInlineTargets = 3
+
+// markdownRenderer renders lint results as Markdown, e.g. for a PR comment.
+type markdownRenderer struct {
+	// linkTemplate is the template of the links to files, with the {sha},
+	// {path}, and {line} placeholders. Files are not linked if it is empty.
+	linkTemplate string
+
+	// sha replaces the {sha} placeholder of the link template.
+	sha string
+}
+
+// render writes the unsatisfied and expired rules among the given findings
+// to w as a Markdown table, followed by the findings fixed since the
+// previous run if the given comparison has any.
+func (r markdownRenderer) render(w io.Writer, findings []difflint.Finding, comparison *difflint.Comparison) error {
+	var b strings.Builder
+	for _, f := range findings {
+		if f.Kind == difflint.FindingSatisfied {
+			continue
+		}
+
+		if b.Len() == 0 {
+			b.WriteString("| File | Lines | Rule | Missing targets | Message |\n")
+			b.WriteString("| --- | --- | --- | --- | --- |\n")
+		}
+
+		r.writeRow(&b, f)
+	}
+
+	if b.Len() == 0 {
+		b.WriteString("✅ difflint: all rules satisfied\n")
+	}
+
+	if comparison != nil && len(comparison.Fixed) > 0 {
+		b.WriteString("\n<details><summary>Fixed since the previous run</summary>\n\n")
+		b.WriteString("| File | Lines | Rule | Missing targets | Message |\n")
+		b.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, f := range comparison.Fixed {
+			r.writeRow(&b, f)
+		}
+
+		b.WriteString("\n</details>\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeRow writes a table row for the given finding to b.
+func (r markdownRenderer) writeRow(b *strings.Builder, f difflint.Finding) {
+	file := "`" + markdownEscape(f.RuleFile) + "`"
+	lines := fmt.Sprintf("%d-%d", f.StartLine, f.EndLine)
+	if f.Config {
+		lines = "rules file"
+	} else if r.linkTemplate != "" {
+		file = fmt.Sprintf("[%s](%s)", file, r.link(f.RuleFile, f.StartLine))
+	}
+
+	var id string
+	if f.RuleID != "" {
+		id = "`" + markdownEscape(f.RuleID) + "`"
+	}
+
+	keys := targetKeys(f.MissingTargets)
+	for i, key := range keys {
+		keys[i] = "`" + markdownEscape(key) + "`"
+	}
+
+	targets := strings.Join(keys, "<br>")
+	if len(keys) > markdownMaxInlineTargets {
+		targets = fmt.Sprintf("<details><summary>%d targets</summary>%s</details>", len(keys), targets)
+	}
+
+	message := f.Message
+	if f.Kind == difflint.FindingExpired || f.Kind == difflint.FindingEmpty {
+		message = strings.TrimPrefix(message, "rule ")
+	}
+
+	if f.Note != "" {
+		message += " (note: " + f.Note + ")"
+	}
+
+	if f.Owner != "" {
+		message += " (owner: " + f.Owner + ")"
+	}
+
+	fmt.Fprintf(b, "| %s | %s | %s | %s | %s |\n", file, lines, id, targets, markdownEscape(message))
+}
+
+// link returns the link to the given line of the given file.
+func (r markdownRenderer) link(path string, line int) string {
+	return strings.NewReplacer(
+		"{sha}", r.sha,
+		"{path}", path,
+		"{line}", strconv.Itoa(line),
+	).Replace(r.linkTemplate)
+}
+
+// markdownEscape escapes the characters of s that would break a table cell.
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
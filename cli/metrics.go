@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/urfave/cli/v2"
+)
+
+// metrics are the per-rule counters written with --metrics-out, summed over
+// one or more runs.
+type metrics struct {
+	Difflint jsonMeta `json:"difflint"`
+
+	// Runs is the number of lints counted.
+	Runs int `json:"runs"`
+
+	// Rules are the counters of each rule that fired, sorted by key.
+	Rules []ruleMetrics `json:"rules"`
+}
+
+// ruleMetrics counts how often a rule fired and with what outcome.
+type ruleMetrics struct {
+	// Key identifies the rule across runs as file:id, or as file:start-end
+	// for a rule without an ID.
+	Key string `json:"key"`
+
+	// RuleFile is the file of the rule.
+	RuleFile string `json:"rule_file"`
+
+	// RuleID is the ID of the rule, if any.
+	RuleID string `json:"rule_id,omitempty"`
+
+	// Triggered counts the lints in which the rule's block changed.
+	Triggered int `json:"triggered"`
+
+	// Satisfied counts the lints in which the rule changed along with its
+	// targets.
+	Satisfied int `json:"satisfied"`
+
+	// Unsatisfied counts the lints in which the rule was not satisfied.
+	Unsatisfied int `json:"unsatisfied"`
+
+	// SkippedByPresence counts the lints in which the rule changed while
+	// none of its targets did.
+	SkippedByPresence int `json:"skipped_by_presence"`
+}
+
+// ruleMetricsKey returns the key of the given rule in metrics.
+func ruleMetricsKey(rule difflint.Rule) string {
+	if rule.ID != nil {
+		return fmt.Sprintf("%s:%s", rule.Hunk.File, *rule.ID)
+	}
+
+	return fmt.Sprintf("%s:%d-%d", rule.Hunk.File, rule.Hunk.Range.Start, rule.Hunk.Range.End)
+}
+
+// add counts the evaluated rules of the given lint result as one more run.
+func (m *metrics) add(result *difflint.LintResult) {
+	m.Runs++
+	current := make([]ruleMetrics, 0, len(result.EvaluatedRules))
+	for _, rule := range result.EvaluatedRules {
+		r := ruleMetrics{Key: ruleMetricsKey(rule.Rule), RuleFile: rule.Hunk.File}
+		if rule.ID != nil {
+			r.RuleID = *rule.ID
+		}
+
+		if rule.Present {
+			r.Triggered = 1
+		}
+
+		switch rule.Outcome {
+		case difflint.RuleOutcomeSatisfied:
+			r.Satisfied = 1
+		case difflint.RuleOutcomeUnsatisfied:
+			r.Unsatisfied = 1
+		case difflint.RuleOutcomeSkippedByPresence:
+			r.SkippedByPresence = 1
+		}
+
+		current = append(current, r)
+	}
+
+	m.merge(metrics{Rules: current})
+}
+
+// merge adds the counters of other, but not its runs, to m.
+func (m *metrics) merge(other metrics) {
+	index := make(map[string]int, len(m.Rules))
+	for i, r := range m.Rules {
+		index[r.Key] = i
+	}
+
+	for _, r := range other.Rules {
+		i, ok := index[r.Key]
+		if !ok {
+			index[r.Key] = len(m.Rules)
+			m.Rules = append(m.Rules, r)
+			continue
+		}
+
+		m.Rules[i].Triggered += r.Triggered
+		m.Rules[i].Satisfied += r.Satisfied
+		m.Rules[i].Unsatisfied += r.Unsatisfied
+		m.Rules[i].SkippedByPresence += r.SkippedByPresence
+	}
+
+	sort.Slice(m.Rules, func(i, j int) bool {
+		return m.Rules[i].Key < m.Rules[j].Key
+	})
+}
+
+// write writes the metrics to w as indented JSON.
+func (m *metrics) write(w io.Writer) error {
+	out := *m
+	out.Difflint = newJSONMeta()
+	if out.Rules == nil {
+		out.Rules = []ruleMetrics{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// writeFile writes the metrics to the given file.
+func (m *metrics) writeFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := m.write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// readMetrics reads the metrics written with --metrics-out to the given file.
+func readMetrics(path string) (metrics, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return metrics{}, err
+	}
+
+	var m metrics
+	if err := json.Unmarshal(data, &m); err != nil {
+		return metrics{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+// newMetricsCommand returns the metrics subcommand.
+func newMetricsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "metrics",
+		Usage: "work with the rule metrics written with --metrics-out",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "merge",
+				Usage:     "sum the counters of metrics files, e.g. of several CI runs, and print them",
+				ArgsUsage: "<metrics files...>",
+				Action:    metricsMergeAction,
+			},
+		},
+	}
+}
+
+func metricsMergeAction(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return fmt.Errorf("metrics merge requires at least one metrics file")
+	}
+
+	var merged metrics
+	for _, path := range ctx.Args().Slice() {
+		m, err := readMetrics(path)
+		if err != nil {
+			return err
+		}
+
+		merged.Runs += m.Runs
+		merged.merge(m)
+	}
+
+	return merged.write(ctx.App.Writer)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ethanthatonekid/difflint"
+)
+
+// Output formats accepted by the --format flag.
+const (
+	formatText   = "text"
+	formatRDJSON = "rdjson"
+)
+
+// rdjsonResult is the top-level object of reviewdog's Diagnostic Format.
+// See https://github.com/reviewdog/reviewdog/tree/master/proto/rdf.
+type rdjsonResult struct {
+	Source      rdjsonSource       `json:"source"`
+	Diagnostics []rdjsonDiagnostic `json:"diagnostics"`
+}
+
+// rdjsonSource names the tool that produced the diagnostics.
+type rdjsonSource struct {
+	Name string `json:"name"`
+}
+
+// rdjsonDiagnostic is a single finding.
+type rdjsonDiagnostic struct {
+	Message  string         `json:"message"`
+	Location rdjsonLocation `json:"location"`
+	Severity string         `json:"severity"`
+	Code     *rdjsonCode    `json:"code,omitempty"`
+}
+
+// rdjsonLocation is the file and line range of a finding.
+type rdjsonLocation struct {
+	Path  string      `json:"path"`
+	Range rdjsonRange `json:"range"`
+}
+
+// rdjsonRange is a line range; columns are omitted.
+type rdjsonRange struct {
+	Start rdjsonPosition `json:"start"`
+	End   rdjsonPosition `json:"end"`
+}
+
+// rdjsonPosition is a position in a file.
+type rdjsonPosition struct {
+	Line int `json:"line"`
+}
+
+// rdjsonCode identifies the rule of a finding.
+type rdjsonCode struct {
+	Value string `json:"value"`
+}
+
+// renderRDJSON writes the unsatisfied and expired rules among the given
+// findings to w in reviewdog's Diagnostic Format.
+func renderRDJSON(w io.Writer, findings []difflint.Finding) error {
+	return writeRDJSON(w, rdjsonDiagnostics(findings, ""))
+}
+
+// renderRDJSONCommits writes the unsatisfied and expired rules of the given
+// commits to w as one reviewdog result, with the abbreviated hash of the
+// commit at the start of each message.
+func renderRDJSONCommits(w io.Writer, commits []commitFindings) error {
+	var diagnostics []rdjsonDiagnostic
+	for _, c := range commits {
+		diagnostics = append(diagnostics, rdjsonDiagnostics(c.findings, shortSHA(c.commit)+": ")...)
+	}
+
+	return writeRDJSON(w, diagnostics)
+}
+
+// writeRDJSON writes the given diagnostics to w in reviewdog's Diagnostic
+// Format.
+func writeRDJSON(w io.Writer, diagnostics []rdjsonDiagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []rdjsonDiagnostic{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rdjsonResult{
+		Source:      rdjsonSource{Name: "difflint"},
+		Diagnostics: diagnostics,
+	})
+}
+
+// rdjsonDiagnostics returns the diagnostics of the unsatisfied and expired
+// rules among the given findings, with messages that start with prefix.
+func rdjsonDiagnostics(findings []difflint.Finding, prefix string) []rdjsonDiagnostic {
+	var diagnostics []rdjsonDiagnostic
+	for _, f := range findings {
+		if f.Kind == difflint.FindingSatisfied {
+			continue
+		}
+
+		d := rdjsonDiagnostic{
+			Message: prefix + diagnosticMessage(f),
+			Location: rdjsonLocation{
+				Path: f.RuleFile,
+				Range: rdjsonRange{
+					Start: rdjsonPosition{Line: f.StartLine},
+					End:   rdjsonPosition{Line: f.EndLine},
+				},
+			},
+			Severity: rdjsonSeverity(f.Severity),
+		}
+
+		if f.RuleID != "" {
+			d.Code = &rdjsonCode{Value: f.RuleID}
+		}
+
+		diagnostics = append(diagnostics, d)
+	}
+
+	return diagnostics
+}
+
+// diagnosticMessage returns the one-line message of the given finding in
+// code review diagnostics: the missing targets of an unsatisfied rule with
+// their owners, or the finding's message, followed by the rule's owner.
+func diagnosticMessage(f difflint.Finding) string {
+	if f.Kind != difflint.FindingUnsatisfied {
+		return withRuleOwner(f.Message, f)
+	}
+
+	targets := targetKeys(f.MissingTargets)
+	for i, target := range f.MissingTargets {
+		if len(target.Owners) > 0 {
+			targets[i] += " (owners: " + strings.Join(target.Owners, " ") + ")"
+		}
+	}
+
+	message := fmt.Sprintf("rule not satisfied for targets: %s", strings.Join(targets, ", "))
+	if f.Note != "" {
+		message += " (note: " + f.Note + ")"
+	}
+
+	if f.Config {
+		message += " (declared in the rules file)"
+	}
+
+	return withRuleOwner(message, f)
+}
+
+// withRuleOwner appends the owner of the finding's rule, if any, to the
+// given message.
+func withRuleOwner(message string, f difflint.Finding) string {
+	if f.Owner == "" {
+		return message
+	}
+
+	return message + " (owner: " + f.Owner + ")"
+}
+
+// rdjsonSeverity returns the reviewdog severity of the given severity.
+func rdjsonSeverity(severity difflint.Severity) string {
+	switch severity {
+	case difflint.SeverityError:
+		return "ERROR"
+	case difflint.SeverityWarn:
+		return "WARNING"
+	default:
+		return "INFO"
+	}
+}
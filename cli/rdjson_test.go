@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderRDJSON(t *testing.T) {
+	var b strings.Builder
+	if err := renderRDJSON(&b, goldenFindings()); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "testdata/rdjson.golden", b.String())
+}
+
+func TestRenderRDJSONEmpty(t *testing.T) {
+	var b strings.Builder
+	if err := renderRDJSON(&b, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "testdata/rdjson_empty.golden", b.String())
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ethanthatonekid/difflint"
+)
+
+// ANSI escape codes used to colorize terminal output.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// Color modes accepted by the --color flag.
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// useColor reports whether output written to w should be colorized in the
+// given color mode.
+func useColor(mode string, w io.Writer) (bool, error) {
+	switch mode {
+	case colorAlways:
+		return true, nil
+	case colorNever:
+		return false, nil
+	case colorAuto, "":
+		return isTerminal(w), nil
+	default:
+		return false, fmt.Errorf("invalid color mode %q, expected %q, %q, or %q", mode, colorAuto, colorAlways, colorNever)
+	}
+}
+
+// isTerminal returns true if the given reader or writer is a terminal.
+func isTerminal(rw any) bool {
+	f, ok := rw.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// renderer renders lint results for the terminal.
+type renderer struct {
+	// color enables ANSI colors.
+	color bool
+
+	// changeLines is the number of changed lines to show under each target.
+	changeLines int
+}
+
+// paint wraps s in the given ANSI code if color is enabled.
+func (r renderer) paint(code, s string) string {
+	if !r.color {
+		return s
+	}
+
+	return code + s + ansiReset
+}
+
+// renderFindings writes the findings to w: unsatisfied rules with the
+// targets of each rule aligned in a column, or grouped by target if
+// groupByTarget is set, followed by the satisfied rules if showSatisfied is
+// set, the expired and empty rules, and the findings fixed since the
+// previous run if the given comparison has any.
+func (r renderer) renderFindings(w io.Writer, findings []difflint.Finding, groupByTarget, showSatisfied bool, comparison *difflint.Comparison) error {
+	var b strings.Builder
+	if groupByTarget {
+		r.writeTargetGroups(&b, findings)
+	}
+
+	for _, f := range findings {
+		switch f.Kind {
+		case difflint.FindingUnsatisfied:
+			if !groupByTarget {
+				r.writeUnsatisfied(&b, f)
+			}
+		case difflint.FindingSatisfied:
+			if showSatisfied {
+				r.writeSatisfied(&b, f)
+			}
+		case difflint.FindingExpired:
+			r.writeExpired(&b, f)
+		case difflint.FindingEmpty:
+			r.writeEmpty(&b, f)
+		}
+	}
+
+	r.writeFixed(&b, comparison)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeRule writes the location and ID of the finding's rule to b.
+func (r renderer) writeRule(b *strings.Builder, f difflint.Finding) {
+	b.WriteString(r.paint(ansiCyan, f.RuleFile))
+	if f.Config {
+		b.WriteString(r.paint(ansiDim, " (rules file)"))
+	} else {
+		b.WriteString(r.paint(ansiDim, fmt.Sprintf(":%d-%d", f.StartLine, f.EndLine)))
+	}
+	if f.RuleID != "" {
+		b.WriteString(" (id: ")
+		b.WriteString(f.RuleID)
+		b.WriteString(")")
+	}
+	if f.Owner != "" {
+		b.WriteString(" (owner: ")
+		b.WriteString(f.Owner)
+		b.WriteString(")")
+	}
+}
+
+// writeUnsatisfied writes an unsatisfied rule to b with its missing targets
+// aligned in a column.
+func (r renderer) writeUnsatisfied(b *strings.Builder, f difflint.Finding) {
+	b.WriteString(r.paint(severityColor(f.Severity), string(f.Severity)))
+	b.WriteString(": rule ")
+	r.writeRule(b, f)
+	b.WriteString(" ")
+	b.WriteString(r.paint(ansiRed, "not satisfied"))
+	b.WriteString(" for targets:\n")
+
+	var width int
+	for _, target := range f.MissingTargets {
+		if len(target.Label()) > width {
+			width = len(target.Label())
+		}
+	}
+
+	for _, target := range f.MissingTargets {
+		var note string
+		if target.Block {
+			if target.Range != nil {
+				note = r.paint(ansiDim, fmt.Sprintf("(lines %d-%d)", target.Range.Start, target.Range.End))
+			} else {
+				note = "(target block not found)"
+			}
+		}
+
+		if len(target.Owners) > 0 {
+			owners := r.paint(ansiDim, "(owners: "+strings.Join(target.Owners, " ")+")")
+			if note != "" {
+				note += " "
+			}
+			note += owners
+		}
+
+		b.WriteString("  ")
+		b.WriteString(r.paint(ansiYellow, target.Label()))
+		if note != "" {
+			b.WriteString(strings.Repeat(" ", width-len(target.Label())+1))
+			b.WriteString(note)
+		}
+		b.WriteString("\n")
+		r.renderChanges(b, target.Changes)
+	}
+
+	if f.Note != "" {
+		b.WriteString(r.paint(ansiDim, "  note: "+f.Note))
+		b.WriteString("\n")
+	}
+}
+
+// writeTargetGroups writes the unsatisfied rules to b grouped by the target
+// that they require a change to.
+func (r renderer) writeTargetGroups(b *strings.Builder, findings []difflint.Finding) {
+	groups := make(map[string][]difflint.Finding)
+	var keys []string
+	for _, f := range findings {
+		for _, target := range f.MissingTargets {
+			if _, ok := groups[target.Key]; !ok {
+				keys = append(keys, target.Key)
+			}
+
+			groups[target.Key] = append(groups[target.Key], f)
+		}
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteString("target ")
+		b.WriteString(r.paint(ansiYellow, key))
+		b.WriteString(" changed; ")
+		b.WriteString(r.paint(ansiRed, "missing changes"))
+		b.WriteString(" to rules:\n")
+		for _, f := range groups[key] {
+			b.WriteString("  ")
+			b.WriteString(r.paint(severityColor(f.Severity), string(f.Severity)))
+			b.WriteString(" ")
+			r.writeRule(b, f)
+			b.WriteString("\n")
+		}
+	}
+}
+
+// writeSatisfied writes a satisfied rule to b along with the changed targets
+// that triggered it.
+func (r renderer) writeSatisfied(b *strings.Builder, f difflint.Finding) {
+	b.WriteString(r.paint(ansiDim, "ok"))
+	b.WriteString(": rule ")
+	r.writeRule(b, f)
+	b.WriteString(" satisfied by targets: ")
+	b.WriteString(strings.Join(targetKeys(f.SatisfiedTargets), ", "))
+	b.WriteString("\n")
+}
+
+// writeExpired writes an expired rule to b.
+func (r renderer) writeExpired(b *strings.Builder, f difflint.Finding) {
+	b.WriteString(r.paint(ansiYellow, "expired"))
+	b.WriteString(": rule ")
+	r.writeRule(b, f)
+	b.WriteString(" ")
+	b.WriteString(strings.TrimPrefix(f.Message, "rule "))
+	b.WriteString("\n")
+}
+
+// writeEmpty writes an empty rule to b.
+func (r renderer) writeEmpty(b *strings.Builder, f difflint.Finding) {
+	b.WriteString(r.paint(ansiYellow, "empty"))
+	b.WriteString(": rule ")
+	r.writeRule(b, f)
+	b.WriteString(" ")
+	b.WriteString(strings.TrimPrefix(f.Message, "rule "))
+	b.WriteString("\n")
+}
+
+// targetKeys returns the keys of the given targets, labeled with their
+// macros.
+func targetKeys(targets []difflint.TargetRef) []string {
+	keys := make([]string, 0, len(targets))
+	for _, target := range targets {
+		keys = append(keys, target.Label())
+	}
+
+	return keys
+}
+
+// renderExplanation writes the explanation of a rule to w.
+func (r renderer) renderExplanation(w io.Writer, e *difflint.Explanation) error {
+	var b strings.Builder
+	b.WriteString("rule ")
+	b.WriteString(r.paint(ansiCyan, e.Rule.Hunk.File))
+	b.WriteString(r.paint(ansiDim, fmt.Sprintf(":%d-%d", e.Rule.Hunk.Range.Start, e.Rule.Hunk.Range.End)))
+	b.WriteString(" (id: ")
+	b.WriteString(*e.Rule.ID)
+	b.WriteString(")\n")
+	if e.Skipped {
+		b.WriteString("  skipped by --skip-rule\n")
+	}
+
+	if e.Expired {
+		fmt.Fprintf(&b, "  expired on %s\n", e.Rule.Expires.Format("2006-01-02"))
+	}
+
+	if len(e.BlockHunks) > 0 {
+		b.WriteString("  block changed by hunks: ")
+		b.WriteString(formatHunks(e.BlockHunks))
+		b.WriteString("\n")
+	} else {
+		b.WriteString("  block not changed by any hunk\n")
+	}
+
+	for _, target := range e.Targets {
+		b.WriteString("  target ")
+		b.WriteString(r.paint(ansiYellow, target.Key))
+		if target.Present {
+			b.WriteString(" changed by hunks: ")
+			b.WriteString(formatHunks(target.Sources))
+		} else {
+			b.WriteString(" not changed")
+		}
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// formatHunks returns the comma-separated locations of the given hunks,
+// with the overlapping and adjacent ranges of each file merged.
+func formatHunks(hunks []difflint.Hunk) string {
+	var files []string
+	ranges := make(map[string][]difflint.Range)
+	for _, hunk := range hunks {
+		if _, ok := ranges[hunk.File]; !ok {
+			files = append(files, hunk.File)
+		}
+
+		ranges[hunk.File] = append(ranges[hunk.File], hunk.Range)
+	}
+
+	locations := make([]string, 0, len(hunks))
+	for _, file := range files {
+		for _, rng := range difflint.MergeRanges(ranges[file]) {
+			locations = append(locations, fmt.Sprintf("%s:%d-%d", file, rng.Start, rng.End))
+		}
+	}
+
+	return strings.Join(locations, ", ")
+}
+
+// renderChanges writes up to changeLines of the changed lines of the given
+// hunks to b.
+func (r renderer) renderChanges(b *strings.Builder, hunks []difflint.Hunk) {
+	if r.changeLines <= 0 {
+		return
+	}
+
+	n := r.changeLines
+	for _, hunk := range hunks {
+		truncated := hunk.Truncated
+		for _, line := range hunk.RemovedLines {
+			if n == 0 {
+				truncated = true
+				break
+			}
+
+			b.WriteString(r.paint(ansiRed, "      -"+line))
+			b.WriteString("\n")
+			n--
+		}
+
+		for _, line := range hunk.AddedLines {
+			if n == 0 {
+				truncated = true
+				break
+			}
+
+			b.WriteString(r.paint(ansiCyan, "      +"+line))
+			b.WriteString("\n")
+			n--
+		}
+
+		if truncated {
+			b.WriteString(r.paint(ansiDim, "      ..."))
+			b.WriteString("\n")
+			return
+		}
+	}
+}
+
+// severityColor returns the ANSI code used to paint the given severity.
+func severityColor(severity difflint.Severity) string {
+	switch severity {
+	case difflint.SeverityError:
+		return ansiRed
+	case difflint.SeverityWarn:
+		return ansiYellow
+	default:
+		return ansiDim
+	}
+}
+
+// summary returns a one-line summary of the given lint result.
+func summary(result *difflint.LintResult) string {
+	if result.Stats.HunksParsed == 0 {
+		return "difflint: no changes (0 hunks)"
+	}
+
+	files := make(map[string]struct{}, len(result.UnsatisfiedRules))
+	for _, rule := range result.UnsatisfiedRules {
+		files[rule.Rule.Hunk.File] = struct{}{}
+	}
+
+	s := fmt.Sprintf(
+		"difflint: %d files scanned, %d rules, %d unsatisfied (%d files)",
+		result.Stats.FilesScanned,
+		result.Stats.RulesParsed,
+		len(result.UnsatisfiedRules),
+		len(files),
+	)
+	if result.Stats.RulesFiltered > 0 {
+		s += fmt.Sprintf(", %d filtered out", result.Stats.RulesFiltered)
+	}
+
+	return s
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// serveMaxDiffBytes is the maximum size of a posted diff.
+	serveMaxDiffBytes = 32 << 20
+
+	// serveRootHeader is the request header that selects the checkout to
+	// lint against, from the --allow-root list.
+	serveRootHeader = "X-Difflint-Root"
+)
+
+// contentTypes are the content types of the output formats.
+var contentTypes = map[string]string{
+	formatText:      "text/plain; charset=utf-8",
+	formatJSON:      "application/json",
+	formatRDJSON:    "application/json",
+	formatJUnit:     "application/xml",
+	formatMarkdown:  "text/markdown; charset=utf-8",
+	formatBitbucket: "application/json",
+}
+
+// newServeCommand returns the serve subcommand.
+func newServeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "serve POST /lint, which lints the posted diff against the root",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "addr",
+				Usage: "address on which to listen",
+				Value: ":8080",
+			},
+			&cli.StringSliceFlag{
+				Name:  "allow-root",
+				Usage: fmt.Sprintf("checkout that requests may select with the %s header instead of --root", serveRootHeader),
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			l, err := newLinter(ctx)
+			if err != nil {
+				return err
+			}
+
+			s := newLintServer(l, ctx.StringSlice("allow-root"))
+			server := &http.Server{
+				Addr:              ctx.String("addr"),
+				Handler:           s,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			go func() {
+				<-ctx.Context.Done()
+				server.Close()
+			}()
+
+			log.New(ctx.App.ErrWriter, "", log.LstdFlags).Printf("difflint: serving on %s", server.Addr)
+			if err := server.ListenAndServe(); err != http.ErrServerClosed {
+				return err
+			}
+
+			return nil
+		},
+	}
+}
+
+// lintServer lints the diffs posted to /lint.
+type lintServer struct {
+	// linter holds the options of each lint; requests override its root,
+	// include and exclude patterns, and format.
+	linter *linter
+
+	// allowedRoots are the cleaned checkouts that requests may select.
+	allowedRoots map[string]struct{}
+
+	// mu guards rootLocks and codeOwners.
+	mu sync.Mutex
+
+	// rootLocks serialize the lints of each root so that concurrent
+	// requests share the root's rule cache instead of racing to rewrite it.
+	rootLocks map[string]*sync.Mutex
+
+	// codeOwners are the CODEOWNERS files of the allowed roots selected so
+	// far, nil for a root without one.
+	codeOwners map[string]*difflint.CodeOwners
+
+	// mux routes the requests.
+	mux *http.ServeMux
+}
+
+// newLintServer returns a server that lints with the given linter's
+// options against its root or one of the allowed roots.
+func newLintServer(l *linter, allowedRoots []string) *lintServer {
+	s := &lintServer{
+		linter:       l,
+		allowedRoots: make(map[string]struct{}, len(allowedRoots)),
+		rootLocks:    make(map[string]*sync.Mutex),
+		codeOwners:   make(map[string]*difflint.CodeOwners),
+		mux:          http.NewServeMux(),
+	}
+
+	for _, root := range allowedRoots {
+		s.allowedRoots[filepath.Clean(root)] = struct{}{}
+	}
+
+	s.mux.HandleFunc("/lint", s.handleLint)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *lintServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleLint lints the posted unified diff. It responds with the findings
+// in the requested format and 200 if no rule failed, 422 if some did, or
+// 400 if the diff cannot be parsed. A root selected with the X-Difflint-Root
+// header is linted with its own CODEOWNERS file.
+func (s *lintServer) handleLint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	l := *s.linter
+	l.color = false
+	l.summary = false
+	l.metricsOut = ""
+	l.commenter = nil
+	query := r.URL.Query()
+	if format := query.Get("format"); format != "" {
+		if _, ok := contentTypes[format]; !ok {
+			http.Error(w, fmt.Sprintf("invalid format %q", format), http.StatusBadRequest)
+			return
+		}
+
+		l.format = format
+		l.groupByTarget = l.groupByTarget && format == formatText
+		l.groupByOwner = l.groupByOwner && format == formatJSON
+	}
+
+	if include, ok := query["include"]; ok {
+		l.options.Include = include
+	}
+
+	if exclude, ok := query["exclude"]; ok {
+		l.options.Exclude = exclude
+	}
+
+	if root := r.Header.Get(serveRootHeader); root != "" {
+		root = filepath.Clean(root)
+		if _, ok := s.allowedRoots[root]; !ok {
+			http.Error(w, fmt.Sprintf("root %q is not allowed", root), http.StatusForbidden)
+			return
+		}
+
+		codeOwners, err := s.rootCodeOwners(root)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		l.options.Root = root
+		l.codeOwners = codeOwners
+	}
+
+	diff, err := io.ReadAll(http.MaxBytesReader(w, r.Body, serveMaxDiffBytes))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read diff: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.lint(r.Context(), l.options, diff)
+	var diffErr *difflint.DiffError
+	switch {
+	case errors.As(err, &diffErr):
+		http.Error(w, fmt.Sprintf("bad diff: %v", diffErr.Err), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var body bytes.Buffer
+	l.stdout = &body
+	if err := l.render(result, nil, nil); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if l.blocks(result) {
+		status = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", contentTypes[l.format])
+	w.WriteHeader(status)
+	w.Write(body.Bytes())
+}
+
+// rootCodeOwners returns the CODEOWNERS file of the given allowed root, if
+// it has one, loading it on first use.
+func (s *lintServer) rootCodeOwners(root string) (*difflint.CodeOwners, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if codeOwners, ok := s.codeOwners[root]; ok {
+		return codeOwners, nil
+	}
+
+	var codeOwners *difflint.CodeOwners
+	if path := difflint.FindCodeOwners(root); path != "" {
+		var err error
+		codeOwners, err = difflint.LoadCodeOwners(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.codeOwners[root] = codeOwners
+	return codeOwners, nil
+}
+
+// lint lints the given diff with the given options, one lint per root at a
+// time.
+func (s *lintServer) lint(ctx context.Context, options difflint.DoOptions, diff []byte) (*difflint.LintResult, error) {
+	root, err := filepath.Abs(options.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	lock, ok := s.rootLocks[root]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.rootLocks[root] = lock
+	}
+	s.mu.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+	options.Reader = bytes.NewReader(diff)
+	return difflint.DoWith(ctx, options)
+}
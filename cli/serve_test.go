@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethanthatonekid/difflint"
+)
+
+// serveTree writes a checkout whose a.go holds a rule targeting b.go, and
+// whose CODEOWNERS file names the given owner of b.go, and returns it.
+func serveTree(t *testing.T, owner string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":               "package p\n//LINT.IF b.go\nvar X = 1\n//LINT.END\n",
+		"b.go":               "package p\nvar Y = 1\n",
+		".github/CODEOWNERS": "b.go " + owner + "\n",
+	}
+
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+// serveDiff returns a diff that changes the second line of the given file.
+func serveDiff(file string) string {
+	return "diff --git a/" + file + " b/" + file + "\n--- a/" + file + "\n+++ b/" + file + "\n@@ -2,1 +2,1 @@\n-var Y = 1\n+var Y = 2\n"
+}
+
+func TestServe(t *testing.T) {
+	root := serveTree(t, "@root-team")
+	other := serveTree(t, "@other-team")
+	codeOwners, err := difflint.LoadCodeOwners(difflint.FindCodeOwners(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := &linter{
+		options: difflint.DoOptions{LintOptions: difflint.LintOptions{
+			Root:       root,
+			Templates:  difflint.DefaultTemplates,
+			FileExtMap: difflint.DefaultFileExtMap,
+		}},
+		logger:     log.New(io.Discard, "", 0),
+		format:     formatJSON,
+		failOn:     difflint.SeverityError,
+		metrics:    &metrics{},
+		codeOwners: codeOwners,
+		stderr:     io.Discard,
+	}
+
+	server := httptest.NewServer(newLintServer(l, []string{other}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		query      string
+		root       string
+		diff       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "satisfied", method: http.MethodPost, diff: serveDiff("c.go"), wantStatus: http.StatusOK},
+		{name: "unsatisfied", method: http.MethodPost, diff: serveDiff("b.go"), wantStatus: http.StatusUnprocessableEntity, wantBody: "@root-team"},
+		{name: "allowed root", method: http.MethodPost, root: other, diff: serveDiff("b.go"), wantStatus: http.StatusUnprocessableEntity, wantBody: "@other-team"},
+		{name: "bad diff", method: http.MethodPost, diff: "diff --git a/b.go b/b.go\n--- a/b.go\n+++ b/b.go\n@@ -x +y @@\n", wantStatus: http.StatusBadRequest, wantBody: "bad diff"},
+		{name: "invalid format", method: http.MethodPost, query: "?format=yaml", diff: serveDiff("b.go"), wantStatus: http.StatusBadRequest, wantBody: "invalid format"},
+		{name: "root not allowed", method: http.MethodPost, root: t.TempDir(), diff: serveDiff("b.go"), wantStatus: http.StatusForbidden, wantBody: "not allowed"},
+		{name: "get", method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, server.URL+"/lint"+test.query, strings.NewReader(test.diff))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.root != "" {
+				req.Header.Set(serveRootHeader, test.root)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != test.wantStatus {
+				t.Fatalf("POST /lint = %d %s, want %d", resp.StatusCode, body, test.wantStatus)
+			}
+
+			if !strings.Contains(string(body), test.wantBody) {
+				t.Errorf("POST /lint = %s, want it to contain %q", body, test.wantBody)
+			}
+
+			if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusUnprocessableEntity {
+				var out jsonResult
+				if err := json.Unmarshal(body, &out); err != nil {
+					t.Errorf("POST /lint = %s, want JSON: %v", body, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// statsFormatTable is the plain text table stats format.
+	statsFormatTable = "table"
+
+	// statsFormatJSON is the JSON stats format.
+	statsFormatJSON = "json"
+)
+
+// newStatsCommand returns the stats subcommand.
+func newStatsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "stats",
+		Usage: "summarize the rules under the root",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: fmt.Sprintf("output format: %q or %q", statsFormatTable, statsFormatJSON),
+				Value: statsFormatTable,
+			},
+		},
+		Action: statsAction,
+	}
+}
+
+func statsAction(ctx *cli.Context) error {
+	format := ctx.String("format")
+	if format != statsFormatTable && format != statsFormatJSON {
+		return fmt.Errorf("invalid stats format %q, expected %q or %q", format, statsFormatTable, statsFormatJSON)
+	}
+
+	// The stats' --format flag shadows the global one, so configure the
+	// linter from the parent command.
+	l, err := newLinter(ctx.Lineage()[1])
+	if err != nil {
+		return err
+	}
+
+	rulesMap, err := difflint.RulesWith(ctx.Context, l.options)
+	if err != nil {
+		return err
+	}
+
+	stats := difflint.ComputeRuleStats(rulesMap.Rules, os.DirFS(l.options.Root))
+	if format == statsFormatJSON {
+		enc := json.NewEncoder(ctx.App.Writer)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Difflint jsonMeta `json:"difflint"`
+			difflint.RuleStats
+		}{newJSONMeta(), stats})
+	}
+
+	return writeStatsTable(ctx.App.Writer, stats)
+}
+
+// writeStatsTable writes the given stats to w as aligned tables.
+func writeStatsTable(w io.Writer, stats difflint.RuleStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "rules\t%d\n", stats.Rules)
+	fmt.Fprintf(tw, "files with rules\t%d\n", stats.Files)
+	fmt.Fprintf(tw, "rules with IDs\t%d\n", stats.WithIDs)
+	fmt.Fprintf(tw, "rules with notes\t%d\n", stats.WithNotes)
+	fmt.Fprintf(tw, "rules with missing targets\t%d\n", stats.DanglingRules)
+
+	counts := make([]int, 0, len(stats.TargetsPerRule))
+	for n := range stats.TargetsPerRule {
+		counts = append(counts, n)
+	}
+	sort.Ints(counts)
+
+	fmt.Fprintf(tw, "\ntargets per rule\trules\n")
+	for _, n := range counts {
+		fmt.Fprintf(tw, "%d\t%d\n", n, stats.TargetsPerRule[n])
+	}
+
+	if len(stats.TopTargets) > 0 {
+		fmt.Fprintf(tw, "\nmost targeted files\trules\n")
+		for _, target := range stats.TopTargets {
+			fmt.Fprintf(tw, "%s\t%d\n", target.File, target.Rules)
+		}
+	}
+
+	return tw.Flush()
+}
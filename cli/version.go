@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/urfave/cli/v2"
+)
+
+// version is the version of difflint, injected at build time with
+// -ldflags "-X main.version=v1.2.3". If empty, the module version from the
+// build info is used.
+var version string
+
+// buildInfo describes the running build of difflint.
+type buildInfo struct {
+	// Version is the version of difflint, or "(devel)".
+	Version string `json:"version"`
+
+	// Commit is the VCS revision from which difflint was built, if known.
+	Commit string `json:"commit,omitempty"`
+
+	// GoVersion is the version of Go with which difflint was built.
+	GoVersion string `json:"go_version"`
+}
+
+// currentBuild returns the build info of the running binary.
+func currentBuild() buildInfo {
+	b := buildInfo{Version: version, GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		if b.Version == "" {
+			b.Version = "(devel)"
+		}
+
+		return b
+	}
+
+	if b.Version == "" {
+		b.Version = info.Main.Version
+	}
+
+	if b.Version == "" {
+		b.Version = "(devel)"
+	}
+
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			b.Commit = setting.Value
+		}
+	}
+
+	return b
+}
+
+// String returns the build info as printed by the version subcommand.
+func (b buildInfo) String() string {
+	commit := b.Commit
+	if commit == "" {
+		commit = "unknown"
+	}
+
+	return fmt.Sprintf("difflint %s\ncommit: %s\ngo: %s\n", b.Version, commit, b.GoVersion)
+}
+
+// printVersion prints the build info; it is used by --version.
+func printVersion(ctx *cli.Context) {
+	fmt.Fprint(ctx.App.Writer, currentBuild())
+}
+
+// newVersionCommand returns the version subcommand.
+func newVersionCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "version",
+		Usage: "print the version, commit, and Go version of difflint",
+		Action: func(ctx *cli.Context) error {
+			printVersion(ctx)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// watchInterval is how often the working tree is polled for changes.
+	watchInterval = 250 * time.Millisecond
+
+	// watchDebounce is how long the working tree must stay unchanged after a
+	// change before it is linted again.
+	watchDebounce = 500 * time.Millisecond
+
+	// clearScreen is the ANSI sequence that clears the terminal.
+	clearScreen = "\x1b[H\x1b[2J"
+)
+
+// watchIgnoredDirs are the names of the directories that are never polled
+// for changes: git internals and vendored dependencies, which are large and
+// change under tools rather than under the developer.
+var watchIgnoredDirs = map[string]struct{}{
+	".git":         {},
+	"node_modules": {},
+	"vendor":       {},
+}
+
+// watch lints the working tree against HEAD whenever a file changes until
+// ctx is done or the process is interrupted.
+func (l *linter) watch(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	var last map[string]string
+	var changedAt time.Time
+	dirty := true
+	for {
+		snapshot, err := l.snapshot(ctx)
+		if err != nil && ctx.Err() == nil {
+			fmt.Fprintf(l.stderr, "error: %v\n", err)
+		}
+
+		if err == nil && !sameSnapshot(snapshot, last) {
+			last = snapshot
+			changedAt = time.Now()
+			dirty = true
+		}
+
+		if dirty && time.Since(changedAt) >= watchDebounce {
+			dirty = false
+			l.watchOnce(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchOnce lints the working tree against HEAD, printing any error instead
+// of returning it so that the watch loop continues.
+func (l *linter) watchOnce(ctx context.Context) {
+	if isTerminal(l.stdout) {
+		fmt.Fprint(l.stdout, clearScreen)
+	}
+
+	r, err := difflint.GitDiff(ctx, l.options.Root, "HEAD")
+	if err == nil {
+		err = l.lint(ctx, r, nil)
+	}
+
+	// Unsatisfied rules have already been printed.
+	if _, ok := err.(cli.ExitCoder); err != nil && !ok && ctx.Err() == nil {
+		fmt.Fprintf(l.stderr, "error: %v\n", err)
+	}
+}
+
+// snapshot returns the modification time and size of every file in the
+// working tree by path. The ignored and excluded directories are not walked.
+func (l *linter) snapshot(ctx context.Context) (map[string]string, error) {
+	snapshot := make(map[string]string)
+	err := fs.WalkDir(os.DirFS(l.options.Root), ".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if file != "." && watchPrunes(file, l.options.Exclude) {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		included, err := difflint.Include(file, l.options.Include, l.options.Exclude)
+		if err != nil || !included {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		snapshot[file] = fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// watchPrunes returns true if the given directory is not polled for changes:
+// it is ignored by name, or an exclude pattern matches it or everything
+// directly in it, e.g. "build" or "build/*".
+func watchPrunes(dir string, exclude []string) bool {
+	if _, ok := watchIgnoredDirs[path.Base(dir)]; ok {
+		return true
+	}
+
+	for _, pattern := range exclude {
+		pattern = filepath.ToSlash(pattern)
+		for _, p := range []string{pattern, strings.TrimSuffix(strings.TrimSuffix(pattern, "/**"), "/*")} {
+			if matched, err := path.Match(p, dir); err == nil && matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// sameSnapshot returns true if the given snapshots are equal.
+func sameSnapshot(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for file, stamp := range a {
+		if b[file] != stamp {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/ethanthatonekid/difflint"
+)
+
+func TestWatchPrunes(t *testing.T) {
+	tests := []struct {
+		dir     string
+		exclude []string
+		want    bool
+	}{
+		{dir: ".git", want: true},
+		{dir: "web/node_modules", want: true},
+		{dir: "vendor", want: true},
+		{dir: "src", want: false},
+		{dir: ".github", want: false},
+		{dir: "build", exclude: []string{"build"}, want: true},
+		{dir: "build", exclude: []string{"build/*"}, want: true},
+		{dir: "build", exclude: []string{"build/**"}, want: true},
+		{dir: "out/gen", exclude: []string{"out/*"}, want: true},
+		{dir: "src", exclude: []string{"build/*", "*.pb.go"}, want: false},
+	}
+
+	for _, test := range tests {
+		if got := watchPrunes(test.dir, test.exclude); got != test.want {
+			t.Errorf("watchPrunes(%q, %q) = %v, want %v", test.dir, test.exclude, got, test.want)
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	root := t.TempDir()
+	for _, file := range []string{"a.go", "src/b.go", ".github/ci.yml", "node_modules/x/index.js", "vendor/y/y.go", "build/out.go", ".git/HEAD", "c.pb.go"} {
+		file = filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := &linter{options: difflint.DoOptions{LintOptions: difflint.LintOptions{
+		Root:    root,
+		Exclude: []string{"build/*", "*.pb.go"},
+	}}}
+	snapshot, err := l.snapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for file := range snapshot {
+		got = append(got, file)
+	}
+	sort.Strings(got)
+
+	if want := []string{".github/ci.yml", "a.go", "src/b.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot() = %q, want %q", got, want)
+	}
+}
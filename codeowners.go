@@ -0,0 +1,129 @@
+package difflint
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CodeOwnersPaths are the paths, relative to the root, at which a CODEOWNERS
+// file is looked for, in order.
+var CodeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeOwners maps files to their owners as declared in a CODEOWNERS file.
+type CodeOwners struct {
+	// entries are the entries of the file, in order.
+	entries []codeOwnersEntry
+}
+
+// codeOwnersEntry is a line of a CODEOWNERS file.
+type codeOwnersEntry struct {
+	// pattern is the path elements matched by the entry.
+	pattern []string
+
+	// owners are the users, teams, or emails that own the matching files.
+	owners []string
+}
+
+// FindCodeOwners returns the path of the CODEOWNERS file of the given root,
+// or "" if there is none.
+func FindCodeOwners(root string) string {
+	for _, p := range CodeOwnersPaths {
+		file := filepath.Join(root, filepath.FromSlash(p))
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+			return file
+		}
+	}
+
+	return ""
+}
+
+// LoadCodeOwners reads the CODEOWNERS file at the given path.
+func LoadCodeOwners(path string) (*CodeOwners, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open CODEOWNERS file %q", path)
+	}
+	defer f.Close()
+
+	owners, err := ParseCodeOwners(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse CODEOWNERS file %q", path)
+	}
+
+	return owners, nil
+}
+
+// ParseCodeOwners parses a CODEOWNERS file. Each line is a gitignore-style
+// pattern followed by its owners; blank lines and comments are skipped.
+func ParseCodeOwners(r io.Reader) (*CodeOwners, error) {
+	var c CodeOwners
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		c.entries = append(c.entries, codeOwnersEntry{
+			pattern: codeOwnersPattern(fields[0]),
+			owners:  fields[1:],
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// codeOwnersPattern returns the path elements matched by the given pattern.
+// A pattern without a slash, other than a trailing one, matches at any
+// depth, and every pattern matches the contents of matching directories.
+func codeOwnersPattern(pattern string) []string {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+	elements := strings.Split(pattern, "/")
+	if !anchored {
+		elements = append([]string{"**"}, elements...)
+	}
+
+	return append(elements, "**")
+}
+
+// Owners returns the owners of the given file according to the last
+// matching entry, or nil if no entry matches or the entry has no owners.
+func (c *CodeOwners) Owners(file string) []string {
+	if c == nil {
+		return nil
+	}
+
+	name := strings.Split(NormalizeKey(file), "/")
+	for i := len(c.entries) - 1; i >= 0; i-- {
+		if matchElements(c.entries[i].pattern, name) {
+			return c.entries[i].owners
+		}
+	}
+
+	return nil
+}
+
+// Annotate sets the owners of the missing targets of the given findings.
+func (c *CodeOwners) Annotate(findings []Finding) {
+	for i := range findings {
+		for j := range findings[i].MissingTargets {
+			target := &findings[i].MissingTargets[j]
+			target.Owners = c.Owners(target.File)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package difflint
+
+import (
+	"sort"
+	"strings"
+)
+
+// Comparison describes how the findings of a lint result differ from those
+// of a previous run.
+type Comparison struct {
+	// New is the number of failing findings that the previous run did not
+	// have.
+	New int
+
+	// Unchanged is the number of failing findings that the previous run also
+	// had.
+	Unchanged int
+
+	// Fixed are the failing findings of the previous run that are gone.
+	Fixed []Finding
+}
+
+// CompareResult returns a copy of the given result without the unsatisfied,
+// expired, and empty rules whose findings the previous run also had, so that
+// only new findings remain, along with the comparison. The given result is
+// not modified. Findings match by kind, rule file, and rule ID, or by kind,
+// rule file, and missing targets for rules without an ID, so that lines
+// moving between runs do not matter.
+func CompareResult(result *LintResult, previous []Finding) (*LintResult, Comparison) {
+	counts := make(map[string]int, len(previous))
+	for _, f := range previous {
+		if f.Kind != FindingSatisfied {
+			counts[findingKey(f)]++
+		}
+	}
+
+	var c Comparison
+	seen := func(f Finding) bool {
+		key := findingKey(f)
+		if counts[key] == 0 {
+			c.New++
+			return false
+		}
+
+		counts[key]--
+		c.Unchanged++
+		return true
+	}
+
+	compared := *result
+	compared.UnsatisfiedRules = nil
+	for _, rule := range result.UnsatisfiedRules {
+		if !seen(BuildFindings(&LintResult{UnsatisfiedRules: UnsatisfiedRules{rule}})[0]) {
+			compared.UnsatisfiedRules = append(compared.UnsatisfiedRules, rule)
+		}
+	}
+
+	compared.ExpiredRules = nil
+	for _, rule := range result.ExpiredRules {
+		if !seen(BuildFindings(&LintResult{ExpiredRules: []Rule{rule}})[0]) {
+			compared.ExpiredRules = append(compared.ExpiredRules, rule)
+		}
+	}
+
+	compared.EmptyRules = nil
+	for _, rule := range result.EmptyRules {
+		if !seen(BuildFindings(&LintResult{EmptyRules: []Rule{rule}})[0]) {
+			compared.EmptyRules = append(compared.EmptyRules, rule)
+		}
+	}
+
+	// The previous findings left over are the ones that are gone, in their
+	// previous order.
+	for _, f := range previous {
+		if f.Kind == FindingSatisfied {
+			continue
+		}
+
+		key := findingKey(f)
+		if counts[key] > 0 {
+			counts[key]--
+			c.Fixed = append(c.Fixed, f)
+		}
+	}
+
+	return &compared, c
+}
+
+// findingKey returns the key by which a finding is matched across runs,
+// which does not depend on the lines of its rule.
+func findingKey(f Finding) string {
+	key := []string{string(f.Kind), f.RuleFile}
+	if f.RuleID != "" {
+		return strings.Join(append(key, "#"+f.RuleID), "\x00")
+	}
+
+	targets := make([]string, len(f.MissingTargets))
+	for i, target := range f.MissingTargets {
+		targets[i] = target.Key
+	}
+
+	sort.Strings(targets)
+	return strings.Join(append(key, targets...), "\x00")
+}
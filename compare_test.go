@@ -0,0 +1,99 @@
+package difflint
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// compareRule returns an unsatisfied rule of a.go at the given line, with
+// the given ID if any, whose target x.go is missing.
+func compareRule(id string, line int) UnsatisfiedRule {
+	target := "x.go"
+	rule := UnsatisfiedRule{
+		Rule: Rule{
+			Hunk:     Hunk{File: "a.go", Range: Range{Start: line, End: line + 2}},
+			Severity: SeverityError,
+			Targets:  []Target{{File: &target}},
+		},
+		UnsatisfiedTargets: map[int]struct{}{0: {}},
+	}
+
+	if id != "" {
+		rule.ID = &id
+	}
+
+	return rule
+}
+
+func TestCompareResult(t *testing.T) {
+	// The previous run had the persisting findings on other lines, and a
+	// finding that is fixed since.
+	previous := BuildFindings(&LintResult{
+		UnsatisfiedRules: UnsatisfiedRules{compareRule("persisting", 40), compareRule("fixed", 50), compareRule("", 60)},
+		EmptyRules:       []Rule{compareRule("empty", 70).Rule},
+		SatisfiedRules:   []SatisfiedRule{{Rule: compareRule("new", 80).Rule}},
+	})
+
+	expired := compareRule("expired", 8).Rule
+	expires := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	expired.Expires = &expires
+	result := &LintResult{
+		UnsatisfiedRules: UnsatisfiedRules{compareRule("new", 1), compareRule("persisting", 10), compareRule("", 20), compareRule("", 30)},
+		EmptyRules:       []Rule{compareRule("empty", 5).Rule},
+		ExpiredRules:     []Rule{expired},
+	}
+	original := *result
+	original.UnsatisfiedRules = append(UnsatisfiedRules(nil), result.UnsatisfiedRules...)
+
+	compared, comparison := CompareResult(result, previous)
+
+	var ids []string
+	for _, rule := range compared.UnsatisfiedRules {
+		ids = append(ids, ruleName(rule.Rule))
+	}
+
+	// Of the two rules without an ID, only one is new since the previous
+	// run had one like them.
+	if want := []string{"new", "a.go:30"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("CompareResult() unsatisfied rules = %q, want %q", ids, want)
+	}
+
+	if len(compared.EmptyRules) != 0 {
+		t.Errorf("CompareResult() empty rules = %v, want the persisting one removed", compared.EmptyRules)
+	}
+
+	if len(compared.ExpiredRules) != 1 || *compared.ExpiredRules[0].ID != "expired" {
+		t.Errorf("CompareResult() expired rules = %v, want the new one", compared.ExpiredRules)
+	}
+
+	if comparison.New != 3 || comparison.Unchanged != 3 {
+		t.Errorf("CompareResult() = %d new and %d unchanged findings, want 3 and 3", comparison.New, comparison.Unchanged)
+	}
+
+	if len(comparison.Fixed) != 1 || comparison.Fixed[0].RuleID != "fixed" || comparison.Fixed[0].StartLine != 50 {
+		t.Errorf("CompareResult() fixed = %+v, want the finding of fixed", comparison.Fixed)
+	}
+
+	if !reflect.DeepEqual(result.UnsatisfiedRules, original.UnsatisfiedRules) || len(result.EmptyRules) != 1 || len(result.ExpiredRules) != 1 {
+		t.Errorf("CompareResult() modified the given result to %+v", result)
+	}
+}
+
+func TestCompareResultNoPrevious(t *testing.T) {
+	result := &LintResult{UnsatisfiedRules: UnsatisfiedRules{compareRule("a", 1)}}
+	compared, comparison := CompareResult(result, nil)
+	if len(compared.UnsatisfiedRules) != 1 || comparison.New != 1 || comparison.Unchanged != 0 || len(comparison.Fixed) != 0 {
+		t.Errorf("CompareResult() = %+v, %+v, want every finding new", compared, comparison)
+	}
+}
+
+// ruleName returns the ID of the given rule, or its file and line.
+func ruleName(rule Rule) string {
+	if rule.ID != nil {
+		return *rule.ID
+	}
+
+	return fmt.Sprintf("%s:%d", rule.Hunk.File, rule.Hunk.Range.Start)
+}
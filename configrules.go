@@ -0,0 +1,110 @@
+package difflint
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ConfigRule is a rule declared in a rules file instead of by directives,
+// for files that cannot hold directives, such as generated code. Its
+// granularity is whole files.
+type ConfigRule struct {
+	// If is the file name or glob pattern whose change requires the Then
+	// files to change.
+	If string `json:"if"`
+
+	// Then are the file names or glob patterns that must change when a file
+	// matching If changes.
+	Then []string `json:"then"`
+
+	// ID is an optional identifier for the rule.
+	ID string `json:"id,omitempty"`
+
+	// Message is an optional note shown when the rule is not satisfied.
+	Message string `json:"message,omitempty"`
+}
+
+// RulesFile is the format of a rules file.
+type RulesFile struct {
+	// Rules are the rules declared in the file.
+	Rules []ConfigRule `json:"rules"`
+
+	// Aliases maps target path aliases, e.g. "@api", to the directories
+	// they stand for, e.g. "services/api".
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// LoadRulesFile reads the JSON rules file at the given path.
+func LoadRulesFile(path string) (*RulesFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read rules file %q", path)
+	}
+
+	var file RulesFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal rules file %q", path)
+	}
+
+	for i, rule := range file.Rules {
+		if rule.If == "" || len(rule.Then) == 0 {
+			return nil, errors.Errorf("rule %d of rules file %q needs both \"if\" and \"then\"", i, path)
+		}
+	}
+
+	for alias, dir := range file.Aliases {
+		if !strings.HasPrefix(alias, "@") || len(alias) == 1 || strings.ContainsAny(alias, "/:") || dir == "" {
+			return nil, errors.Errorf("invalid alias %q of rules file %q, expected \"@name\": \"dir\"", alias, path)
+		}
+	}
+
+	return &file, nil
+}
+
+// LoadConfigRules reads the rules declared in the JSON rules file at the
+// given path.
+func LoadConfigRules(path string) ([]ConfigRule, error) {
+	file, err := LoadRulesFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return file.Rules, nil
+}
+
+// rules converts the config rule into one rule per Then pattern, each of
+// which spans the whole of the matching files and targets the If pattern.
+// A rule is present if any of the given changed files matches its pattern.
+func (c ConfigRule) rules(changedFiles []string) []Rule {
+	rules := make([]Rule, 0, len(c.Then))
+	for _, then := range c.Then {
+		then = NormalizeKey(then)
+		target := NormalizeKey(c.If)
+		r := Rule{
+			Hunk:     Hunk{File: then},
+			Targets:  []Target{{File: &target, Raw: c.If}},
+			Severity: SeverityError,
+			Note:     c.Message,
+			Config:   true,
+		}
+
+		if c.ID != "" {
+			id := c.ID
+			r.ID = &id
+		}
+
+		for _, file := range changedFiles {
+			if matchTargetFile(then, file) {
+				r.Present = true
+				break
+			}
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules
+}
@@ -1,24 +1,36 @@
 package difflint
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/pkg/errors"
 
 	"github.com/sourcegraph/go-diff/diff"
 )
 
-// Range represents a range of line numbers.
+// Range represents a range of line numbers. Both bounds are inclusive, so
+// that Range{Start: 3, End: 5} has the lines 3, 4, and 5. A range whose End
+// is before its Start is empty, such as the body of a block whose IF and END
+// directives are adjacent or the hunk of lines deleted before line Start.
 type Range struct {
 	// Start line number.
-	Start int
+	Start int `json:"start"`
 
 	// End line number.
-	End int
+	End int `json:"end"`
 }
 
 // Intersects returns true if the given ranges intersect.
@@ -26,17 +38,250 @@ func Intersects(a, b Range) bool {
 	return a.Start <= b.End && b.Start <= a.End
 }
 
+// Contains returns true if the given line is in the range.
+func (r Range) Contains(line int) bool {
+	return r.Start <= line && line <= r.End
+}
+
+// Len returns the number of lines of the range, which is zero if it is empty.
+func (r Range) Len() int {
+	if r.End < r.Start {
+		return 0
+	}
+
+	return r.End - r.Start + 1
+}
+
+// Union returns the range of the lines of both ranges, if they intersect or
+// are adjacent so that it has no other lines. The union with an empty range
+// is the other range.
+func (r Range) Union(other Range) (Range, bool) {
+	switch {
+	case other.Len() == 0:
+		return r, true
+	case r.Len() == 0:
+		return other, true
+	case r.Start > other.End+1 || other.Start > r.End+1:
+		return Range{}, false
+	}
+
+	union := r
+	if other.Start < union.Start {
+		union.Start = other.Start
+	}
+
+	if other.End > union.End {
+		union.End = other.End
+	}
+
+	return union, true
+}
+
+// Intersection returns the range of the lines that are in both ranges, if
+// there are any.
+func (r Range) Intersection(other Range) (Range, bool) {
+	if r.Len() == 0 || other.Len() == 0 || !Intersects(r, other) {
+		return Range{}, false
+	}
+
+	intersection := r
+	if other.Start > intersection.Start {
+		intersection.Start = other.Start
+	}
+
+	if other.End < intersection.End {
+		intersection.End = other.End
+	}
+
+	return intersection, true
+}
+
+// Ranges is a list of line ranges, e.g. the changed lines of a file.
+type Ranges []Range
+
+// Merge returns the ranges sorted by start line with overlapping and
+// adjacent ranges merged, so that their end lines are sorted too.
+func (rs Ranges) Merge() Ranges {
+	sorted := make(Ranges, len(rs))
+	copy(sorted, rs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	// Empty ranges, such as the hunks of lines deleted before a line, are
+	// kept unless another range covers them so that they still intersect the
+	// lines around them.
+	var merged Ranges
+	for _, rng := range sorted {
+		if n := len(merged); n > 0 && rng.Start <= merged[n-1].End+1 {
+			if rng.End > merged[n-1].End {
+				merged[n-1].End = rng.End
+			}
+
+			continue
+		}
+
+		merged = append(merged, rng)
+	}
+
+	return merged
+}
+
+// AnyIntersects returns true if the given range intersects any of the
+// ranges, which must be merged by Merge so that the search takes logarithmic
+// time.
+func (rs Ranges) AnyIntersects(rng Range) bool {
+	i := sort.Search(len(rs), func(i int) bool {
+		return rs[i].End >= rng.Start
+	})
+
+	return i < len(rs) && rs[i].Start <= rng.End
+}
+
+// MergeRanges returns the given ranges sorted by start line with overlapping
+// and adjacent ranges merged, so that their end lines are sorted too.
+func MergeRanges(ranges []Range) []Range {
+	return Ranges(ranges).Merge()
+}
+
+// hunkIndex finds the hunks of a file that intersect a range without
+// visiting every hunk.
+type hunkIndex struct {
+	// hunks are the hunks in diff order.
+	hunks []Hunk
+
+	// order are the indices of the hunks sorted by start line.
+	order []int
+
+	// maxEnds are the greatest end line of the hunks of order up to each
+	// index.
+	maxEnds []int
+}
+
+// newHunkIndex returns the index of the given hunks of a file.
+func newHunkIndex(hunks []Hunk) *hunkIndex {
+	x := &hunkIndex{
+		hunks:   hunks,
+		order:   make([]int, len(hunks)),
+		maxEnds: make([]int, len(hunks)),
+	}
+
+	for i := range hunks {
+		x.order[i] = i
+	}
+
+	sort.SliceStable(x.order, func(i, j int) bool {
+		return hunks[x.order[i]].Range.Start < hunks[x.order[j]].Range.Start
+	})
+
+	for i, j := range x.order {
+		x.maxEnds[i] = hunks[j].Range.End
+		if i > 0 && x.maxEnds[i-1] > x.maxEnds[i] {
+			x.maxEnds[i] = x.maxEnds[i-1]
+		}
+	}
+
+	return x
+}
+
+// intersecting returns the hunks that intersect the given range, in diff
+// order.
+func (x *hunkIndex) intersecting(rng Range) []Hunk {
+	// Only the hunks that start before the range ends can intersect it, and
+	// the scan stops once no earlier hunk reaches the range's start.
+	n := sort.Search(len(x.order), func(i int) bool {
+		return x.hunks[x.order[i]].Range.Start > rng.End
+	})
+
+	var found []int
+	for i := n - 1; i >= 0 && x.maxEnds[i] >= rng.Start; i-- {
+		if Intersects(rng, x.hunks[x.order[i]].Range) {
+			found = append(found, x.order[i])
+		}
+	}
+
+	if len(found) == 0 {
+		return nil
+	}
+
+	sort.Ints(found)
+	hunks := make([]Hunk, len(found))
+	for i, j := range found {
+		hunks[i] = x.hunks[j]
+	}
+
+	return hunks
+}
+
+// FilterScope determines what the include and exclude patterns apply to.
+type FilterScope string
+
+const (
+	// FilterScopeRules only enforces the rules of included files. Changes in
+	// any file can still require a rule to change.
+	FilterScopeRules FilterScope = "rules"
+
+	// FilterScopeChanges ignores changes in excluded files, so they can never
+	// require a rule to change. The rules of every file are enforced.
+	FilterScopeChanges FilterScope = "changes"
+
+	// FilterScopeAll applies both FilterScopeRules and FilterScopeChanges.
+	FilterScopeAll FilterScope = "all"
+)
+
+// ParseFilterScope parses the given string and returns the filter scope.
+func ParseFilterScope(s string) (FilterScope, error) {
+	scope := FilterScope(s)
+	switch scope {
+	case FilterScopeRules, FilterScopeChanges, FilterScopeAll:
+		return scope, nil
+	default:
+		return "", errors.Errorf("unknown filter scope %q, expected %q, %q, or %q", s, FilterScopeRules, FilterScopeChanges, FilterScopeAll)
+	}
+}
+
+// filtersRules returns true if the include and exclude patterns determine
+// which rules are enforced.
+func (s FilterScope) filtersRules() bool {
+	return s == "" || s == FilterScopeRules || s == FilterScopeAll
+}
+
+// filtersChanges returns true if the include and exclude patterns determine
+// which changes are considered.
+func (s FilterScope) filtersChanges() bool {
+	return s == FilterScopeChanges || s == FilterScopeAll
+}
+
 // LintOptions represents the options for a linting operation.
 type LintOptions struct {
 	// Reader is the reader from which the diff is read.
 	Reader io.Reader
 
-	// Include is a list of file patterns to include in the linting.
+	// Files are files, relative to the root, to lint as if every one of
+	// their lines changed instead of reading a diff from Reader. Files that
+	// do not exist are linted as deleted.
+	Files []string
+
+	// Root is the directory to which the paths in the diff are relative.
+	// Defaults to the current directory. Ignored if FS is set.
+	Root string
+
+	// FS is the file system containing the files referenced by the diff.
+	// Defaults to os.DirFS(Root).
+	FS fs.FS
+
+	// Include is a list of file patterns to include in the linting. What
+	// they apply to is determined by FilterScope.
 	Include []string
 
-	// Exclude is a list of file patterns to exclude from the linting.
+	// Exclude is a list of file patterns to exclude from the linting. What
+	// they apply to is determined by FilterScope.
 	Exclude []string
 
+	// FilterScope determines what Include and Exclude apply to. Defaults to
+	// FilterScopeRules.
+	FilterScope FilterScope
+
 	// Templates is the list of directive templates.
 	Templates []string // []string{"//LINT.?", "#LINT.?", "<!-- LINT.? -->"}
 
@@ -45,6 +290,206 @@ type LintOptions struct {
 
 	// DefaultTemplate is the default directive template.
 	DefaultTemplate int
+
+	// StrictDirectives makes unknown directives and the unresolved symbols of
+	// FUNC directives an error instead of a warning.
+	StrictDirectives bool
+
+	// WarnMismatchedTemplates warns about directive-like lines that do not
+	// match any template for the file type.
+	WarnMismatchedTemplates bool
+
+	// DirectiveWord is the word that introduces directives, which is looked
+	// for by WarnMismatchedTemplates. Defaults to DefaultDirectiveWord.
+	DirectiveWord string
+
+	// CommentsOnly lists the file extensions, e.g. "go", in whose files
+	// directive-like lines inside multi-line string literals, or fenced code
+	// blocks of Markdown, are ignored. Go, JavaScript, TypeScript, Python,
+	// and Markdown are supported; other extensions are matched textually.
+	CommentsOnly []string
+
+	// TargetMacros maps target macros, e.g. "@tests", to the targets they
+	// expand to by file extension. Defaults to DefaultTargetMacros.
+	TargetMacros map[string]map[string]string
+
+	// TargetAliases maps path aliases, e.g. "@api", to the directories they
+	// stand for, e.g. "services/api", so that "@api/schema.sql" targets
+	// "services/api/schema.sql". Macros take precedence over aliases.
+	TargetAliases map[string]string
+
+	// ConfigRules are rules declared outside of directives, checked along
+	// with the rules parsed from files.
+	ConfigRules []ConfigRule
+
+	// TargetResolvers resolve custom targets, e.g. "proto:UserService", to
+	// the keys of the files or blocks they stand for. They are consulted in
+	// order before the default path logic.
+	TargetResolvers []TargetResolver
+
+	// Index is a rule index from which the rules of unchanged files are
+	// read instead of parsing every file. Changed files are still parsed.
+	Index *IndexFile
+
+	// StripPrefixes is a list of path prefixes to strip from the file names in
+	// the diff. If empty, the "a/" and "b/" prefixes are detected automatically.
+	StripPrefixes []string
+
+	// Logger receives progress messages. Defaults to discarding them.
+	Logger Logger
+
+	// SkipRules is a list of rule IDs, ID glob patterns, files, or "file:id"
+	// keys whose rules are not checked.
+	SkipRules []string
+
+	// OnlyRules limits the checked rules to those whose ID matches one of
+	// the given IDs or glob patterns, e.g. "sec-*". Rules without IDs are
+	// not checked.
+	OnlyRules []string
+
+	// SkipUnnamed excludes the rules without IDs from checking.
+	SkipUnnamed bool
+
+	// OnlyTags limits the checked rules to those with at least one of the
+	// given tags.
+	OnlyTags []string
+
+	// SkipTags excludes the rules with any of the given tags from checking.
+	SkipTags []string
+
+	// Explain is the "file:id" key of a rule to explain in the result.
+	Explain string
+
+	// RelativeTargets resolves bare target file names, such as "other.go",
+	// relative to the directory of the rule's file instead of the root.
+	RelativeTargets bool
+
+	// StrictPresence requires every target of a rule whose block changed to
+	// change too, instead of treating the rule as satisfied.
+	StrictPresence bool
+
+	// ContentOnly ignores changes without lines, such as changes of file
+	// mode or of binary files, which otherwise change the whole file.
+	ContentOnly bool
+
+	// ExclusiveMarkers ignores changes to the directive lines of a block,
+	// such as its IF and END lines, when deciding whether it changed.
+	ExclusiveMarkers bool
+
+	// BlankBlocksEmpty reports blocks that guard only blank lines as empty,
+	// along with blocks that guard no lines.
+	BlankBlocksEmpty bool
+
+	// VCS is the version control system flavor of the diff. Defaults to
+	// detecting it.
+	VCS VCS
+
+	// CacheDir is the directory in which parsed directives are cached
+	// across runs. Caching is disabled if it is empty.
+	CacheDir string
+
+	// FollowSymlinks follows symbolic links while walking the root instead
+	// of skipping them. Ignored if FS is set.
+	FollowSymlinks bool
+
+	// AllowHidden are the patterns, e.g. ".github/**", of the hidden files
+	// and directories that are searched for rules, along with those that
+	// match an Include pattern. A "**" element matches any number of
+	// directories. Defaults to DefaultAllowHidden. The .git directory is
+	// never searched.
+	AllowHidden []string
+
+	// MaxHunkLines is the maximum number of added and removed lines stored
+	// per hunk. Zero means DefaultMaxHunkLines and a negative value means no
+	// limit.
+	MaxHunkLines int
+
+	// MaxDiffBytes is the maximum size of the diff read from Reader. Reading
+	// stops with ErrDiffTooLarge once it is exceeded. Zero means no limit.
+	MaxDiffBytes int64
+
+	// MaxFileSize is the maximum size in bytes of a file scanned for rules;
+	// larger files are skipped. Zero means DefaultMaxFileSize and a negative
+	// value means no limit.
+	MaxFileSize int64
+
+	// CaseInsensitivePaths matches target paths to changed files and files
+	// with rules regardless of case, as on the default file systems of macOS
+	// and Windows. Targets that only match when ignoring case are reported
+	// as warnings.
+	CaseInsensitivePaths bool
+}
+
+// hiddenPatterns returns the patterns of the hidden paths that are searched
+// for rules.
+func (o *LintOptions) hiddenPatterns() []string {
+	allow := o.AllowHidden
+	if allow == nil {
+		allow = DefaultAllowHidden
+	}
+
+	return append(append([]string(nil), allow...), o.Include...)
+}
+
+// directiveWord returns the configured directive word or the default one.
+func (o *LintOptions) directiveWord() string {
+	if o.DirectiveWord == "" {
+		return DefaultDirectiveWord
+	}
+
+	return o.DirectiveWord
+}
+
+// literalScanner returns the literal scanner of the given file if its
+// extension is listed in CommentsOnly and has one, or nil.
+func (o *LintOptions) literalScanner(file string) literalScanner {
+	ext := strings.TrimPrefix(path.Ext(file), ".")
+	for _, commentsOnly := range o.CommentsOnly {
+		if strings.TrimPrefix(commentsOnly, ".") != ext {
+			continue
+		}
+
+		if s, ok := newLiteralScanner(ext); ok {
+			return s
+		}
+	}
+
+	return nil
+}
+
+// MacrosFromFile returns the target macros of the given file type and the
+// targets they expand to.
+func (o *LintOptions) MacrosFromFile(file string) map[string]string {
+	macros := o.TargetMacros
+	if macros == nil {
+		macros = DefaultTargetMacros
+	}
+
+	fileType := strings.TrimPrefix(filepath.Ext(file), ".")
+	expansions := make(map[string]string, len(macros)+len(o.TargetAliases))
+	for alias, dir := range o.TargetAliases {
+		expansions[alias] = dir
+	}
+
+	for macro, byExt := range macros {
+		if target, ok := byExt[fileType]; ok {
+			expansions[macro] = target
+			continue
+		}
+
+		if target, ok := byExt[""]; ok {
+			expansions[macro] = target
+		}
+	}
+
+	return expansions
+}
+
+// hasTemplates returns true if the given file extension has templates of its
+// own rather than the default template.
+func (o *LintOptions) hasTemplates(ext string) bool {
+	_, ok := o.FileExtMap[ext]
+	return ok
 }
 
 // TemplatesFromFile returns the directive templates for the given file type.
@@ -73,8 +518,92 @@ type Hunk struct {
 
 	// Range of code in which a diff hunk intersects.
 	Range Range
+
+	// Added is true if the file is newly added in the diff.
+	Added bool
+
+	// Deleted is true if the file is deleted in the diff, in which case File
+	// is the original path and Range is in the original file.
+	Deleted bool
+
+	// AddedLines is the text of the lines added by the hunk.
+	AddedLines []string
+
+	// RemovedLines is the text of the lines removed by the hunk.
+	RemovedLines []string
+
+	// Truncated is true if lines were omitted from AddedLines or RemovedLines
+	// to stay within the maximum number of lines stored per hunk.
+	Truncated bool
+
+	// MetadataOnly is true if the hunk stands for a change without lines,
+	// such as a change of file mode or of a binary file. It marks the whole
+	// file as changed and has no range.
+	MetadataOnly bool
+}
+
+// ErrDiffTooLarge is returned when a diff is larger than
+// LintOptions.MaxDiffBytes.
+var ErrDiffTooLarge = errors.New("diff too large")
+
+// DiffError is returned when the diff cannot be read or parsed, as opposed to
+// failures of the lint itself.
+type DiffError struct {
+	// Err is the error of reading or parsing the diff.
+	Err error
+}
+
+// Error implements error.
+func (e *DiffError) Error() string {
+	return "failed to parse diff hunks: " + e.Err.Error()
+}
+
+// Unwrap returns the error of reading or parsing the diff.
+func (e *DiffError) Unwrap() error {
+	return e.Err
+}
+
+// maxBytesReader reads from r until more than limit bytes have been read,
+// after which it fails with ErrDiffTooLarge.
+type maxBytesReader struct {
+	// r is the underlying reader.
+	r io.Reader
+
+	// limit is the maximum number of bytes to read.
+	limit int64
+
+	// n is the number of bytes read so far.
+	n int64
+}
+
+// Read implements io.Reader.
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.n += int64(n)
+	if m.n > m.limit {
+		return n, errors.Wrapf(ErrDiffTooLarge, "read more than %d bytes", m.limit)
+	}
+
+	return n, err
 }
 
+// maxFileLines is the maximum number of added and removed lines stored per
+// file unless there is no limit per hunk, so that huge diffs of generated
+// files such as lockfiles do not hold all of their lines in memory.
+const maxFileLines = 1000
+
+// DefaultMaxHunkLines is the default maximum number of added and removed
+// lines stored per hunk.
+const DefaultMaxHunkLines = 50
+
+// DefaultMaxFileSize is the default maximum size in bytes of a file scanned
+// for rules.
+const DefaultMaxFileSize = 10 << 20
+
+// DefaultAllowHidden are the default patterns of the hidden paths that are
+// searched for rules, so that CI workflows can hold rules.
+var DefaultAllowHidden = []string{".github/**"}
+
 // UnsatisfiedRule represents a rule that is not satisfied.
 type UnsatisfiedRule struct {
 	// Rule that is not satisfied.
@@ -82,6 +611,55 @@ type UnsatisfiedRule struct {
 
 	// UnsatisfiedTargets is the list of target indices that are not satisfied.
 	UnsatisfiedTargets map[int]struct{}
+
+	// TargetRanges is the line range of the block referenced by each target
+	// that has an ID, by target index. Targets whose block could not be found
+	// are absent.
+	TargetRanges map[int]Range
+
+	// TargetChanges is the list of hunks that changed each unsatisfied
+	// target, by target index.
+	TargetChanges map[int][]Hunk
+}
+
+// SatisfiedRule represents a rule whose targets changed and that changed
+// along with them.
+type SatisfiedRule struct {
+	// Rule is the rule that is satisfied.
+	Rule
+
+	// ChangedTargets is the set of indices of the targets that changed.
+	ChangedTargets map[int]struct{}
+
+	// TargetChanges are the hunks that changed each changed target, by
+	// target index.
+	TargetChanges map[int][]Hunk
+}
+
+// RuleOutcome is the outcome of checking a rule whose block or targets
+// changed.
+type RuleOutcome string
+
+const (
+	// RuleOutcomeSatisfied is a rule that changed along with its targets.
+	RuleOutcomeSatisfied RuleOutcome = "satisfied"
+
+	// RuleOutcomeUnsatisfied is a rule whose targets changed without it, or
+	// that changed without all of its targets under strict presence.
+	RuleOutcomeUnsatisfied RuleOutcome = "unsatisfied"
+
+	// RuleOutcomeSkippedByPresence is a rule that changed while none of its
+	// targets did, so that there was nothing to check.
+	RuleOutcomeSkippedByPresence RuleOutcome = "skipped_by_presence"
+)
+
+// EvaluatedRule represents a rule whose block or targets changed.
+type EvaluatedRule struct {
+	// Rule is the rule that was evaluated.
+	Rule
+
+	// Outcome is the outcome of checking the rule.
+	Outcome RuleOutcome
 }
 
 // UnsatisfiedRules is a list of unsatisfied rules.
@@ -91,15 +669,16 @@ type UnsatisfiedRules []UnsatisfiedRule
 func (r *UnsatisfiedRules) String() string {
 	var b strings.Builder
 	for _, rule := range *r {
-		b.WriteString("rule (")
+		b.WriteString(string(rule.Rule.Severity))
+		b.WriteString(": rule ")
 		b.WriteString(rule.Rule.Hunk.File)
-		b.WriteString(":")
-		b.WriteString(fmt.Sprintf("%d", rule.Rule.Hunk.Range.Start))
-		b.WriteString(",")
-		b.WriteString(rule.Rule.Hunk.File)
-		b.WriteString(":")
-		b.WriteString(fmt.Sprintf("%d", rule.Rule.Hunk.Range.End))
-		b.WriteString(") not satisfied for targets:\n")
+		b.WriteString(fmt.Sprintf(":%d-%d", rule.Rule.Hunk.Range.Start, rule.Rule.Hunk.Range.End))
+		if rule.Rule.ID != nil {
+			b.WriteString(" (id: ")
+			b.WriteString(*rule.Rule.ID)
+			b.WriteString(")")
+		}
+		b.WriteString(" not satisfied for targets:\n")
 		for i, target := range rule.Targets {
 			if _, ok := rule.UnsatisfiedTargets[i]; !ok {
 				continue
@@ -108,41 +687,144 @@ func (r *UnsatisfiedRules) String() string {
 			key := TargetKey(rule.Rule.Hunk.File, target)
 			b.WriteString("  ")
 			b.WriteString(key)
+			if target.ID != nil && !target.Wildcard() {
+				if rng, ok := rule.TargetRanges[i]; ok {
+					b.WriteString(fmt.Sprintf(" (lines %d-%d)", rng.Start, rng.End))
+				} else {
+					b.WriteString(" (target block not found)")
+				}
+			}
 			b.WriteString("\n")
 		}
 	}
 	return b.String()
 }
 
+// TargetGroup is a target that changed along with the unsatisfied rules
+// that require a change to it.
+type TargetGroup struct {
+	// Key is the key of the target.
+	Key string
+
+	// Rules are the unsatisfied rules that reference the target.
+	Rules UnsatisfiedRules
+}
+
+// GroupByTarget inverts the unsatisfied rules into one group per target key,
+// sorted by key, so that a target required by many rules is reported once.
+func (r UnsatisfiedRules) GroupByTarget() []TargetGroup {
+	groups := make(map[string]UnsatisfiedRules)
+	for _, rule := range r {
+		for i, target := range rule.Targets {
+			if _, ok := rule.UnsatisfiedTargets[i]; !ok {
+				continue
+			}
+
+			key := TargetKey(rule.Rule.Hunk.File, target)
+			groups[key] = append(groups[key], rule)
+		}
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	result := make([]TargetGroup, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, TargetGroup{Key: key, Rules: groups[key]})
+	}
+
+	return result
+}
+
 // Result of a linting operation.
 type LintResult struct {
 	// List of rules that were not satisfied.
 	UnsatisfiedRules UnsatisfiedRules
+
+	// List of rules whose targets changed and that changed along with them.
+	SatisfiedRules []SatisfiedRule
+
+	// List of rules that have expired and should be removed.
+	ExpiredRules []Rule
+
+	// List of rules whose blocks guard no lines, which is usually an
+	// oversight of a refactor.
+	EmptyRules []Rule
+
+	// List of the checked rules whose block or targets changed, by file and
+	// line, e.g. to count how often rules fire.
+	EvaluatedRules []EvaluatedRule
+
+	// List of non-fatal problems found while linting.
+	Warnings []Warning
+
+	// Explanation of the rule named by LintOptions.Explain, if any.
+	Explanation *Explanation
+
+	// Counters describing the work done while linting.
+	Stats Stats
+}
+
+// Stats represents counters describing the work done by a linting operation.
+type Stats struct {
+	// FilesScanned is the number of files that were walked.
+	FilesScanned int
+
+	// RulesParsed is the number of rules that were parsed.
+	RulesParsed int
+
+	// HunksParsed is the number of diff hunks that were parsed.
+	HunksParsed int
+
+	// RulesFiltered is the number of parsed rules that were not checked
+	// because of OnlyRules, SkipRules, or SkipUnnamed.
+	RulesFiltered int
 }
 
-// Walk walks the file tree rooted at root, calling callback for each file or
-// directory in the tree, including root.
-func Walk(root string, include []string, exclude []string, callback filepath.WalkFunc) error {
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+// Walk walks the file tree rooted at root, calling callback for each file in
+// the tree. Symbolic links and hidden files and directories are skipped,
+// unless they match an include pattern; the .git directory is always
+// skipped. Paths passed to callback use forward slashes regardless of the
+// operating system. The walk stops early with the context's error if ctx is
+// done.
+func Walk(ctx context.Context, root string, include []string, exclude []string, callback filepath.WalkFunc) error {
+	err := filepath.Walk(root, func(pathname string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, pathname)
 		if err != nil {
 			return err
 		}
 
-		if info.IsDir() && filepath.Base(path) == ".git" {
-			return filepath.SkipDir
+		if skipHidden(filepath.ToSlash(rel), info.IsDir(), include) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
 		}
 
-		if info.IsDir() {
+		if info.IsDir() || info.Mode()&fs.ModeSymlink != 0 {
 			return nil
 		}
 
-		included, err := Include(path, include, exclude)
+		pathname = filepath.ToSlash(pathname)
+		included, err := Include(pathname, include, exclude)
 		if err != nil {
 			return err
 		}
 
 		if included {
-			return callback(path, info, nil)
+			return callback(pathname, info, nil)
 		}
 
 		return nil
@@ -154,51 +836,438 @@ func Walk(root string, include []string, exclude []string, callback filepath.Wal
 	return nil
 }
 
-// Lint lints the given hunks against the given rules and returns the result.
-func Lint(o LintOptions) (*LintResult, error) {
-	// Parse the diff hunks.
-	hunks, err := ParseHunks(o.Reader, o.Include, o.Exclude)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse diff hunks")
-	}
-
-	// Parse rules from hunks.
-	rulesMap, presentTargetsMap, err := RulesMapFromHunks(hunks, o)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to parse rules from hunks")
-	}
-
-	// Collect the rules that are not satisfied.
-	unsatisfiedRules, err := Check(rulesMap, presentTargetsMap)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to check rules")
-	}
+// WalkFS walks the file system fsys, calling callback for each file in the
+// tree. Symbolic links and hidden files and directories are skipped, unless
+// they match an include pattern; the .git directory is always skipped. Paths
+// passed to callback are relative to the root of fsys. The walk stops early
+// with the context's error if ctx is done.
+func WalkFS(ctx context.Context, fsys fs.FS, include []string, exclude []string, callback fs.WalkDirFunc) error {
+	return walkFS(ctx, fsys, include, exclude, include, callback)
+}
 
-	// Filter out rules that are not intended to be included in the output.
-	var filteredUnsatisfiedRules UnsatisfiedRules
-	for _, rule := range unsatisfiedRules {
-		included, err := Include(rule.Rule.Hunk.File, o.Include, o.Exclude)
+// walkFS walks fsys like WalkFS, but walks the hidden paths that match the
+// given allowHidden patterns instead of the include patterns.
+func walkFS(ctx context.Context, fsys fs.FS, include, exclude, allowHidden []string, callback fs.WalkDirFunc) error {
+	return fs.WalkDir(fsys, ".", func(pathname string, d fs.DirEntry, err error) error {
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to check if file is included")
+			return err
 		}
 
-		if !included {
-			continue
+		if err := ctx.Err(); err != nil {
+			return err
 		}
 
-		filteredUnsatisfiedRules = append(filteredUnsatisfiedRules, rule)
-	}
+		if skipHidden(pathname, d.IsDir(), allowHidden) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
 
-	return &LintResult{UnsatisfiedRules: filteredUnsatisfiedRules}, nil
+			return nil
+		}
+
+		if d.IsDir() || d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+
+		included, err := Include(pathname, include, exclude)
+		if err != nil {
+			return err
+		}
+
+		if included {
+			return callback(pathname, d, nil)
+		}
+
+		return nil
+	})
+}
+
+// skipHidden returns true if the given slash-separated path, relative to
+// the root of a walk, is hidden and matches none of the given patterns. A
+// hidden directory is kept if a pattern may match a path inside it. The
+// .git directory is always skipped.
+func skipHidden(pathname string, dir bool, allow []string) bool {
+	elements := strings.Split(pathname, "/")
+	var hidden bool
+	for _, element := range elements {
+		if element == ".git" {
+			return true
+		}
+
+		hidden = hidden || strings.HasPrefix(element, ".") && element != "." && element != ".."
+	}
+
+	if !hidden {
+		return false
+	}
+
+	for _, pattern := range allow {
+		p := strings.Split(path.Clean(filepath.ToSlash(pattern)), "/")
+		if matchElements(p, elements) || dir && matchElementsPrefix(p, elements) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// walkFiles calls callback for each of the given files that exists in fsys
+// and is a regular file, in order.
+func walkFiles(ctx context.Context, fsys fs.FS, files []string, callback fs.WalkDirFunc) error {
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		info, err := fs.Stat(fsys, file)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			return callback(file, nil, err)
+		}
+
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		if err := callback(file, fs.FileInfoToDirEntry(info), nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// contentHunks returns the given hunks without the metadata-only ones.
+func contentHunks(hunks []Hunk) []Hunk {
+	filtered := hunks[:0]
+	for _, hunk := range hunks {
+		if !hunk.MetadataOnly {
+			filtered = append(filtered, hunk)
+		}
+	}
+
+	return filtered
+}
+
+// hunks returns the hunks of the diff read from the options' Reader, or of
+// the options' Files if set, that are included by the given patterns.
+func (o LintOptions) hunks(include, exclude []string) ([]Hunk, error) {
+	if o.Files != nil {
+		fsys := o.FS
+		if fsys == nil {
+			root := o.Root
+			if root == "" {
+				root = "."
+			}
+
+			fsys = os.DirFS(root)
+		}
+
+		return HunksFromFiles(fsys, o.Files, include, exclude)
+	}
+
+	r := o.Reader
+	if o.MaxDiffBytes > 0 {
+		r = &maxBytesReader{r: r, limit: o.MaxDiffBytes}
+	}
+
+	r, err := NormalizeDiff(r, o.VCS)
+	if err != nil {
+		return nil, &DiffError{Err: err}
+	}
+
+	hunks, err := ParseHunks(r, include, exclude, o.StripPrefixes, o.MaxHunkLines, o.Logger)
+	if err != nil {
+		return nil, &DiffError{Err: err}
+	}
+
+	return hunks, nil
+}
+
+// Lint lints the given hunks against the given rules and returns the result.
+func Lint(ctx context.Context, o LintOptions) (*LintResult, error) {
+	// Parse the diff hunks.
+	var changesInclude, changesExclude []string
+	if o.FilterScope.filtersChanges() {
+		changesInclude, changesExclude = o.Include, o.Exclude
+	}
+
+	hunks, err := o.hunks(changesInclude, changesExclude)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.ContentOnly {
+		hunks = contentHunks(hunks)
+	}
+
+	// Without changes no rule can be unsatisfied, so skip the walk.
+	if len(hunks) == 0 {
+		loggerOrNop(o.Logger).Printf("no changes: zero hunks")
+		return &LintResult{}, nil
+	}
+
+	// Parse rules from hunks.
+	rulesMap, err := RulesMapFromHunks(ctx, hunks, o)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse rules from hunks")
+	}
+
+	var rulesParsed int
+	for _, rules := range rulesMap.Rules {
+		rulesParsed += len(rules)
+	}
+
+	// Log the rules that are skipped so that suppression is auditable.
+	logger := loggerOrNop(o.Logger)
+	skip := make(map[string]struct{}, len(o.SkipRules))
+	for _, id := range o.SkipRules {
+		skip[id] = struct{}{}
+	}
+
+	// Drop the rules that are not selected by ID before checking.
+	var rulesFiltered int
+	for file, rules := range rulesMap.Rules {
+		selected := rules[:0]
+		for _, rule := range rules {
+			if !SelectsRule(rule, o.OnlyRules, o.SkipUnnamed) {
+				logger.Printf("skipping rule %s:%d-%d by ID", rule.Hunk.File, rule.Hunk.Range.Start, rule.Hunk.Range.End)
+				rulesFiltered++
+				continue
+			}
+
+			if IsSkipped(rule, skip) {
+				rulesFiltered++
+			}
+
+			selected = append(selected, rule)
+		}
+
+		rulesMap.Rules[file] = selected
+	}
+
+	// Expired rules are not checked but reported so that they are removed.
+	now := time.Now()
+	var expiredRules []Rule
+	for _, rules := range rulesMap.Rules {
+		for _, rule := range rules {
+			if !rule.Expired(now) || IsSkipped(rule, skip) {
+				continue
+			}
+
+			reported, err := o.reports(rule)
+			if err != nil {
+				return nil, err
+			}
+
+			if reported {
+				logger.Printf("rule %s:%d-%d expired on %s", rule.Hunk.File, rule.Hunk.Range.Start, rule.Hunk.Range.End, rule.Expires.Format(time.RFC3339))
+				expiredRules = append(expiredRules, rule)
+			}
+		}
+	}
+
+	// Empty rules are checked but reported since they guard nothing.
+	var emptyRules []Rule
+	for _, rules := range rulesMap.Rules {
+		for _, rule := range rules {
+			if !rule.Empty(o.BlankBlocksEmpty) || rule.Expired(now) || IsSkipped(rule, skip) {
+				continue
+			}
+
+			reported, err := o.reports(rule)
+			if err != nil {
+				return nil, err
+			}
+
+			if reported {
+				emptyRules = append(emptyRules, rule)
+			}
+		}
+	}
+
+	for _, rules := range rulesMap.Rules {
+		for _, rule := range rules {
+			if IsSkipped(rule, skip) {
+				logger.Printf("skipping rule %s:%d-%d", rule.Hunk.File, rule.Hunk.Range.Start, rule.Hunk.Range.End)
+			} else if !MatchesTags(rule, o.OnlyTags, o.SkipTags) {
+				logger.Printf("skipping rule %s:%d-%d by tags %v", rule.Hunk.File, rule.Hunk.Range.Start, rule.Hunk.Range.End, rule.Tags)
+			}
+		}
+	}
+
+	// Collect the rules that are not satisfied.
+	unsatisfiedRules, err := Check(rulesMap.Rules, rulesMap.PresentTargets, skip)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to check rules")
+	}
+
+	// With strict presence, the rules that changed must also have all of
+	// their targets changed.
+	strictMisses := make(map[string]struct{})
+	if o.StrictPresence {
+		for _, rule := range NewRuleIndex(rulesMap.Rules).EvaluatePresent(rulesMap.PresentTargets, skip) {
+			strictMisses[fmt.Sprintf("%s:%d", rule.Hunk.File, rule.Hunk.Range.Start)] = struct{}{}
+			unsatisfiedRules = append(unsatisfiedRules, rule)
+		}
+
+		sort.SliceStable(unsatisfiedRules, func(i, j int) bool {
+			a, b := unsatisfiedRules[i].Hunk, unsatisfiedRules[j].Hunk
+			if a.File != b.File {
+				return a.File < b.File
+			}
+
+			return a.Range.Start < b.Range.Start
+		})
+	}
+
+	// Filter out rules that are not intended to be included in the output.
+	var filteredUnsatisfiedRules UnsatisfiedRules
+	for _, rule := range unsatisfiedRules {
+		reported, err := o.reports(rule.Rule)
+		if err != nil {
+			return nil, err
+		}
+
+		if !reported {
+			continue
+		}
+
+		rule.TargetChanges = targetChanges(rule, hunks, rulesMap.TargetSources)
+		filteredUnsatisfiedRules = append(filteredUnsatisfiedRules, rule)
+	}
+
+	// Collect the rules whose targets changed along with them.
+	var satisfiedRules []SatisfiedRule
+	for _, rule := range CheckSatisfied(rulesMap.Rules, rulesMap.PresentTargets, skip) {
+		if _, ok := strictMisses[fmt.Sprintf("%s:%d", rule.Hunk.File, rule.Hunk.Range.Start)]; ok {
+			continue
+		}
+
+		reported, err := o.reports(rule.Rule)
+		if err != nil {
+			return nil, err
+		}
+
+		if reported {
+			rule.TargetChanges = make(map[int][]Hunk, len(rule.ChangedTargets))
+			for i := range rule.ChangedTargets {
+				rule.TargetChanges[i] = rulesMap.TargetSources[TargetKey(rule.Hunk.File, rule.Targets[i])]
+			}
+
+			satisfiedRules = append(satisfiedRules, rule)
+		}
+	}
+
+	evaluatedRules, err := o.evaluatedRules(rulesMap.Rules, filteredUnsatisfiedRules, satisfiedRules, skip)
+	if err != nil {
+		return nil, err
+	}
+
+	var explanation *Explanation
+	if o.Explain != "" {
+		explanation, err = Explain(rulesMap, o.Explain, skip)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &LintResult{
+		UnsatisfiedRules: filteredUnsatisfiedRules,
+		SatisfiedRules:   satisfiedRules,
+		ExpiredRules:     expiredRules,
+		EmptyRules:       emptyRules,
+		EvaluatedRules:   evaluatedRules,
+		Warnings:         rulesMap.Warnings,
+		Explanation:      explanation,
+		Stats: Stats{
+			FilesScanned:  rulesMap.FilesScanned,
+			RulesParsed:   rulesParsed,
+			HunksParsed:   len(hunks),
+			RulesFiltered: rulesFiltered,
+		},
+	}, nil
+}
+
+// evaluatedRules returns the reported rules of the given rules map whose
+// block or targets changed, along with their outcome, sorted by file and
+// line. Expired and skipped rules are not checked and so not returned.
+func (o LintOptions) evaluatedRules(rulesMap map[string][]Rule, unsatisfied UnsatisfiedRules, satisfied []SatisfiedRule, skip map[string]struct{}) ([]EvaluatedRule, error) {
+	outcomes := make(map[string]RuleOutcome, len(unsatisfied)+len(satisfied))
+	for _, rule := range satisfied {
+		outcomes[fmt.Sprintf("%s:%d", rule.Hunk.File, rule.Hunk.Range.Start)] = RuleOutcomeSatisfied
+	}
+
+	for _, rule := range unsatisfied {
+		outcomes[fmt.Sprintf("%s:%d", rule.Hunk.File, rule.Hunk.Range.Start)] = RuleOutcomeUnsatisfied
+	}
+
+	files := make([]string, 0, len(rulesMap))
+	for file := range rulesMap {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	now := time.Now()
+	var evaluated []EvaluatedRule
+	for _, file := range files {
+		for _, rule := range rulesMap[file] {
+			outcome, ok := outcomes[fmt.Sprintf("%s:%d", rule.Hunk.File, rule.Hunk.Range.Start)]
+			if !ok {
+				if !rule.Present || rule.Expired(now) || IsSkipped(rule, skip) {
+					continue
+				}
+
+				reported, err := o.reports(rule)
+				if err != nil {
+					return nil, err
+				}
+
+				if !reported {
+					continue
+				}
+
+				outcome = RuleOutcomeSkippedByPresence
+			}
+
+			evaluated = append(evaluated, EvaluatedRule{Rule: rule, Outcome: outcome})
+		}
+	}
+
+	return evaluated, nil
+}
+
+// reports returns true if the given rule passes the tag filters and, if
+// the filter scope applies to rules, the include and exclude patterns.
+func (o LintOptions) reports(rule Rule) (bool, error) {
+	if !MatchesTags(rule, o.OnlyTags, o.SkipTags) {
+		return false, nil
+	}
+
+	if !o.FilterScope.filtersRules() {
+		return true, nil
+	}
+
+	included, err := Include(rule.Hunk.File, o.Include, o.Exclude)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to check if file is included")
+	}
+
+	return included, nil
 }
 
-// TargetKey returns the key for the given target.
+// TargetKey returns the key for the given target. Keys always use forward
+// slashes so that they match the paths found in diffs.
 func TargetKey(pathname string, target Target) string {
-	key := string(pathname)
+	key := NormalizeKey(pathname)
 	if target.File != nil && *target.File != "" {
-		key = *target.File
-		if isRelativeToCurrentDirectory(*target.File) {
-			key = filepath.Join(filepath.Dir(pathname), *target.File)
+		file := filepath.ToSlash(*target.File)
+		key = NormalizeKey(file)
+		if isRelativeToCurrentDirectory(file) {
+			key = NormalizeKey(path.Join(path.Dir(pathname), file))
 		}
 	}
 
@@ -206,7 +1275,19 @@ func TargetKey(pathname string, target Target) string {
 		key += ":" + *target.ID
 	}
 
-	return filepath.Clean(key)
+	if target.Range != nil {
+		key += fmt.Sprintf("#L%d-L%d", target.Range.Start, target.Range.End)
+	}
+
+	return key
+}
+
+// NormalizeKey returns the canonical form of the given path as used in
+// target keys: forward slashes, "." and ".." elements collapsed, and no
+// duplicate or trailing slashes, so that "dir/./file.go", "dir//file.go",
+// and "./dir/file.go" all become "dir/file.go".
+func NormalizeKey(pathname string) string {
+	return path.Clean(filepath.ToSlash(pathname))
 }
 
 // isRelativeToCurrentDirectory returns true if the given path is a specific relative path.
@@ -223,94 +1304,524 @@ func isRelativeToCurrentDirectory(path string) bool {
 }
 
 // Check returns the list of unsatisfied rules for the given map of rules.
-func Check(rulesMap map[string][]Rule, targetsMap map[string]struct{}) (UnsatisfiedRules, error) {
-	var unsatisfiedRules UnsatisfiedRules
+// Rules in the skip set are not checked. Callers that evaluate the same rules
+// repeatedly should build a RuleIndex once and call its Evaluate method.
+func Check(rulesMap map[string][]Rule, targetsMap map[string]struct{}, skip map[string]struct{}) (UnsatisfiedRules, error) {
+	return NewRuleIndex(rulesMap).Evaluate(targetsMap, skip)
+}
 
-	// Check each rule.
+// CheckSatisfied returns the rules that are satisfied: their block changed
+// and so did at least one of their targets. Rules that expired or are in the
+// skip set are not returned.
+func CheckSatisfied(rulesMap map[string][]Rule, targetsMap map[string]struct{}, skip map[string]struct{}) []SatisfiedRule {
+	var satisfiedRules []SatisfiedRule
+	now := time.Now()
 	for _, rules := range rulesMap {
 		for _, rule := range rules {
-			if rule.Present {
+			if !rule.Present || rule.Expired(now) || IsSkipped(rule, skip) {
 				continue
 			}
 
-			unsatisfiedTargets := make(map[int]struct{}, len(rule.Targets))
+			changedTargets := make(map[int]struct{}, len(rule.Targets))
 			for i, target := range rule.Targets {
 				key := TargetKey(rule.Hunk.File, target)
 				if _, ok := targetsMap[key]; ok {
-					unsatisfiedTargets[i] = struct{}{}
+					changedTargets[i] = struct{}{}
 				}
 			}
 
-			// If there are unsatisfied targets, then the rule is unsatisfied.
-			if len(unsatisfiedTargets) > 0 {
-				unsatisfiedRules = append(unsatisfiedRules, UnsatisfiedRule{
-					Rule:               rule,
-					UnsatisfiedTargets: unsatisfiedTargets,
+			if len(changedTargets) > 0 {
+				satisfiedRules = append(satisfiedRules, SatisfiedRule{
+					Rule:           rule,
+					ChangedTargets: changedTargets,
 				})
 			}
 		}
 	}
 
-	// Return the unordered list of unsatisfied rules.
-	return unsatisfiedRules, nil
+	return satisfiedRules
 }
 
-// Do is the difflint command's entrypoint.
-func Do(r io.Reader, include, exclude []string, extMapPath string) (UnsatisfiedRules, error) {
-	// Parse options.
-	extMap := NewExtMap(extMapPath)
+// targetChanges returns the hunks that changed each unsatisfied target of
+// the given rule. The changes of wildcard targets are taken from sources.
+func targetChanges(rule UnsatisfiedRule, hunks []Hunk, sources map[string][]Hunk) map[int][]Hunk {
+	changes := make(map[int][]Hunk, len(rule.UnsatisfiedTargets))
+	for i := range rule.UnsatisfiedTargets {
+		target := rule.Targets[i]
+		if target.Wildcard() {
+			changes[i] = sources[TargetKey(rule.Hunk.File, target)]
+			continue
+		}
+
+		file := TargetKey(rule.Hunk.File, Target{File: target.File})
+		rng, hasRange := rule.TargetRanges[i]
+		for _, hunk := range hunks {
+			if !matchTargetFile(file, NormalizeKey(hunk.File)) {
+				continue
+			}
+
+			if (target.ID != nil || target.Range != nil) && (!hasRange || !Intersects(hunk.Range, rng)) {
+				continue
+			}
+
+			changes[i] = append(changes[i], hunk)
+		}
+
+		// Targets resolved by a TargetResolver do not name the changed
+		// files themselves.
+		if len(changes[i]) == 0 {
+			changes[i] = sources[TargetKey(rule.Hunk.File, target)]
+		}
+	}
+
+	return changes
+}
+
+// matchTargetFile returns true if the given target file key, which may be a
+// glob pattern, matches the given file name. A "**" element of the pattern
+// matches any number of directories.
+func matchTargetFile(key, file string) bool {
+	if !isGlob(key) {
+		return key == file
+	}
+
+	return matchElements(strings.Split(key, "/"), strings.Split(file, "/"))
+}
+
+// matchElements returns true if the given pattern elements match the given
+// path elements.
+func matchElements(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchElements(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// matchElementsPrefix returns true if the given pattern elements may match
+// a path inside the given path elements.
+func matchElementsPrefix(pattern, name []string) bool {
+	for ; len(name) > 0; pattern, name = pattern[1:], name[1:] {
+		if len(pattern) == 0 {
+			return false
+		}
+
+		if pattern[0] == "**" {
+			return true
+		}
+
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// targetRanges resolves the line ranges of the blocks referenced by the given
+// targets of the rule.
+func targetRanges(rule Rule, targets map[int]struct{}, rulesMap map[string][]Rule) map[int]Range {
+	ranges := make(map[int]Range, len(targets))
+	for i := range targets {
+		target := rule.Targets[i]
+		if target.Range != nil {
+			ranges[i] = *target.Range
+			continue
+		}
+
+		if target.ID == nil {
+			continue
+		}
+
+		file := TargetKey(rule.Hunk.File, Target{File: target.File})
+		for _, r := range rulesMap[file] {
+			if r.ID != nil && *r.ID == *target.ID {
+				ranges[i] = r.Hunk.Range
+				break
+			}
+		}
+	}
+
+	return ranges
+}
+
+// DoOptions represents the options for the difflint command: the options
+// of a lint along with the files from which its templates, rules, and index
+// are loaded. Only Reader, or Files, is required.
+type DoOptions struct {
+	// LintOptions are the options of the lint. Templates and FileExtMap are
+	// loaded from ExtMapPath, and Index from IndexPath if it is set. The
+	// rules and aliases of RulesPath are added to ConfigRules and
+	// TargetAliases, whose aliases take precedence.
+	LintOptions
+
+	// ExtMapPath is the path to a JSON file extension map. If empty, the
+	// default templates and file extension map are used.
+	ExtMapPath string
+
+	// RulesPath is the path to a JSON file of rules declared outside of
+	// directives. If empty, only directives and ConfigRules are checked.
+	RulesPath string
+
+	// IndexPath is the path to a rule index file written by the index
+	// command. If empty, Index is used.
+	IndexPath string
+}
+
+// DoWith is the difflint command's entrypoint.
+func DoWith(ctx context.Context, o DoOptions) (*LintResult, error) {
+	options, err := o.lintOptions()
+	if err != nil {
+		return nil, err
+	}
 
 	// Lint the hunks.
-	result, err := Lint(LintOptions{
-		Reader:          r,
-		Include:         include,
-		Exclude:         exclude,
-		DefaultTemplate: 0,
-		Templates:       extMap.Templates,
-		FileExtMap:      extMap.FileExtMap,
-	})
+	result, err := Lint(ctx, options)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to lint hunks")
 	}
 
+	return result, nil
+}
+
+// RulesWith parses the rules of every file under the root without
+// comparing them against a diff, so none of them is present.
+func RulesWith(ctx context.Context, o DoOptions) (*RulesMap, error) {
+	options, err := o.lintOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	rulesMap, err := RulesMapFromHunks(ctx, nil, options)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse rules")
+	}
+
+	return rulesMap, nil
+}
+
+// lintOptions returns the lint options of the difflint command.
+func (o DoOptions) lintOptions() (LintOptions, error) {
+	options := o.LintOptions
+	if options.DirectiveWord == "" {
+		options.DirectiveWord = DefaultDirectiveWord
+	}
+
+	extMap, err := NewExtMapWithWord(o.ExtMapPath, options.DirectiveWord)
+	if err != nil {
+		return LintOptions{}, errors.Wrap(err, "failed to load file extension map")
+	}
+
+	options.Templates, options.FileExtMap, options.DefaultTemplate = extMap.Templates, extMap.FileExtMap, 0
+	if o.RulesPath != "" {
+		rulesFile, err := LoadRulesFile(o.RulesPath)
+		if err != nil {
+			return LintOptions{}, err
+		}
+
+		options.ConfigRules = append(append([]ConfigRule(nil), o.ConfigRules...), rulesFile.Rules...)
+		if len(rulesFile.Aliases) > 0 {
+			options.TargetAliases = make(map[string]string, len(o.TargetAliases)+len(rulesFile.Aliases))
+			for alias, dir := range rulesFile.Aliases {
+				options.TargetAliases[alias] = dir
+			}
+
+			for alias, dir := range o.TargetAliases {
+				options.TargetAliases[alias] = dir
+			}
+		}
+	}
+
+	if o.IndexPath != "" {
+		options.Index, err = ReadIndexFile(o.IndexPath)
+		if err != nil {
+			return LintOptions{}, err
+		}
+	}
+
+	return options, nil
+}
+
+// Do lints the diff read from r and returns the unsatisfied rules.
+//
+// Deprecated: Use DoWith, which accepts a DoOptions struct.
+func Do(r io.Reader, include, exclude []string, extMapPath string) (UnsatisfiedRules, error) {
+	result, err := DoWith(context.Background(), DoOptions{
+		LintOptions: LintOptions{
+			Reader:  r,
+			Include: include,
+			Exclude: exclude,
+		},
+		ExtMapPath: extMapPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return result.UnsatisfiedRules, nil
 }
 
 // ParseHunks parses the input diff and returns the extracted file paths along
-// with associated line number ranges.
-func ParseHunks(r io.Reader, include, exclude []string) ([]Hunk, error) {
-	diffs, err := diff.NewMultiFileDiffReader(r).ReadAllFiles()
+// with associated line number ranges. Hunks of files that are not included by
+// the include and exclude patterns are dropped, so that they neither make
+// rules present nor satisfy targets; Lint only passes the patterns on if the
+// filter scope applies to changes.
+// At most maxHunkLines added and removed lines are stored per hunk; zero means
+// DefaultMaxHunkLines and a negative value means no limit.
+// The diff is read one file at a time, and the bodies of its hunks are
+// dropped once their lines are extracted.
+func ParseHunks(r io.Reader, include, exclude, stripPrefixes []string, maxHunkLines int, logger Logger) ([]Hunk, error) {
+	br := bufio.NewReader(r)
+
+	// An empty or whitespace-only diff has no changes.
+	empty, err := skipSpace(br)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to read files")
+		return nil, errors.Wrap(err, "failed to read diff")
 	}
 
-	hunks := make([]Hunk, 0, len(diffs))
-	for _, d := range diffs {
+	if empty {
+		return nil, nil
+	}
+
+	if maxHunkLines == 0 {
+		maxHunkLines = DefaultMaxHunkLines
+	}
+
+	var hunks []Hunk
+	logger = loggerOrNop(logger)
+	files := diff.NewMultiFileDiffReader(br)
+	for {
+		d, err := files.ReadFile()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read files")
+		}
+
+		file := DiffFileName(d, stripPrefixes)
+		if isSubmoduleDiff(d) {
+			logger.Printf("skipping submodule %s", file)
+			continue
+		}
+
+		included, err := Include(file, include, exclude)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to check if file is included")
+		}
+
+		if !included {
+			logger.Printf("skipping changes in excluded file %s", file)
+			continue
+		}
+
+		added := unquoteDiffName(d.OrigName) == "/dev/null"
+		deleted := unquoteDiffName(d.NewName) == "/dev/null"
+		var fileLines int
 		for _, h := range d.Hunks {
 			hunk := Hunk{
-				File: strings.TrimPrefix(d.NewName, "b/"),
+				File: file,
 				Range: Range{
 					Start: int(h.NewStartLine),
 					End:   int(h.NewStartLine + h.NewLines - 1),
-				}}
+				},
+				Added:   added,
+				Deleted: deleted,
+			}
+			limit := maxHunkLines
+			if limit >= 0 && maxFileLines-fileLines < limit {
+				limit = maxFileLines - fileLines
+			}
+
+			hunk.AddedLines, hunk.RemovedLines, hunk.Truncated = hunkLines(h.Body, limit)
+			fileLines += len(hunk.AddedLines) + len(hunk.RemovedLines)
+
+			// A deleted file has no new lines, so use the original ones.
+			if deleted {
+				hunk.Range = Range{
+					Start: int(h.OrigStartLine),
+					End:   int(h.OrigStartLine + h.OrigLines - 1),
+				}
+			}
+
 			hunks = append(hunks, hunk)
 		}
+
+		// An empty added file has no hunks but is still present in the diff.
+		if added && len(d.Hunks) == 0 {
+			hunks = append(hunks, Hunk{File: file, Added: true})
+			continue
+		}
+
+		// So is a file whose mode changed or a binary file.
+		if len(d.Hunks) == 0 && isMetadataDiff(d) {
+			hunks = append(hunks, Hunk{File: file, Deleted: deleted, MetadataOnly: true})
+		}
 	}
 
 	return hunks, nil
 }
 
+// skipSpace reads the leading white space of r and returns true if there is
+// nothing else.
+func skipSpace(r *bufio.Reader) (bool, error) {
+	for {
+		c, err := r.ReadByte()
+		if err == io.EOF {
+			return true, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		if !unicode.IsSpace(rune(c)) {
+			return false, r.UnreadByte()
+		}
+	}
+}
+
+// hunkLines returns the added and removed lines of the given hunk body,
+// storing at most limit lines in total unless limit is negative.
+func hunkLines(body []byte, limit int) (added, removed []string, truncated bool) {
+	for len(body) > 0 {
+		line := body
+		if i := bytes.IndexByte(body, '\n'); i >= 0 {
+			line, body = body[:i], body[i+1:]
+		} else {
+			body = nil
+		}
+
+		if len(line) == 0 || (line[0] != '+' && line[0] != '-') {
+			continue
+		}
+
+		if limit >= 0 && len(added)+len(removed) >= limit {
+			truncated = true
+			break
+		}
+
+		// Copy the line so that the body is not retained.
+		if line[0] == '+' {
+			added = append(added, string(line[1:]))
+		} else {
+			removed = append(removed, string(line[1:]))
+		}
+	}
+
+	return added, removed, truncated
+}
+
+// DiffFileName returns the path of the new file in the given file diff, or
+// of the original file if it was deleted, with the first matching prefix
+// stripped. If no prefixes are given, the "b/" (or "a/") prefix is stripped
+// only if the diff uses git's default "a/" and "b/" prefixes.
+func DiffFileName(d *diff.FileDiff, stripPrefixes []string) string {
+	name := unquoteDiffName(d.NewName)
+	otherName := unquoteDiffName(d.OrigName)
+	prefix, otherPrefix := "b/", "a/"
+	if name == "/dev/null" {
+		name, otherName = otherName, name
+		prefix, otherPrefix = otherPrefix, prefix
+	}
+
+	if len(stripPrefixes) > 0 {
+		for _, prefix := range stripPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				return strings.TrimPrefix(name, prefix)
+			}
+		}
+
+		return name
+	}
+
+	hasOtherPrefix := strings.HasPrefix(otherName, otherPrefix) || otherName == "/dev/null"
+	if hasOtherPrefix && strings.HasPrefix(name, prefix) {
+		return strings.TrimPrefix(name, prefix)
+	}
+
+	return name
+}
+
+// isSubmoduleDiff returns true if the given file diff is a submodule update,
+// which git represents as a pseudo-diff of "Subproject commit" lines.
+func isSubmoduleDiff(d *diff.FileDiff) bool {
+	for _, header := range d.Extended {
+		if strings.HasSuffix(header, " 160000") {
+			return true
+		}
+	}
+
+	for _, h := range d.Hunks {
+		body := bytes.TrimLeft(h.Body, "+- ")
+		if bytes.HasPrefix(body, []byte("Subproject commit ")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isMetadataDiff returns true if the given file diff changes the file's mode
+// or a binary file, neither of which has hunks.
+func isMetadataDiff(d *diff.FileDiff) bool {
+	for _, header := range d.Extended {
+		if strings.HasPrefix(header, "new mode ") || strings.HasPrefix(header, "GIT binary patch") || (strings.HasPrefix(header, "Binary files ") && strings.HasSuffix(header, " differ")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unquoteDiffName unquotes a file name that git quoted because it contains
+// spaces, escapes, or non-ASCII characters.
+func unquoteDiffName(name string) string {
+	if len(name) < 2 || name[0] != '"' || name[len(name)-1] != '"' {
+		return name
+	}
+
+	unquoted, err := strconv.Unquote(name)
+	if err != nil {
+		return name
+	}
+
+	return unquoted
+}
+
 // Include determines if a given diff should be included in the linting process.
+// The pathname and patterns are matched using forward slashes.
 func Include(pathname string, include, exclude []string) (bool, error) {
 	// If there are no include or exclude rules, return true.
 	if len(include) == 0 && len(exclude) == 0 {
 		return true, nil
 	}
 
+	pathname = NormalizeKey(pathname)
+
 	// If there are exclude rules, check if the diff matches any of them.
 	if len(exclude) > 0 {
 		for _, e := range exclude {
-			if matched, err := filepath.Match(e, pathname); err != nil {
+			if matched, err := path.Match(filepath.ToSlash(e), pathname); err != nil {
 				return false, errors.Wrap(err, "failed to match exclude rule")
 			} else if matched {
 				return false, nil
@@ -318,10 +1829,15 @@ func Include(pathname string, include, exclude []string) (bool, error) {
 		}
 	}
 
+	// If there are no include rules, everything not excluded is included.
+	if len(include) == 0 {
+		return true, nil
+	}
+
 	// If there are include rules, check if the diff matches any of them.
 	if len(include) > 0 {
 		for _, i := range include {
-			if matched, err := filepath.Match(i, pathname); err != nil {
+			if matched, err := path.Match(filepath.ToSlash(i), pathname); err != nil {
 				return false, errors.Wrap(err, "failed to match include rule")
 			} else if matched {
 				return true, nil
@@ -0,0 +1,407 @@
+package difflint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTree writes the given files, by slash-separated path, under a new
+// temporary directory and returns the directory.
+func writeTree(t testing.TB, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+// syntheticTree returns a tree of n Go files spread over directories, each
+// of which holds a rule.
+func syntheticTree(n int) map[string]string {
+	files := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		files[fmt.Sprintf("pkg%d/f%d.go", i%50, i)] = "package p\n//LINT.IF target.go\nvar X = 1\n//LINT.END\n"
+	}
+
+	return files
+}
+
+func TestWalkCancel(t *testing.T) {
+	root := writeTree(t, syntheticTree(2000))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var visited int
+	start := time.Now()
+	err := Walk(ctx, root, nil, nil, func(pathname string, info os.FileInfo, err error) error {
+		visited++
+		if visited == 100 {
+			cancel()
+		}
+
+		return err
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Walk() = %v, want %v", err, context.Canceled)
+	}
+
+	if visited != 100 {
+		t.Errorf("Walk() visited %d files after cancellation at 100", visited)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Walk() took %s to stop", elapsed)
+	}
+}
+
+func TestWalkFSCancel(t *testing.T) {
+	root := writeTree(t, syntheticTree(2000))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var visited int
+	err := WalkFS(ctx, os.DirFS(root), nil, nil, func(pathname string, d fs.DirEntry, err error) error {
+		visited++
+		if visited == 100 {
+			cancel()
+		}
+
+		return err
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("WalkFS() = %v, want %v", err, context.Canceled)
+	}
+
+	if visited != 100 {
+		t.Errorf("WalkFS() visited %d files after cancellation at 100", visited)
+	}
+}
+
+func TestLintCancel(t *testing.T) {
+	root := writeTree(t, syntheticTree(2000))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Lint(ctx, LintOptions{
+		Root:       root,
+		Reader:     strings.NewReader("diff --git a/target.go b/target.go\n--- a/target.go\n+++ b/target.go\n@@ -1,1 +1,1 @@\n-a\n+b\n"),
+		Templates:  DefaultTemplates,
+		FileExtMap: DefaultFileExtMap,
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Lint() = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestDoWithZeroOptions(t *testing.T) {
+	result, err := DoWith(context.Background(), DoOptions{LintOptions: LintOptions{Reader: strings.NewReader("")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.UnsatisfiedRules) != 0 {
+		t.Errorf("DoWith() = %v, want no unsatisfied rules", result.UnsatisfiedRules)
+	}
+}
+
+func TestDoOptionsLintOptions(t *testing.T) {
+	o := DoOptions{LintOptions: LintOptions{
+		Root:          "root",
+		DirectiveWord: "DIFF",
+		SkipRules:     []string{"a"},
+		MaxFileSize:   42,
+		TargetAliases: map[string]string{"@api": "api"},
+	}}
+
+	options, err := o.lintOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options.Root != "root" || options.SkipRules[0] != "a" || options.MaxFileSize != 42 || options.TargetAliases["@api"] != "api" {
+		t.Errorf("lintOptions() = %+v, want the options of o", options)
+	}
+
+	if options.Templates[1] != "//DIFF.?" {
+		t.Errorf("lintOptions().Templates = %q, want the templates of DIFF", options.Templates)
+	}
+}
+
+// syntheticDiff returns a file of n rules targeting target.go and a diff
+// with a one-line hunk in each of the rules' blocks, and one in target.go.
+func syntheticDiff(n int) (file, diff string) {
+	var f, d strings.Builder
+	d.WriteString("diff --git a/gen.go b/gen.go\n--- a/gen.go\n+++ b/gen.go\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&f, "//LINT.IF target.go\nvar X%d = 1\n//LINT.END\n\n", i)
+		fmt.Fprintf(&d, "@@ -%d,1 +%d,1 @@\n-var X%d = 0\n+var X%d = 1\n", 4*i+2, 4*i+2, i, i)
+	}
+
+	d.WriteString("diff --git a/target.go b/target.go\n--- a/target.go\n+++ b/target.go\n@@ -1,1 +1,1 @@\n-a\n+b\n")
+	return f.String(), d.String()
+}
+
+// benchmarkRules is the number of synthetic rules checked by BenchmarkCheck.
+const benchmarkRules = 50000
+
+func BenchmarkCheck(b *testing.B) {
+	file, diff := syntheticDiff(benchmarkRules)
+	root := writeTree(b, map[string]string{"gen.go": file, "target.go": "b\n"})
+	hunks, err := ParseHunks(strings.NewReader(diff), nil, nil, nil, 0, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var ranges Ranges
+	for _, hunk := range hunks {
+		if hunk.File == "gen.go" {
+			ranges = append(ranges, hunk.Range)
+		}
+	}
+
+	rules := make([]Range, benchmarkRules)
+	for i := range rules {
+		rules[i] = Range{Start: 4*i + 2, End: 4*i + 2}
+	}
+
+	// linear is the presence check without merged ranges, for comparison.
+	b.Run("presence/linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, rule := range rules {
+				for _, rng := range ranges {
+					if Intersects(rule, rng) {
+						break
+					}
+				}
+			}
+		}
+	})
+
+	b.Run("presence/merged", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			merged := ranges.Merge()
+			for _, rule := range rules {
+				merged.AnyIntersects(rule)
+			}
+		}
+	})
+
+	b.Run("lint", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			result, err := Lint(context.Background(), LintOptions{
+				Root:       root,
+				Reader:     strings.NewReader(diff),
+				Templates:  DefaultTemplates,
+				FileExtMap: DefaultFileExtMap,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			if len(result.UnsatisfiedRules) != 0 || len(result.SatisfiedRules) != benchmarkRules {
+				b.Fatalf("Lint() = %d unsatisfied and %d satisfied rules, want %d satisfied", len(result.UnsatisfiedRules), len(result.SatisfiedRules), benchmarkRules)
+			}
+		}
+	})
+
+	// Each rule targets a file of its own, so a change to one target makes
+	// one rule unsatisfied.
+	rulesMap := make(map[string][]Rule)
+	for i := 0; i < benchmarkRules; i++ {
+		file, target := fmt.Sprintf("pkg%d/f%d.go", i%50, i), fmt.Sprintf("target%d.go", i)
+		rulesMap[file] = append(rulesMap[file], Rule{
+			Hunk:    Hunk{File: file, Range: Range{Start: 1, End: 3}},
+			Body:    Range{Start: 2, End: 2},
+			Targets: []Target{{File: &target, Raw: target}},
+		})
+	}
+
+	changed := map[string]struct{}{"target7.go": {}}
+	check := func(b *testing.B, unsatisfied UnsatisfiedRules, err error) {
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if len(unsatisfied) != 1 || unsatisfied[0].Hunk.File != "pkg7/f7.go" {
+			b.Fatalf("unsatisfied rules = %v, want the rule of pkg7/f7.go", unsatisfied)
+		}
+	}
+
+	// check builds the index of every rule for a single-hunk change.
+	b.Run("check", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			unsatisfied, err := Check(rulesMap, changed, nil)
+			check(b, unsatisfied, err)
+		}
+	})
+
+	// index/evaluate reuses the index across single-hunk changes, as in
+	// watch mode.
+	b.Run("index/evaluate", func(b *testing.B) {
+		idx := NewRuleIndex(rulesMap)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			unsatisfied, err := idx.Evaluate(changed, nil)
+			check(b, unsatisfied, err)
+		}
+	})
+}
+
+// BenchmarkRulesMapFromHunksNoDirectives walks a tree of 10k files without
+// directives, each of which the prefilter skips without lexing it.
+func BenchmarkRulesMapFromHunksNoDirectives(b *testing.B) {
+	files := make(map[string]string, 10000)
+	for i := 0; i < 10000; i++ {
+		files[fmt.Sprintf("pkg%d/f%d.go", i%100, i)] = fmt.Sprintf("package p\n\n// X%d is a LINT-free value.\nvar X%d = %d\n", i, i, i)
+	}
+
+	root := writeTree(b, files)
+	options := LintOptions{Root: root, Templates: DefaultTemplates, FileExtMap: DefaultFileExtMap}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rulesMap, err := RulesMapFromHunks(context.Background(), nil, options)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if len(rulesMap.Rules) != 0 || rulesMap.FilesScanned != 10000 {
+			b.Fatalf("RulesMapFromHunks() = %d files with rules of %d scanned, want none of 10000", len(rulesMap.Rules), rulesMap.FilesScanned)
+		}
+	}
+}
+
+// diffGenerator generates a diff of files, each of which adds the same
+// lines, without holding the diff in memory.
+type diffGenerator struct {
+	// files is the number of files left to generate.
+	files int
+
+	// lines is the number of lines added to each file.
+	lines int
+
+	// pending holds the rest of the current file.
+	pending []byte
+}
+
+// Read implements io.Reader.
+func (g *diffGenerator) Read(p []byte) (int, error) {
+	if len(g.pending) == 0 {
+		if g.files == 0 {
+			return 0, io.EOF
+		}
+
+		g.files--
+		var b strings.Builder
+		fmt.Fprintf(&b, "diff --git a/f%d.lock b/f%d.lock\n--- a/f%d.lock\n+++ b/f%d.lock\n@@ -1,0 +1,%d @@\n", g.files, g.files, g.files, g.files, g.lines)
+		for i := 0; i < g.lines; i++ {
+			b.WriteString("+lorem ipsum dolor sit amet, consectetur adipiscing\n")
+		}
+
+		g.pending = []byte(b.String())
+	}
+
+	n := copy(p, g.pending)
+	g.pending = g.pending[n:]
+	return n, nil
+}
+
+func BenchmarkParseHunks(b *testing.B) {
+	// Each file's diff is about 100KB.
+	const lines = 2000
+	for _, files := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("%dMB", files/10), func(b *testing.B) {
+			b.ReportAllocs()
+			var retained uint64
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				hunks, err := ParseHunks(&diffGenerator{files: files, lines: lines}, nil, nil, nil, 0, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				if len(hunks) != files {
+					b.Fatalf("ParseHunks() = %d hunks, want %d", len(hunks), files)
+				}
+
+				if after.HeapAlloc > before.HeapAlloc {
+					retained += after.HeapAlloc - before.HeapAlloc
+				}
+				runtime.KeepAlive(hunks)
+			}
+
+			b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+		})
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"dir/file.go", "dir/file.go"},
+		{"./dir/file.go", "dir/file.go"},
+		{"dir/./file.go", "dir/file.go"},
+		{"dir//file.go", "dir/file.go"},
+		{"dir///sub//file.go", "dir/sub/file.go"},
+		{"dir/sub/../file.go", "dir/file.go"},
+		{"dir/sub/./../file.go", "dir/file.go"},
+		{"dir/", "dir"},
+		{"dir//", "dir"},
+		{"./dir/./", "dir"},
+		{".", "."},
+		{"./", "."},
+		{"dir/..", "."},
+		{"../file.go", "../file.go"},
+		{"dir/../../file.go", "../file.go"},
+		{"/abs//dir/../file.go", "/abs/file.go"},
+		{filepath.Join("dir", "sub", "file.go"), "dir/sub/file.go"},
+	}
+
+	for _, test := range tests {
+		if got := NormalizeKey(test.path); got != test.want {
+			t.Errorf("NormalizeKey(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestTargetKeyMatchesHunkKey(t *testing.T) {
+	for _, file := range []string{"dir/file.go", "dir/./file.go", "dir//file.go", "./dir/file.go", "other/../dir/file.go"} {
+		if got, want := TargetKey("a.go", Target{File: &file}), NormalizeKey("dir/file.go"); got != want {
+			t.Errorf("TargetKey(%q) = %q, want the hunk key %q", file, got, want)
+		}
+	}
+
+	relative := "../dir/file.go"
+	if got := TargetKey("sub//a.go", Target{File: &relative}); got != "dir/file.go" {
+		t.Errorf("TargetKey(%q) from sub//a.go = %q, want %q", relative, got, "dir/file.go")
+	}
+}
@@ -0,0 +1,136 @@
+// Package difflinttest provides helpers for testing LINT rules: a builder of
+// unified diffs and assertions that lint them against an in-memory tree.
+package difflinttest
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ethanthatonekid/difflint"
+)
+
+// DiffBuilder builds a unified diff, as printed by git diff, one file change
+// at a time. The zero value is an empty diff.
+type DiffBuilder struct {
+	// b holds the diff built so far.
+	b strings.Builder
+}
+
+// NewDiffBuilder returns an empty diff builder.
+func NewDiffBuilder() *DiffBuilder {
+	return &DiffBuilder{}
+}
+
+// AddFile adds the creation of the named file with the given content.
+func (d *DiffBuilder) AddFile(name, content string) *DiffBuilder {
+	lines := splitLines(content)
+	fmt.Fprintf(&d.b, "diff --git a/%s b/%s\nnew file mode 100644\n", name, name)
+	if len(lines) == 0 {
+		return d
+	}
+
+	fmt.Fprintf(&d.b, "--- /dev/null\n+++ b/%s\n@@ -0,0 +1,%d @@\n", name, len(lines))
+	for _, line := range lines {
+		d.b.WriteString("+" + line + "\n")
+	}
+
+	return d
+}
+
+// ModifyLines adds a change of lines start through end, inclusive, of the
+// named file. The lines are replaced by placeholders; only their numbers
+// matter to difflint.
+func (d *DiffBuilder) ModifyLines(name string, start, end int) *DiffBuilder {
+	n := end - start + 1
+	fmt.Fprintf(&d.b, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n@@ -%d,%d +%d,%d @@\n", name, name, name, name, start, n, start, n)
+	for line := start; line <= end; line++ {
+		fmt.Fprintf(&d.b, "-line %d\n", line)
+	}
+
+	for line := start; line <= end; line++ {
+		fmt.Fprintf(&d.b, "+line %d changed\n", line)
+	}
+
+	return d
+}
+
+// DeleteFile adds the deletion of the named file, whose content was the
+// given content.
+func (d *DiffBuilder) DeleteFile(name, content string) *DiffBuilder {
+	lines := splitLines(content)
+	fmt.Fprintf(&d.b, "diff --git a/%s b/%s\ndeleted file mode 100644\n", name, name)
+	if len(lines) == 0 {
+		return d
+	}
+
+	fmt.Fprintf(&d.b, "--- a/%s\n+++ /dev/null\n@@ -1,%d +0,0 @@\n", name, len(lines))
+	for _, line := range lines {
+		d.b.WriteString("-" + line + "\n")
+	}
+
+	return d
+}
+
+// String returns the diff.
+func (d *DiffBuilder) String() string {
+	return d.b.String()
+}
+
+// splitLines returns the lines of the given content, without a trailing
+// empty line.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// Lint lints the given diff against the given tree, such as an
+// fstest.MapFS, with the default templates.
+func Lint(tree fs.FS, diff string) (*difflint.LintResult, error) {
+	extMap, err := difflint.NewExtMap("")
+	if err != nil {
+		return nil, err
+	}
+
+	return difflint.Lint(context.Background(), difflint.LintOptions{
+		Reader:          strings.NewReader(diff),
+		FS:              tree,
+		Templates:       extMap.Templates,
+		FileExtMap:      extMap.FileExtMap,
+		DefaultTemplate: 0,
+	})
+}
+
+// AssertUnsatisfied lints the given diff against the given tree and fails
+// the test unless the unsatisfied rules are exactly those with the given
+// IDs, in any order. Rules without IDs are named by "file:line" of their
+// first directive.
+func AssertUnsatisfied(t testing.TB, tree fs.FS, diff string, wantRuleIDs []string) {
+	t.Helper()
+	result, err := Lint(tree, diff)
+	if err != nil {
+		t.Fatalf("difflint: %v", err)
+	}
+
+	got := make([]string, 0, len(result.UnsatisfiedRules))
+	for _, rule := range result.UnsatisfiedRules {
+		if rule.Rule.ID != nil {
+			got = append(got, *rule.Rule.ID)
+		} else {
+			got = append(got, fmt.Sprintf("%s:%d", rule.Rule.Hunk.File, rule.Rule.Hunk.Range.Start))
+		}
+	}
+
+	want := append([]string(nil), wantRuleIDs...)
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unsatisfied rules = %q, want %q", got, want)
+	}
+}
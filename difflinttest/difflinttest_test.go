@@ -0,0 +1,102 @@
+package difflinttest_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ethanthatonekid/difflint"
+	"github.com/ethanthatonekid/difflint/difflinttest"
+)
+
+func TestDiffBuilder(t *testing.T) {
+	diff := difflinttest.NewDiffBuilder().
+		AddFile("new.go", "package p\nvar X = 1\n").
+		ModifyLines("mod.go", 3, 5).
+		DeleteFile("old.go", "package p\n").
+		String()
+
+	hunks, err := difflint.ParseHunks(strings.NewReader(diff), nil, nil, nil, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, 0, len(hunks))
+	for _, hunk := range hunks {
+		got = append(got, fmt.Sprintf("%s %d-%d added=%t deleted=%t", hunk.File, hunk.Range.Start, hunk.Range.End, hunk.Added, hunk.Deleted))
+	}
+
+	want := []string{
+		"new.go 1-2 added=true deleted=false",
+		"mod.go 3-5 added=false deleted=false",
+		"old.go 1-1 added=false deleted=true",
+	}
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ParseHunks() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// tree holds a rule with an ID and one without, both targeting api.md.
+var tree = fstest.MapFS{
+	"api.go": {Data: []byte("package api\n//LINT.IF api.md\nvar V = 1\n//LINT.END bump\n\n//LINT.IF api.md\nvar W = 1\n//LINT.END\n")},
+	"api.md": {Data: []byte("# API\nv1\n")},
+}
+
+func TestAssertUnsatisfied(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want []string
+	}{
+		{
+			name: "target changed alone",
+			diff: difflinttest.NewDiffBuilder().ModifyLines("api.md", 2, 2).String(),
+			want: []string{"bump", "api.go:6"},
+		},
+		{
+			name: "one block changed along",
+			diff: difflinttest.NewDiffBuilder().ModifyLines("api.md", 2, 2).ModifyLines("api.go", 3, 3).String(),
+			want: []string{"api.go:6"},
+		},
+		{
+			name: "unrelated change",
+			diff: difflinttest.NewDiffBuilder().AddFile("other.go", "package other\n").String(),
+			want: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			difflinttest.AssertUnsatisfied(t, tree, test.diff, test.want)
+		})
+	}
+}
+
+// recorder records the failures of a test instead of failing it.
+type recorder struct {
+	testing.TB
+
+	// failures are the messages of the failures.
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+func TestAssertUnsatisfiedFails(t *testing.T) {
+	r := &recorder{TB: t}
+	diff := difflinttest.NewDiffBuilder().ModifyLines("api.md", 2, 2).String()
+	difflinttest.AssertUnsatisfied(r, tree, diff, []string{"bump"})
+	if len(r.failures) != 1 || !strings.Contains(r.failures[0], "api.go:6") {
+		t.Errorf("AssertUnsatisfied() failures = %q, want the unexpected rule", r.failures)
+	}
+}
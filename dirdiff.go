@@ -0,0 +1,317 @@
+package difflint
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// dirDiffContext is the number of context lines around each hunk of a
+// directory diff.
+const dirDiffContext = 3
+
+// binarySniffSize is the number of leading bytes checked for a NUL byte to
+// decide whether a file is binary.
+const binarySniffSize = 8000
+
+// DiffDirs returns a unified diff from the files in oldDir to the files in
+// newDir, as if they were two revisions of a repository. Files present on one
+// side only are added or deleted. A changed binary file is reported as a
+// single changed line.
+func DiffDirs(oldDir, newDir string) (io.Reader, error) {
+	oldFiles, err := listFiles(oldDir)
+	if err != nil {
+		return nil, err
+	}
+
+	newFiles, err := listFiles(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(oldFiles)+len(newFiles))
+	for name := range oldFiles {
+		names = append(names, name)
+	}
+
+	for name := range newFiles {
+		if _, ok := oldFiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	for _, name := range names {
+		_, inOld := oldFiles[name]
+		_, inNew := newFiles[name]
+
+		var oldContent, newContent []byte
+		if inOld {
+			if oldContent, err = os.ReadFile(filepath.Join(oldDir, filepath.FromSlash(name))); err != nil {
+				return nil, errors.Wrapf(err, "failed to read %s", name)
+			}
+		}
+
+		if inNew {
+			if newContent, err = os.ReadFile(filepath.Join(newDir, filepath.FromSlash(name))); err != nil {
+				return nil, errors.Wrapf(err, "failed to read %s", name)
+			}
+		}
+
+		if inOld && inNew && bytes.Equal(oldContent, newContent) {
+			continue
+		}
+
+		writeFileDiff(&b, name, inOld, inNew, oldContent, newContent)
+	}
+
+	return &b, nil
+}
+
+// listFiles returns the set of slash-separated paths of the regular files
+// in dir, skipping .git directories.
+func listFiles(dir string) (map[string]struct{}, error) {
+	files := make(map[string]struct{})
+	err := filepath.WalkDir(dir, func(pathname string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, pathname)
+		if err != nil {
+			return err
+		}
+
+		files[filepath.ToSlash(rel)] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to walk %s", dir)
+	}
+
+	return files, nil
+}
+
+// writeFileDiff writes the unified diff of a single file to b.
+func writeFileDiff(b *bytes.Buffer, name string, inOld, inNew bool, oldContent, newContent []byte) {
+	oldName, newName := "a/"+name, "b/"+name
+	fmt.Fprintf(b, "diff --git %s %s\n", oldName, newName)
+	switch {
+	case !inOld:
+		b.WriteString("new file mode 100644\n")
+		oldName = "/dev/null"
+	case !inNew:
+		b.WriteString("deleted file mode 100644\n")
+		newName = "/dev/null"
+	}
+
+	fmt.Fprintf(b, "--- %s\n+++ %s\n", oldName, newName)
+
+	var oldLines, newLines []string
+	if isBinary(oldContent) || isBinary(newContent) {
+		if inOld {
+			oldLines = []string{"Binary file " + name}
+		}
+
+		if inNew {
+			newLines = []string{"Binary file " + name}
+		}
+
+		// Make both sides differ so that the change is not lost.
+		if inOld && inNew {
+			newLines[0] += " (changed)"
+		}
+	} else {
+		oldLines = splitLines(oldContent)
+		newLines = splitLines(newContent)
+	}
+
+	writeHunks(b, diffLines(oldLines, newLines))
+}
+
+// isBinary returns true if the given content looks binary.
+func isBinary(content []byte) bool {
+	if len(content) > binarySniffSize {
+		content = content[:binarySniffSize]
+	}
+
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// splitLines splits the given content into lines without line terminators.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+// lineEdit is a line of a line diff.
+type lineEdit struct {
+	// op is ' ' for an unchanged line, '-' for a deleted line, and '+' for
+	// an inserted line.
+	op byte
+
+	// line is the content of the line.
+	line string
+
+	// oldPos and newPos are the numbers of old and new lines before the edit.
+	oldPos, newPos int
+}
+
+// diffLines returns the shortest edit script from a to b using Myers'
+// algorithm.
+func diffLines(a, b []string) []lineEdit {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Backtrack through the trace to recover the edits in reverse.
+	var edits []lineEdit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, lineEdit{op: ' ', line: a[x], oldPos: x, newPos: y})
+		}
+
+		if d == 0 {
+			break
+		}
+
+		if x == prevX {
+			y--
+			edits = append(edits, lineEdit{op: '+', line: b[y], oldPos: x, newPos: y})
+		} else {
+			x--
+			edits = append(edits, lineEdit{op: '-', line: a[x], oldPos: x, newPos: y})
+		}
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+
+	return edits
+}
+
+// writeHunks writes the given edits to b as unified diff hunks with
+// dirDiffContext lines of context.
+func writeHunks(b *bytes.Buffer, edits []lineEdit) {
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+
+		// Extend the hunk until a run of unchanged lines is long enough to
+		// separate it from the next change.
+		start := i - dirDiffContext
+		if start < 0 {
+			start = 0
+		}
+
+		end := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].op != ' ' {
+				end = j + 1
+			} else if j-end >= 2*dirDiffContext {
+				break
+			}
+		}
+
+		i = end
+		end += dirDiffContext
+		if end > len(edits) {
+			end = len(edits)
+		}
+
+		var oldLen, newLen int
+		for _, edit := range edits[start:end] {
+			if edit.op != '+' {
+				oldLen++
+			}
+
+			if edit.op != '-' {
+				newLen++
+			}
+		}
+
+		oldStart, newStart := edits[start].oldPos, edits[start].newPos
+		if oldLen > 0 {
+			oldStart++
+		}
+
+		if newLen > 0 {
+			newStart++
+		}
+
+		fmt.Fprintf(b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLen, newStart, newLen)
+		for _, edit := range edits[start:end] {
+			b.WriteByte(edit.op)
+			b.WriteString(edit.line)
+			b.WriteByte('\n')
+		}
+	}
+}
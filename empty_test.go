@@ -0,0 +1,56 @@
+package difflint
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLintEmptyRules(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":      "package a\n//LINT.IF target.go\n//LINT.END a tags=docs\n",
+		"b.go":      "package b\n//LINT.IF target.go\n\n\t\n//LINT.END b tags=security\n",
+		"gen/c.go":  "package c\n//LINT.IF ../target.go\n//LINT.END c\n",
+		"d.go":      "package d\n//LINT.IF target.go\nvar W = 1\n//LINT.END d\n",
+		"target.go": "package target\n",
+	})
+
+	tests := []struct {
+		name    string
+		options LintOptions
+		want    []string
+	}{
+		{name: "no lines", want: []string{"a.go", "gen/c.go"}},
+		{name: "blank lines", options: LintOptions{BlankBlocksEmpty: true}, want: []string{"a.go", "b.go", "gen/c.go"}},
+		{name: "only tags", options: LintOptions{BlankBlocksEmpty: true, OnlyTags: []string{"security"}}, want: []string{"b.go"}},
+		{name: "skip tags", options: LintOptions{SkipTags: []string{"docs"}}, want: []string{"gen/c.go"}},
+		{name: "include", options: LintOptions{Include: []string{"*.go"}}, want: []string{"a.go"}},
+		{name: "exclude", options: LintOptions{Exclude: []string{"gen/*"}}, want: []string{"a.go"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := test.options
+			o.Root = root
+			o.Reader = strings.NewReader("diff --git a/target.go b/target.go\n--- a/target.go\n+++ b/target.go\n@@ -1,1 +1,1 @@\n-package t\n+package target\n")
+			o.Templates = DefaultTemplates
+			o.FileExtMap = DefaultFileExtMap
+			result, err := Lint(context.Background(), o)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, rule := range result.EmptyRules {
+				got = append(got, rule.Hunk.File)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Lint() empty rules of %q, want %q", got, test.want)
+			}
+		})
+	}
+}
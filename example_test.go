@@ -0,0 +1,49 @@
+package difflint_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing/fstest"
+
+	"github.com/ethanthatonekid/difflint"
+)
+
+// ExampleDoWith lints a diff with the custom target resolver of the README,
+// which maps "proto:User" to the generated gen/user.pb.go. The generated
+// file changed without the User struct that must change with it.
+func ExampleDoWith() {
+	proto := func(ruleFile string, target difflint.Target) ([]string, bool, error) {
+		if !strings.HasPrefix(target.Raw, "proto:") {
+			return nil, false, nil
+		}
+
+		service := strings.TrimPrefix(target.Raw, "proto:")
+		return []string{"gen/" + strings.ToLower(service) + ".pb.go"}, true, nil
+	}
+
+	fsys := fstest.MapFS{
+		"user.go":        {Data: []byte("package api\n//LINT.IF proto:User\ntype User struct{ Name string }\n//LINT.END\n")},
+		"gen/user.pb.go": {Data: []byte("package gen\n\ntype User struct{ Name, Email string }\n")},
+	}
+
+	diff := "diff --git a/gen/user.pb.go b/gen/user.pb.go\n--- a/gen/user.pb.go\n+++ b/gen/user.pb.go\n@@ -3,1 +3,1 @@\n-type User struct{ Name string }\n+type User struct{ Name, Email string }\n"
+	result, err := difflint.DoWith(context.Background(), difflint.DoOptions{
+		LintOptions: difflint.LintOptions{
+			Reader:          strings.NewReader(diff),
+			FS:              fsys,
+			TargetResolvers: []difflint.TargetResolver{proto},
+		},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for _, rule := range result.UnsatisfiedRules {
+		for i := range rule.UnsatisfiedTargets {
+			fmt.Println(rule.Hunk.File, "needs", rule.Targets[i].Raw)
+		}
+	}
+	// Output: user.go needs proto:User
+}
@@ -0,0 +1,55 @@
+package difflint
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLintExpiredRulesReported(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":      "package a\n//LINT.IF target.go\n//LINT.EXPIRES 2020-01-01\nvar X = 1\n//LINT.END a tags=docs\n",
+		"b.go":      "package b\n//LINT.IF target.go\n//LINT.EXPIRES 2020-01-01\nvar Y = 1\n//LINT.END b tags=security\n",
+		"gen/c.go":  "package c\n//LINT.IF ../target.go\n//LINT.EXPIRES 2020-01-01\nvar Z = 1\n//LINT.END c\n",
+		"d.go":      "package d\n//LINT.IF target.go\n//LINT.EXPIRES 2999-01-01\nvar W = 1\n//LINT.END d\n",
+		"target.go": "package target\n",
+	})
+
+	tests := []struct {
+		name    string
+		options LintOptions
+		want    []string
+	}{
+		{name: "all", want: []string{"a.go", "b.go", "gen/c.go"}},
+		{name: "only tags", options: LintOptions{OnlyTags: []string{"security"}}, want: []string{"b.go"}},
+		{name: "skip tags", options: LintOptions{SkipTags: []string{"docs"}}, want: []string{"b.go", "gen/c.go"}},
+		{name: "include", options: LintOptions{Include: []string{"*.go"}}, want: []string{"a.go", "b.go"}},
+		{name: "exclude", options: LintOptions{Exclude: []string{"gen/*"}}, want: []string{"a.go", "b.go"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := test.options
+			o.Root = root
+			o.Reader = strings.NewReader("diff --git a/target.go b/target.go\n--- a/target.go\n+++ b/target.go\n@@ -1,1 +1,1 @@\n-package t\n+package target\n")
+			o.Templates = DefaultTemplates
+			o.FileExtMap = DefaultFileExtMap
+			result, err := Lint(context.Background(), o)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, rule := range result.ExpiredRules {
+				got = append(got, rule.Hunk.File)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Lint() expired rules of %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package difflint
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Explanation describes how a rule was evaluated, for debugging rules that
+// fire or do not fire unexpectedly.
+type Explanation struct {
+	// Rule is the explained rule.
+	Rule Rule
+
+	// BlockHunks are the hunks that intersect the rule's own block.
+	BlockHunks []Hunk
+
+	// Targets are the explanations of the rule's targets, in order.
+	Targets []TargetExplanation
+
+	// Skipped is true if the rule is in the skip set.
+	Skipped bool
+
+	// Expired is true if the rule has expired.
+	Expired bool
+}
+
+// TargetExplanation describes how a target of a rule was resolved.
+type TargetExplanation struct {
+	// Key is the resolved target key.
+	Key string
+
+	// Present is true if the key is among the present targets.
+	Present bool
+
+	// Sources are the hunks that made the key present.
+	Sources []Hunk
+}
+
+// Explain returns the explanation of the rule with the given "file:id" key
+// in the given rules map.
+func Explain(rulesMap *RulesMap, key string, skip map[string]struct{}) (*Explanation, error) {
+	key = filepath.ToSlash(key)
+	for file, rules := range rulesMap.Rules {
+		for _, rule := range rules {
+			if rule.ID == nil {
+				continue
+			}
+
+			ruleKey := TargetKey(file, Target{ID: rule.ID})
+			if ruleKey != key {
+				continue
+			}
+
+			explanation := &Explanation{
+				Rule:       rule,
+				BlockHunks: rulesMap.TargetSources[ruleKey],
+				Skipped:    IsSkipped(rule, skip),
+				Expired:    rule.Expired(time.Now()),
+			}
+
+			for _, target := range rule.Targets {
+				targetKey := TargetKey(file, target)
+				_, present := rulesMap.PresentTargets[targetKey]
+				explanation.Targets = append(explanation.Targets, TargetExplanation{
+					Key:     targetKey,
+					Present: present,
+					Sources: rulesMap.TargetSources[targetKey],
+				})
+			}
+
+			return explanation, nil
+		}
+	}
+
+	return nil, errors.Errorf("no rule with key %q found", key)
+}
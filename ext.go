@@ -2,10 +2,17 @@ package difflint
 
 import (
 	"encoding/json"
-	"log"
 	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
 )
 
+// DefaultDirectiveWord is the word that introduces the directives of the
+// default templates, as in "//LINT.IF".
+const DefaultDirectiveWord = "LINT"
+
 var (
 	// DefaultTemplates is the default list of directive templates.
 	DefaultTemplates = []string{
@@ -14,6 +21,8 @@ var (
 		"/*LINT.?",
 		"<!--LINT.?",
 		"'LINT.?",
+		"/*LINT.? */",
+		"--LINT.?",
 	}
 
 	// DefaultFileExtMap is the default map of file extensions to directive templates.
@@ -21,29 +30,56 @@ var (
 		"py":       {0},
 		"sh":       {0},
 		"go":       {1},
-		"js":       {1, 2},
-		"jsx":      {1, 2},
-		"mjs":      {1, 2},
-		"ts":       {1, 2},
-		"tsx":      {1, 2},
-		"jsonc":    {1, 2},
-		"c":        {1, 2},
-		"cc":       {1, 2},
-		"cpp":      {1, 2},
-		"h":        {1, 2},
-		"hpp":      {1, 2},
+		"js":       {1, 2, 5},
+		"jsx":      {1, 2, 5},
+		"mjs":      {1, 2, 5},
+		"ts":       {1, 2, 5},
+		"tsx":      {1, 2, 5},
+		"jsonc":    {1, 2, 5},
+		"c":        {1, 2, 5},
+		"cc":       {1, 2, 5},
+		"cpp":      {1, 2, 5},
+		"h":        {1, 2, 5},
+		"hpp":      {1, 2, 5},
 		"java":     {1},
 		"rs":       {1},
 		"swift":    {1},
-		"svelte":   {1, 2, 3},
-		"css":      {2},
+		"svelte":   {1, 2, 3, 5},
+		"css":      {2, 5},
 		"html":     {3},
 		"md":       {3},
 		"markdown": {3},
 		"bas":      {4},
+		"kt":       {1},
+		"kts":      {1},
+		"tf":       {0, 1},
+		"hcl":      {0, 1},
+		"ex":       {0},
+		"exs":      {0},
+		"rb":       {0},
+		"yaml":     {0},
+		"yml":      {0},
+		"scala":    {1},
+		"dart":     {1},
+		"php":      {1},
+		"tfvars":   {0},
+		"lua":      {6},
+		"sql":      {6},
+		"hs":       {6},
+		"vue":      {1, 2, 3, 5},
+		"ps1":      {0},
+		"toml":     {0},
 	}
 )
 
+// DefaultTargetMacros is the default map of target macros to the targets
+// they expand to by file extension. The empty extension applies to files
+// whose extension has no entry of its own.
+var DefaultTargetMacros = map[string]map[string]string{
+	"@tests": {"go": "./*_test.go"},
+	"@dir":   {"": "./*"},
+}
+
 // ExtFileJSON is a JSON representation of a file extension to directive template map.
 type ExtFileJSON map[string][]string
 
@@ -56,11 +92,44 @@ type ExtMap struct {
 	FileExtMap map[string][]int
 }
 
+// DefaultTemplatesFor returns the default templates with the given
+// directive word in place of DefaultDirectiveWord, e.g. "//DIFF.?".
+func DefaultTemplatesFor(word string) []string {
+	templates := make([]string, len(DefaultTemplates))
+	for i, template := range DefaultTemplates {
+		templates[i] = strings.Replace(template, DefaultDirectiveWord+".", word+".", 1)
+	}
+
+	return templates
+}
+
+// ParseDirectiveWord validates the given directive word, which must be
+// non-empty and free of whitespace, dots, and "?".
+func ParseDirectiveWord(word string) (string, error) {
+	if word == "" || strings.ContainsAny(word, " \t.?") {
+		return "", errors.Errorf("invalid directive word %q", word)
+	}
+
+	return word, nil
+}
+
 // NewExtMap returns a new ExtMap instance.
-func NewExtMap(path string) *ExtMap {
+func NewExtMap(path string) (*ExtMap, error) {
+	return NewExtMapWithWord(path, DefaultDirectiveWord)
+}
+
+// NewExtMapWithWord returns a new ExtMap instance whose default templates
+// use the given directive word. Templates listed in the JSON file at path are
+// added as they are, so that several words can be configured at once.
+func NewExtMapWithWord(path, word string) (*ExtMap, error) {
+	word, err := ParseDirectiveWord(word)
+	if err != nil {
+		return nil, err
+	}
+
 	o := &ExtMap{
-		Templates:  DefaultTemplates,
-		FileExtMap: DefaultFileExtMap,
+		Templates:  DefaultTemplatesFor(word),
+		FileExtMap: copyFileExtMap(DefaultFileExtMap),
 	}
 
 	// If a path is provided, update the templates and file extension map.
@@ -69,11 +138,11 @@ func NewExtMap(path string) *ExtMap {
 		var extFile ExtFileJSON
 		bytes, err := os.ReadFile(path)
 		if err != nil {
-			log.Fatalf("error reading JSON file %q: %v", path, err)
+			return nil, errors.Wrapf(err, "failed to read JSON file %q", path)
 		}
 
 		if err := json.Unmarshal(bytes, &extFile); err != nil {
-			log.Fatalf("error unmarshaling JSON file %q: %v", path, err)
+			return nil, errors.Wrapf(err, "failed to unmarshal JSON file %q", path)
 		}
 
 		// Update the templates and file extension map.
@@ -82,9 +151,68 @@ func NewExtMap(path string) *ExtMap {
 				o.With(ext, tpl)
 			}
 		}
+
+		if err := o.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid JSON file %q", path)
+		}
 	}
 
-	return o
+	return o, nil
+}
+
+// copyFileExtMap returns a deep copy of the given file extension map, so that
+// adding templates to the copy leaves the original, e.g. DefaultFileExtMap,
+// unchanged.
+func copyFileExtMap(m map[string][]int) map[string][]int {
+	c := make(map[string][]int, len(m))
+	for ext, indices := range m {
+		c[ext] = append([]int(nil), indices...)
+	}
+
+	return c
+}
+
+// Validate returns an error if a template is invalid or if two templates of
+// a file extension can match the same line with different arguments: their
+// prefixes and suffixes nest and they are of the same length, so that
+// neither wins as the longest match.
+func (o *ExtMap) Validate() error {
+	matchers, err := compileTemplates(o.Templates)
+	if err != nil {
+		return err
+	}
+
+	exts := make([]string, 0, len(o.FileExtMap))
+	for ext := range o.FileExtMap {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+
+	for _, ext := range exts {
+		indices := o.FileExtMap[ext]
+		for i := range indices {
+			for j := i + 1; j < len(indices); j++ {
+				a, b := matchers[indices[i]], matchers[indices[j]]
+				if ambiguousTemplates(a, b) {
+					return errors.Errorf("templates %q and %q of extension %q match the same lines with different arguments", a.template, b.template, ext)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// ambiguousTemplates returns true if the given templates are distinct but
+// can match the same line and are of the same length.
+func ambiguousTemplates(a, b templateMatcher) bool {
+	if a.template == b.template || a.length() != b.length() {
+		return false
+	}
+
+	prefixesNest := strings.HasPrefix(a.prefix, b.prefix) || strings.HasPrefix(b.prefix, a.prefix)
+	suffixesNest := strings.HasSuffix(a.suffix, b.suffix) || strings.HasSuffix(b.suffix, a.suffix)
+	return prefixesNest && suffixesNest
 }
 
 // With adds a directive template for a file extension.
@@ -0,0 +1,195 @@
+package difflint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultFileExtMapLexes(t *testing.T) {
+	tests := []struct {
+		ext      string
+		template int
+		content  string
+	}{
+		{ext: "py", template: 0, content: "#LINT.IF target.md\nx = 1\n#LINT.END\n"},
+		{ext: "sh", template: 0, content: "#LINT.IF target.md\nx=1\n#LINT.END\n"},
+		{ext: "go", template: 1, content: "//LINT.IF target.md\nvar X = 1\n//LINT.END\n"},
+		{ext: "js", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "js", template: 2, content: "/*LINT.IF target.md\nx = 1;\n/*LINT.END\n"},
+		{ext: "js", template: 5, content: "/*LINT.IF target.md */\nx = 1;\n/*LINT.END */\n"},
+		{ext: "jsx", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "jsx", template: 2, content: "/*LINT.IF target.md\nx = 1;\n/*LINT.END\n"},
+		{ext: "jsx", template: 5, content: "/*LINT.IF target.md */\nx = 1;\n/*LINT.END */\n"},
+		{ext: "mjs", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "mjs", template: 2, content: "/*LINT.IF target.md\nx = 1;\n/*LINT.END\n"},
+		{ext: "mjs", template: 5, content: "/*LINT.IF target.md */\nx = 1;\n/*LINT.END */\n"},
+		{ext: "ts", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "ts", template: 2, content: "/*LINT.IF target.md\nx = 1;\n/*LINT.END\n"},
+		{ext: "ts", template: 5, content: "/*LINT.IF target.md */\nx = 1;\n/*LINT.END */\n"},
+		{ext: "tsx", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "tsx", template: 2, content: "/*LINT.IF target.md\nx = 1;\n/*LINT.END\n"},
+		{ext: "tsx", template: 5, content: "/*LINT.IF target.md */\nx = 1;\n/*LINT.END */\n"},
+		{ext: "jsonc", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "jsonc", template: 2, content: "/*LINT.IF target.md\nx = 1;\n/*LINT.END\n"},
+		{ext: "jsonc", template: 5, content: "/*LINT.IF target.md */\nx = 1;\n/*LINT.END */\n"},
+		{ext: "c", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "c", template: 2, content: "/*LINT.IF target.md\nx = 1;\n/*LINT.END\n"},
+		{ext: "c", template: 5, content: "/*LINT.IF target.md */\nx = 1;\n/*LINT.END */\n"},
+		{ext: "cc", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "cc", template: 2, content: "/*LINT.IF target.md\nx = 1;\n/*LINT.END\n"},
+		{ext: "cc", template: 5, content: "/*LINT.IF target.md */\nx = 1;\n/*LINT.END */\n"},
+		{ext: "cpp", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "cpp", template: 2, content: "/*LINT.IF target.md\nx = 1;\n/*LINT.END\n"},
+		{ext: "cpp", template: 5, content: "/*LINT.IF target.md */\nx = 1;\n/*LINT.END */\n"},
+		{ext: "h", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "h", template: 2, content: "/*LINT.IF target.md\nx = 1;\n/*LINT.END\n"},
+		{ext: "h", template: 5, content: "/*LINT.IF target.md */\nx = 1;\n/*LINT.END */\n"},
+		{ext: "hpp", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "hpp", template: 2, content: "/*LINT.IF target.md\nx = 1;\n/*LINT.END\n"},
+		{ext: "hpp", template: 5, content: "/*LINT.IF target.md */\nx = 1;\n/*LINT.END */\n"},
+		{ext: "java", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "rs", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "swift", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "svelte", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "svelte", template: 2, content: "/*LINT.IF target.md\nx = 1;\n/*LINT.END\n"},
+		{ext: "svelte", template: 3, content: "<!--LINT.IF target.md\nx = 1;\n<!--LINT.END\n"},
+		{ext: "svelte", template: 5, content: "/*LINT.IF target.md */\nx = 1;\n/*LINT.END */\n"},
+		{ext: "css", template: 2, content: "/*LINT.IF target.md\na { color: red; }\n/*LINT.END\n"},
+		{ext: "css", template: 5, content: "/*LINT.IF target.md */\na { color: red; }\n/*LINT.END */\n"},
+		{ext: "html", template: 3, content: "<!--LINT.IF target.md\n<p>Text.</p>\n<!--LINT.END\n"},
+		{ext: "md", template: 3, content: "<!--LINT.IF target.md\nText.\n<!--LINT.END\n"},
+		{ext: "markdown", template: 3, content: "<!--LINT.IF target.md\nText.\n<!--LINT.END\n"},
+		{ext: "bas", template: 4, content: "'LINT.IF target.md\nDim X\n'LINT.END\n"},
+		{ext: "kt", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "kts", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "tf", template: 0, content: "#LINT.IF target.md\nx = 1\n#LINT.END\n"},
+		{ext: "tf", template: 1, content: "//LINT.IF target.md\nx = 1\n//LINT.END\n"},
+		{ext: "hcl", template: 0, content: "#LINT.IF target.md\nx = 1\n#LINT.END\n"},
+		{ext: "hcl", template: 1, content: "//LINT.IF target.md\nx = 1\n//LINT.END\n"},
+		{ext: "ex", template: 0, content: "#LINT.IF target.md\nx = 1\n#LINT.END\n"},
+		{ext: "exs", template: 0, content: "#LINT.IF target.md\nx = 1\n#LINT.END\n"},
+		{ext: "rb", template: 0, content: "#LINT.IF target.md\nx = 1\n#LINT.END\n"},
+		{ext: "yaml", template: 0, content: "#LINT.IF target.md\nx: 1\n#LINT.END\n"},
+		{ext: "yml", template: 0, content: "#LINT.IF target.md\nx: 1\n#LINT.END\n"},
+		{ext: "scala", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "dart", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "php", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "tfvars", template: 0, content: "#LINT.IF target.md\nx = 1\n#LINT.END\n"},
+		{ext: "lua", template: 6, content: "--LINT.IF target.md\nx = 1\n--LINT.END\n"},
+		{ext: "sql", template: 6, content: "--LINT.IF target.md\nSELECT 1;\n--LINT.END\n"},
+		{ext: "hs", template: 6, content: "--LINT.IF target.md\nx = 1\n--LINT.END\n"},
+		{ext: "vue", template: 1, content: "//LINT.IF target.md\nx = 1;\n//LINT.END\n"},
+		{ext: "vue", template: 2, content: "/*LINT.IF target.md\nx = 1;\n/*LINT.END\n"},
+		{ext: "vue", template: 3, content: "<!--LINT.IF target.md\nx = 1;\n<!--LINT.END\n"},
+		{ext: "vue", template: 5, content: "/*LINT.IF target.md */\nx = 1;\n/*LINT.END */\n"},
+		{ext: "ps1", template: 0, content: "#LINT.IF target.md\n$x = 1\n#LINT.END\n"},
+		{ext: "toml", template: 0, content: "#LINT.IF target.md\nx = 1\n#LINT.END\n"}}
+
+	tested := make(map[string]map[int]bool)
+	for _, test := range tests {
+		if tested[test.ext] == nil {
+			tested[test.ext] = make(map[int]bool)
+		}
+		tested[test.ext][test.template] = true
+
+		t.Run(test.ext+"/"+DefaultTemplates[test.template], func(t *testing.T) {
+			rules, err := ParseFileRules("fixture."+test.ext, strings.NewReader(test.content), LintOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(rules) != 1 || len(rules[0].Targets) != 1 || rules[0].Targets[0].File == nil || *rules[0].Targets[0].File != "target.md" || rules[0].Body != (Range{Start: 2, End: 2}) {
+				t.Errorf("ParseFileRules(%q) = %+v, want one rule of line 2 targeting target.md", test.content, rules)
+			}
+		})
+	}
+
+	for ext, templates := range DefaultFileExtMap {
+		for _, template := range templates {
+			if !tested[ext][template] {
+				t.Errorf("extension %q with template %q has no fixture", ext, DefaultTemplates[template])
+			}
+		}
+	}
+}
+
+func TestNewExtMapLeavesDefaultsUnchanged(t *testing.T) {
+	want := copyFileExtMap(DefaultFileExtMap)
+	wantTemplates := append([]string(nil), DefaultTemplates...)
+	path := filepath.Join(t.TempDir(), "ext.json")
+	if err := os.WriteFile(path, []byte(`{"go": ["//DIFF.?"], "vue": ["<!--LINT.?-->"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		extMap, err := NewExtMap(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := len(extMap.FileExtMap["go"]); got != len(want["go"])+1 {
+			t.Errorf("NewExtMap() go templates = %v, want one more than %v", extMap.FileExtMap["go"], want["go"])
+		}
+	}
+
+	if !reflect.DeepEqual(DefaultFileExtMap, want) {
+		t.Errorf("NewExtMap() changed DefaultFileExtMap to %v, want %v", DefaultFileExtMap, want)
+	}
+
+	if !reflect.DeepEqual(DefaultTemplates, wantTemplates) {
+		t.Errorf("NewExtMap() changed DefaultTemplates to %v, want %v", DefaultTemplates, wantTemplates)
+	}
+}
+
+func TestNewExtMapOverlappingTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		ext     string
+		wantErr string
+	}{
+		{
+			name: "longest first",
+			ext:  `{"go": ["//LINT.STRICT.?"]}`,
+		},
+		{
+			name:    "same length",
+			ext:     `{"go": ["//?LINT."]}`,
+			wantErr: `templates "//LINT.?" and "//?LINT." of extension "go" match the same lines with different arguments`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ext.json")
+			if err := os.WriteFile(path, []byte(test.ext), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			extMap, err := NewExtMap(path)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("NewExtMap() error = %v, want %q", err, test.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			content := "package a\n//LINT.STRICT.IF b.go\nvar X = 1\n//LINT.STRICT.END\n"
+			rules, err := ParseFileRules("a.go", strings.NewReader(content), LintOptions{Templates: extMap.Templates, FileExtMap: extMap.FileExtMap})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(rules) != 1 || len(rules[0].Targets) != 1 || *rules[0].Targets[0].File != "b.go" {
+				t.Errorf("ParseFileRules() = %+v, want one rule targeting b.go by the longer template", rules)
+			}
+		})
+	}
+}
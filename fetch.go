@@ -0,0 +1,61 @@
+package difflint
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FetchDiffOptions represents the options for fetching a diff over HTTP.
+type FetchDiffOptions struct {
+	// URL is the location of the diff.
+	URL string
+
+	// Headers is a list of "Name: Value" headers sent with the request.
+	Headers []string
+
+	// Timeout is the maximum duration of the request. Zero means no timeout.
+	Timeout time.Duration
+}
+
+// FetchDiff fetches the diff at the given URL and returns a reader over its
+// body. Redirects are followed and any non-200 response is an error. The
+// request is canceled along with ctx.
+func FetchDiff(ctx context.Context, o FetchDiffOptions) (io.Reader, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.URL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create request for %s", o.URL)
+	}
+
+	for _, header := range o.Headers {
+		name, value, found := strings.Cut(header, ":")
+		if !found {
+			return nil, errors.Errorf("invalid header %q, expected \"Name: Value\"", header)
+		}
+
+		req.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	client := &http.Client{Timeout: o.Timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch diff from %s", o.URL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to fetch diff from %s: unexpected status %s", o.URL, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read diff from %s", o.URL)
+	}
+
+	return bytes.NewReader(body), nil
+}
@@ -0,0 +1,120 @@
+package difflint
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFetchDiff(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/fetch.diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const token = "s3cret-token"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pr.diff", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "bad credentials", http.StatusUnauthorized)
+			return
+		}
+
+		w.Write(fixture)
+	})
+	mux.HandleFunc("/moved.diff", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/pr.diff", http.StatusFound)
+	})
+	started := make(chan struct{})
+	mux.HandleFunc("/slow.diff", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("200", func(t *testing.T) {
+		r, err := FetchDiff(context.Background(), FetchDiffOptions{
+			URL:     server.URL + "/pr.diff",
+			Headers: []string{"Authorization: Bearer " + token},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != string(fixture) {
+			t.Errorf("FetchDiff() = %q, want %q", got, fixture)
+		}
+
+		hunks, err := ParseHunks(strings.NewReader(string(got)), nil, nil, nil, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(hunks) != 1 || hunks[0].File != "a.go" {
+			t.Errorf("ParseHunks() = %+v, want one hunk of a.go", hunks)
+		}
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		r, err := FetchDiff(context.Background(), FetchDiffOptions{
+			URL:     server.URL + "/moved.diff",
+			Headers: []string{"Authorization: Bearer " + token},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, _ := io.ReadAll(r); string(got) != string(fixture) {
+			t.Errorf("FetchDiff() = %q, want %q", got, fixture)
+		}
+	})
+
+	t.Run("401", func(t *testing.T) {
+		_, err := FetchDiff(context.Background(), FetchDiffOptions{
+			URL:     server.URL + "/pr.diff",
+			Headers: []string{"Authorization: Bearer wrong-" + token},
+		})
+		if err == nil {
+			t.Fatal("FetchDiff() succeeded, want an error")
+		}
+
+		if !strings.Contains(err.Error(), "401") {
+			t.Errorf("FetchDiff() error = %q, want the status", err)
+		}
+
+		if strings.Contains(err.Error(), token) {
+			t.Errorf("FetchDiff() error = %q, which leaks the token", err)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-started
+			cancel()
+		}()
+
+		_, err := FetchDiff(ctx, FetchDiffOptions{URL: server.URL + "/slow.diff"})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("FetchDiff() error = %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("invalid header", func(t *testing.T) {
+		_, err := FetchDiff(context.Background(), FetchDiffOptions{URL: server.URL + "/pr.diff", Headers: []string{"Authorization"}})
+		if err == nil {
+			t.Fatal("FetchDiff() succeeded, want an error")
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package difflint
+
+import (
+	"bytes"
+	"io/fs"
+
+	"github.com/pkg/errors"
+)
+
+// HunksFromFiles returns a hunk spanning the whole of each of the given
+// files in fsys, as if every line changed, so that a list of changed files
+// can be linted without a diff. Files that do not exist are deleted and
+// their hunks have no range. Files that are not included by the include
+// and exclude patterns are dropped.
+func HunksFromFiles(fsys fs.FS, files, include, exclude []string) ([]Hunk, error) {
+	hunks := make([]Hunk, 0, len(files))
+	for _, file := range files {
+		file = NormalizeKey(file)
+		included, err := Include(file, include, exclude)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to check if file is included")
+		}
+
+		if !included {
+			continue
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if errors.Is(err, fs.ErrNotExist) {
+			hunks = append(hunks, Hunk{File: file, Deleted: true})
+			continue
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read file %s", file)
+		}
+
+		hunk := Hunk{File: file}
+		if lines := lineCount(content); lines > 0 {
+			hunk.Range = Range{Start: 1, End: lines}
+		}
+
+		hunks = append(hunks, hunk)
+	}
+
+	return hunks, nil
+}
+
+// lineCount returns the number of lines of the given content, counting a
+// last line without a trailing newline.
+func lineCount(content []byte) int {
+	n := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		n++
+	}
+
+	return n
+}
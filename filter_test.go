@@ -0,0 +1,84 @@
+package difflint
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// filterDiff changes the guarded block of a.go and the generated file that is
+// the block's target.
+const filterDiff = "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -3,1 +3,1 @@\n-var X = 1\n+var X = 2\n" +
+	"diff --git a/gen/b.go b/gen/b.go\n--- a/gen/b.go\n+++ b/gen/b.go\n@@ -2,1 +2,1 @@\n-var Y = 1\n+var Y = 2\n"
+
+func TestParseHunksFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{name: "none", want: []string{"a.go", "gen/b.go"}},
+		{name: "exclude", exclude: []string{"gen/*"}, want: []string{"a.go"}},
+		{name: "include", include: []string{"gen/*.go"}, want: []string{"gen/b.go"}},
+		{name: "include and exclude", include: []string{"*.go", "gen/*.go"}, exclude: []string{"gen/*"}, want: []string{"a.go"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hunks, err := ParseHunks(strings.NewReader(filterDiff), test.include, test.exclude, nil, 0, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, hunk := range hunks {
+				got = append(got, hunk.File)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseHunks() files = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLintFilterScopeExcludedTarget(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":     "package a\n//LINT.IF gen/b.go\nvar X = 1\n//LINT.END\n",
+		"gen/b.go": "package gen\nvar Y = 1\n",
+	})
+
+	tests := []struct {
+		name  string
+		scope FilterScope
+		want  int
+	}{
+		// The excluded change still satisfies the target.
+		{name: "rules", scope: FilterScopeRules, want: 0},
+		{name: "changes", scope: FilterScopeChanges, want: 1},
+		{name: "all", scope: FilterScopeAll, want: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Lint(context.Background(), LintOptions{
+				Root:           root,
+				Reader:         strings.NewReader(filterDiff),
+				Templates:      DefaultTemplates,
+				FileExtMap:     DefaultFileExtMap,
+				Exclude:        []string{"gen/*"},
+				FilterScope:    test.scope,
+				StrictPresence: true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result.UnsatisfiedRules) != test.want || len(result.SatisfiedRules) != 1-test.want {
+				t.Errorf("Lint() = %v unsatisfied and %v satisfied, want %d unsatisfied rules", result.UnsatisfiedRules, result.SatisfiedRules, test.want)
+			}
+		})
+	}
+}
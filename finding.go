@@ -0,0 +1,278 @@
+package difflint
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FindingKind is the kind of a finding.
+type FindingKind string
+
+const (
+	// FindingUnsatisfied is a rule whose targets changed without it.
+	FindingUnsatisfied FindingKind = "unsatisfied"
+
+	// FindingSatisfied is a rule that changed along with its targets.
+	FindingSatisfied FindingKind = "satisfied"
+
+	// FindingExpired is a rule that has passed its expiry date.
+	FindingExpired FindingKind = "expired"
+
+	// FindingEmpty is a rule whose block guards no lines.
+	FindingEmpty FindingKind = "empty"
+)
+
+// Finding is a format-independent description of a rule in a lint result,
+// shared by all output formats.
+type Finding struct {
+	// Kind is the kind of the finding.
+	Kind FindingKind `json:"kind"`
+
+	// RuleFile is the file of the rule.
+	RuleFile string `json:"rule_file"`
+
+	// RuleID is the ID of the rule, if any.
+	RuleID string `json:"rule_id,omitempty"`
+
+	// StartLine is the line of the rule's IF directive.
+	StartLine int `json:"start_line"`
+
+	// EndLine is the line of the rule's END directive.
+	EndLine int `json:"end_line"`
+
+	// Severity is the severity of the rule.
+	Severity Severity `json:"severity"`
+
+	// Message describes the finding.
+	Message string `json:"message"`
+
+	// Note is the rule's free text note, if any.
+	Note string `json:"note,omitempty"`
+
+	// Owner is the rule's owner, if any.
+	Owner string `json:"owner,omitempty"`
+
+	// Config is true if the rule was declared in a rules file, in which case
+	// RuleFile is a file name or glob pattern and there are no lines.
+	Config bool `json:"config,omitempty"`
+
+	// MissingTargets are the targets that changed without the rule.
+	MissingTargets []TargetRef `json:"missing_targets,omitempty"`
+
+	// SatisfiedTargets are the targets that changed along with the rule.
+	SatisfiedTargets []TargetRef `json:"satisfied_targets,omitempty"`
+}
+
+// TargetRef is a target of a finding's rule.
+type TargetRef struct {
+	// Key is the target key.
+	Key string `json:"key"`
+
+	// File is the file name, or glob pattern, of the target.
+	File string `json:"file"`
+
+	// Owners are the code owners of the target's file, if known.
+	Owners []string `json:"owners,omitempty"`
+
+	// Block is true if the target refers to a block by ID.
+	Block bool `json:"block,omitempty"`
+
+	// Macro is the target macro that expanded to the target, if any.
+	Macro string `json:"macro,omitempty"`
+
+	// Range is the line range of the target block, if it was found.
+	Range *Range `json:"range,omitempty"`
+
+	// Changes are the hunks that changed the target.
+	Changes []Hunk `json:"-"`
+
+	// SatisfiedBy is the first hunk that changed the target, if known.
+	SatisfiedBy *HunkRef `json:"satisfied_by,omitempty"`
+}
+
+// Hunk match kinds of a HunkRef.
+const (
+	// HunkMatchPath is a hunk anywhere in the target file.
+	HunkMatchPath = "path"
+
+	// HunkMatchID is a hunk that intersects the target block.
+	HunkMatchID = "id"
+
+	// HunkMatchRange is a hunk that intersects the target line range.
+	HunkMatchRange = "range"
+)
+
+// HunkRef locates the hunk that changed a target.
+type HunkRef struct {
+	// File is the file of the hunk.
+	File string `json:"file"`
+
+	// StartLine is the first line of the hunk.
+	StartLine int `json:"start_line"`
+
+	// EndLine is the last line of the hunk.
+	EndLine int `json:"end_line"`
+
+	// Match is how the hunk matched the target: HunkMatchPath, HunkMatchID,
+	// or HunkMatchRange.
+	Match string `json:"match"`
+}
+
+// Label returns the target key along with the macro that expanded to it,
+// e.g. "pkg/*_test.go (@tests)".
+func (t TargetRef) Label() string {
+	if t.Macro == "" {
+		return t.Key
+	}
+
+	return fmt.Sprintf("%s (%s)", t.Key, t.Macro)
+}
+
+// newHunkRef returns a reference to the first of the given hunks that
+// changed the given target, or nil if there are none.
+func newHunkRef(target Target, changes []Hunk) *HunkRef {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	ref := &HunkRef{
+		File:      changes[0].File,
+		StartLine: changes[0].Range.Start,
+		EndLine:   changes[0].Range.End,
+		Match:     HunkMatchPath,
+	}
+
+	switch {
+	case target.ID != nil:
+		ref.Match = HunkMatchID
+	case target.Range != nil:
+		ref.Match = HunkMatchRange
+	}
+
+	return ref
+}
+
+// BuildFindings returns the findings of the given lint result ordered by
+// rule file and line: unsatisfied, satisfied, expired, and empty rules.
+func BuildFindings(result *LintResult) []Finding {
+	findings := make([]Finding, 0, len(result.UnsatisfiedRules)+len(result.SatisfiedRules)+len(result.ExpiredRules)+len(result.EmptyRules))
+	for _, rule := range result.UnsatisfiedRules {
+		f := newFinding(FindingUnsatisfied, rule.Rule, fmt.Sprintf("%s: rule not satisfied", rule.Rule.Severity))
+		for i, target := range rule.Targets {
+			if _, ok := rule.UnsatisfiedTargets[i]; !ok {
+				continue
+			}
+
+			ref := TargetRef{
+				Key:     TargetKey(rule.Rule.Hunk.File, target),
+				File:    TargetKey(rule.Rule.Hunk.File, Target{File: target.File}),
+				Block:   target.ID != nil && !target.Wildcard(),
+				Macro:   target.Macro,
+				Changes: rule.TargetChanges[i],
+			}
+
+			if rng, ok := rule.TargetRanges[i]; ok {
+				ref.Range = &rng
+			}
+
+			ref.SatisfiedBy = newHunkRef(target, ref.Changes)
+			f.MissingTargets = append(f.MissingTargets, ref)
+		}
+
+		findings = append(findings, f)
+	}
+
+	for _, rule := range result.SatisfiedRules {
+		f := newFinding(FindingSatisfied, rule.Rule, "rule satisfied")
+		for i, target := range rule.Targets {
+			if _, ok := rule.ChangedTargets[i]; ok {
+				f.SatisfiedTargets = append(f.SatisfiedTargets, TargetRef{
+					Key:         TargetKey(rule.Hunk.File, target),
+					File:        TargetKey(rule.Hunk.File, Target{File: target.File}),
+					Block:       target.ID != nil && !target.Wildcard(),
+					Macro:       target.Macro,
+					SatisfiedBy: newHunkRef(target, rule.TargetChanges[i]),
+				})
+			}
+		}
+
+		findings = append(findings, f)
+	}
+
+	for _, rule := range result.ExpiredRules {
+		f := newFinding(FindingExpired, rule, fmt.Sprintf("rule expired on %s, please remove it", rule.Expires.Format("2006-01-02")))
+		f.Severity = SeverityWarn
+		findings = append(findings, f)
+	}
+
+	for _, rule := range result.EmptyRules {
+		message := "rule guards no lines, please remove it or restore its code"
+		if rule.Body.Start <= rule.Body.End {
+			message = "rule guards only blank lines, please remove it or restore its code"
+		}
+
+		f := newFinding(FindingEmpty, rule, message)
+		f.Severity = SeverityWarn
+		findings = append(findings, f)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Kind != findings[j].Kind {
+			return findingKindOrder(findings[i].Kind) < findingKindOrder(findings[j].Kind)
+		}
+
+		if findings[i].RuleFile != findings[j].RuleFile {
+			return findings[i].RuleFile < findings[j].RuleFile
+		}
+
+		return findings[i].StartLine < findings[j].StartLine
+	})
+
+	return findings
+}
+
+// Location returns the rule's location as "file:start-end", or as
+// "file (rules file)" for a rule declared in a rules file.
+func (f Finding) Location() string {
+	if f.Config {
+		return f.RuleFile + " (rules file)"
+	}
+
+	return fmt.Sprintf("%s:%d-%d", f.RuleFile, f.StartLine, f.EndLine)
+}
+
+// newFinding returns a finding of the given kind for the given rule.
+func newFinding(kind FindingKind, rule Rule, message string) Finding {
+	f := Finding{
+		Kind:      kind,
+		RuleFile:  rule.Hunk.File,
+		StartLine: rule.Hunk.Range.Start,
+		EndLine:   rule.Hunk.Range.End,
+		Severity:  rule.Severity,
+		Message:   message,
+		Note:      rule.Note,
+		Owner:     rule.Owner,
+		Config:    rule.Config,
+	}
+
+	if rule.ID != nil {
+		f.RuleID = *rule.ID
+	}
+
+	return f
+}
+
+// findingKindOrder returns the position of the given kind in the order of
+// findings.
+func findingKindOrder(kind FindingKind) int {
+	switch kind {
+	case FindingUnsatisfied:
+		return 0
+	case FindingSatisfied:
+		return 1
+	case FindingExpired:
+		return 2
+	default:
+		return 3
+	}
+}
@@ -0,0 +1,55 @@
+package difflint_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/ethanthatonekid/difflint/difflinttest"
+)
+
+func TestLintFS(t *testing.T) {
+	schema := "package db\n//LINT.IF docs/schema.md\nvar Tables = 1\n//LINT.END schema\n"
+	tree := fstest.MapFS{
+		"db/schema.go":   {Data: []byte(schema)},
+		"docs/schema.md": {Data: []byte("# Schema\ntables\n")},
+		"new/handler.go": {Data: []byte("package handler\n//LINT.IF ../docs/schema.md\nvar H = 1\n//LINT.END handler\n")},
+	}
+
+	tests := []struct {
+		name string
+		diff *difflinttest.DiffBuilder
+		want []string
+	}{
+		{
+			name: "target changed without the block",
+			diff: difflinttest.NewDiffBuilder().ModifyLines("docs/schema.md", 2, 2),
+			want: []string{"handler", "schema"},
+		},
+		{
+			name: "target changed with the block",
+			diff: difflinttest.NewDiffBuilder().ModifyLines("docs/schema.md", 2, 2).ModifyLines("db/schema.go", 3, 3),
+			want: []string{"handler"},
+		},
+		{
+			name: "target deleted",
+			diff: difflinttest.NewDiffBuilder().DeleteFile("docs/schema.md", "# Schema\ntables\n"),
+			want: []string{"handler", "schema"},
+		},
+		{
+			name: "rules of an added file are present",
+			diff: difflinttest.NewDiffBuilder().ModifyLines("docs/schema.md", 2, 2).AddFile("new/handler.go", "package handler\n//LINT.IF ../docs/schema.md\nvar H = 1\n//LINT.END handler\n"),
+			want: []string{"schema"},
+		},
+		{
+			name: "block outside the changed lines",
+			diff: difflinttest.NewDiffBuilder().ModifyLines("docs/schema.md", 1, 1).ModifyLines("db/schema.go", 1, 1),
+			want: []string{"handler", "schema"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			difflinttest.AssertUnsatisfied(t, tree, test.diff.String(), test.want)
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package difflint
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GitRoot returns the top-level directory of the git repository containing
+// the current directory.
+func GitRoot() (string, error) {
+	out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find git repository root")
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// GitDiff runs git diff with the given arguments in the given directory and
+// returns a reader over its output.
+func GitDiff(ctx context.Context, dir string, args ...string) (io.Reader, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"diff"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to run git diff %s", strings.Join(args, " "))
+	}
+
+	return bytes.NewReader(out), nil
+}
+
+// GitCommit is a commit listed by GitCommits.
+type GitCommit struct {
+	// SHA is the full hash of the commit.
+	SHA string
+
+	// Subject is the first line of the commit message.
+	Subject string
+
+	// Message is the full commit message, including its trailers.
+	Message string
+}
+
+// GitCommits returns the commits in the given revision range, such as
+// "v1.2.0..HEAD", oldest first.
+func GitCommits(ctx context.Context, dir, revisionRange string) ([]GitCommit, error) {
+	cmd := exec.CommandContext(ctx, "git", "log", "--reverse", "--format=%H%x00%s%x00%B%x1e", revisionRange, "--")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list commits in %s", revisionRange)
+	}
+
+	var commits []GitCommit
+	for _, record := range strings.Split(string(out), "\x1e") {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+
+		fields := strings.SplitN(record, "\x00", 3)
+		if len(fields) != 3 {
+			return nil, errors.Errorf("unexpected output of git log: %q", record)
+		}
+
+		commits = append(commits, GitCommit{SHA: fields[0], Subject: fields[1], Message: fields[2]})
+	}
+
+	return commits, nil
+}
+
+// GitCommitDiff returns a reader over the diff introduced by the given
+// commit against its first parent, or against the empty tree for a root
+// commit.
+func GitCommitDiff(ctx context.Context, dir, sha string) (io.Reader, error) {
+	cmd := exec.CommandContext(ctx, "git", "diff-tree", "-p", "--root", "--no-commit-id", "-r", sha)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to run git diff-tree %s", sha)
+	}
+
+	return bytes.NewReader(out), nil
+}
+
+// GitArchive extracts the tree of the given revision into the directory
+// dest, which must exist. Only directories and regular files are extracted.
+func GitArchive(ctx context.Context, dir, rev, dest string) error {
+	cmd := exec.CommandContext(ctx, "git", "archive", "--format=tar", rev)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return errors.Wrapf(err, "failed to run git archive %s", rev)
+	}
+
+	tr := tar.NewReader(bytes.NewReader(out))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "failed to read archive of %s", rev)
+		}
+
+		name := path.Clean(header.Name)
+		if !fs.ValidPath(name) {
+			return errors.Errorf("invalid path %q in archive of %s", header.Name, rev)
+		}
+
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+
+			b, err := io.ReadAll(tr)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read %s from archive of %s", name, rev)
+			}
+
+			if err := os.WriteFile(target, b, 0o644); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// GitHooksDir returns the directory in which git looks for hooks, honoring
+// core.hooksPath.
+func GitHooksDir() (string, error) {
+	out, err := exec.Command("git", "rev-parse", "--path-format=absolute", "--git-path", "hooks").Output()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find git hooks directory")
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,224 @@
+package difflint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultGitHubAPIURL is the base URL of the GitHub API.
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+const (
+	// gitHubDiffMediaType is the media type of the GitHub API that returns
+	// the diff of a pull request.
+	gitHubDiffMediaType = "application/vnd.github.v3.diff"
+
+	// gitHubJSONMediaType is the media type of the JSON responses of the
+	// GitHub API.
+	gitHubJSONMediaType = "application/vnd.github+json"
+
+	// gitHubFilesPerPage is the number of files requested per page of the
+	// files of a pull request.
+	gitHubFilesPerPage = 100
+
+	// gitHubMaxFilePages is the number of pages of files after which the
+	// GitHub API lists no more files of a pull request.
+	gitHubMaxFilePages = 30
+)
+
+// GitHubPullRequest identifies a pull request on GitHub.
+type GitHubPullRequest struct {
+	// Owner is the user or organization that owns the repository.
+	Owner string
+
+	// Repo is the name of the repository.
+	Repo string
+
+	// Number is the number of the pull request.
+	Number int
+}
+
+// ParseGitHubPullRequest parses a pull request given as "owner/repo#number".
+func ParseGitHubPullRequest(s string) (GitHubPullRequest, error) {
+	repo, number, found := strings.Cut(s, "#")
+	owner, name, ok := strings.Cut(repo, "/")
+	n, err := strconv.Atoi(number)
+	if !found || !ok || owner == "" || name == "" || strings.Contains(name, "/") || err != nil || n <= 0 {
+		return GitHubPullRequest{}, errors.Errorf("invalid pull request %q, expected owner/repo#number", s)
+	}
+
+	return GitHubPullRequest{Owner: owner, Repo: name, Number: n}, nil
+}
+
+// String returns the pull request as "owner/repo#number".
+func (pr GitHubPullRequest) String() string {
+	return fmt.Sprintf("%s/%s#%d", pr.Owner, pr.Repo, pr.Number)
+}
+
+// FetchGitHubDiffOptions represents the options for fetching the diff of a
+// GitHub pull request.
+type FetchGitHubDiffOptions struct {
+	// PullRequest is the pull request whose diff is fetched.
+	PullRequest GitHubPullRequest
+
+	// BaseURL is the base URL of the GitHub API. DefaultGitHubAPIURL is used
+	// if it is empty.
+	BaseURL string
+
+	// Token authenticates the requests, if set. It is never logged.
+	Token string
+
+	// Timeout is the maximum duration of each request. Zero means no
+	// timeout.
+	Timeout time.Duration
+
+	// Logger receives the files whose changes are left out, if set.
+	Logger Logger
+}
+
+// gitHubFile is a changed file of a pull request as listed by the GitHub
+// API.
+type gitHubFile struct {
+	Filename         string `json:"filename"`
+	PreviousFilename string `json:"previous_filename"`
+	Status           string `json:"status"`
+	Patch            string `json:"patch"`
+}
+
+// FetchGitHubDiff fetches the diff of the given pull request. The GitHub API
+// refuses to return the diff of very large pull requests, in which case the
+// diff is assembled from the patches of the pull request's files instead.
+func FetchGitHubDiff(ctx context.Context, o FetchGitHubDiffOptions) (io.Reader, error) {
+	if o.BaseURL == "" {
+		o.BaseURL = DefaultGitHubAPIURL
+	}
+
+	pullURL := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", strings.TrimSuffix(o.BaseURL, "/"), o.PullRequest.Owner, o.PullRequest.Repo, o.PullRequest.Number)
+	client := &http.Client{Timeout: o.Timeout}
+	body, status, err := gitHubGet(ctx, client, pullURL, gitHubDiffMediaType, o.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch status {
+	case http.StatusOK:
+		return bytes.NewReader(body), nil
+	case http.StatusNotAcceptable:
+		loggerOrNop(o.Logger).Printf("diff of %s is too large for the GitHub API, assembling it from its files", o.PullRequest)
+		return fetchGitHubFilesDiff(ctx, client, pullURL, o)
+	default:
+		return nil, gitHubStatusError(pullURL, status, body)
+	}
+}
+
+// fetchGitHubFilesDiff returns a git diff assembled from the patches of the
+// files of the pull request at the given URL. Files without a patch, such as
+// binary files, are diffed as binary files so that they still change.
+func fetchGitHubFilesDiff(ctx context.Context, client *http.Client, pullURL string, o FetchGitHubDiffOptions) (io.Reader, error) {
+	var b bytes.Buffer
+	for page := 1; page <= gitHubMaxFilePages; page++ {
+		filesURL := fmt.Sprintf("%s/files?per_page=%d&page=%d", pullURL, gitHubFilesPerPage, page)
+		body, status, err := gitHubGet(ctx, client, filesURL, gitHubJSONMediaType, o.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		if status != http.StatusOK {
+			return nil, errors.Wrapf(gitHubStatusError(filesURL, status, body), "the diff of %s is too large for the GitHub API and its files could not be listed", o.PullRequest)
+		}
+
+		var files []gitHubFile
+		if err := json.Unmarshal(body, &files); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse files of %s", o.PullRequest)
+		}
+
+		for _, file := range files {
+			writeGitHubFileDiff(&b, file)
+			if file.Patch == "" {
+				loggerOrNop(o.Logger).Printf("file %s of %s has no patch, treating it as a binary change", file.Filename, o.PullRequest)
+			}
+		}
+
+		if len(files) < gitHubFilesPerPage {
+			return &b, nil
+		}
+	}
+
+	return nil, errors.Errorf("the diff of %s is too large for the GitHub API, which lists at most %d of its files", o.PullRequest, gitHubFilesPerPage*gitHubMaxFilePages)
+}
+
+// writeGitHubFileDiff writes the git diff of the given file of a pull
+// request to b.
+func writeGitHubFileDiff(b *bytes.Buffer, file gitHubFile) {
+	oldName, newName := file.Filename, file.Filename
+	if file.PreviousFilename != "" {
+		oldName = file.PreviousFilename
+	}
+
+	fmt.Fprintf(b, "diff --git a/%s b/%s\n", oldName, newName)
+	oldPath, newPath := "a/"+oldName, "b/"+newName
+	switch file.Status {
+	case "added":
+		fmt.Fprintf(b, "new file mode 100644\n")
+		oldPath = "/dev/null"
+	case "removed":
+		fmt.Fprintf(b, "deleted file mode 100644\n")
+		newPath = "/dev/null"
+	}
+
+	if file.Patch == "" {
+		fmt.Fprintf(b, "Binary files %s and %s differ\n", oldPath, newPath)
+		return
+	}
+
+	fmt.Fprintf(b, "--- %s\n+++ %s\n%s", oldPath, newPath, file.Patch)
+	if !strings.HasSuffix(file.Patch, "\n") {
+		b.WriteString("\n")
+	}
+}
+
+// gitHubGet gets the given URL of the GitHub API with the given media type
+// and returns the body and status of the response.
+func gitHubGet(ctx context.Context, client *http.Client, url, mediaType, token string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to create request for %s", url)
+	}
+
+	req.Header.Set("Accept", mediaType)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to fetch %s", url)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed to read %s", url)
+	}
+
+	return body, resp.StatusCode, nil
+}
+
+// gitHubStatusError returns the error of an unexpected response of the
+// GitHub API, with the start of its body.
+func gitHubStatusError(url string, status int, body []byte) error {
+	if len(body) > 1024 {
+		body = body[:1024]
+	}
+
+	return errors.Errorf("failed to fetch %s: unexpected status %d %s: %s", url, status, http.StatusText(status), strings.TrimSpace(string(body)))
+}
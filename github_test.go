@@ -0,0 +1,164 @@
+package difflint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// gitHubFiles returns the files of a pull request too large for the diff
+// media type: gitHubFilesPerPage modified files, an added file, a renamed
+// file, and a binary file without a patch.
+func gitHubFiles() []gitHubFile {
+	var files []gitHubFile
+	for i := 0; i < gitHubFilesPerPage; i++ {
+		files = append(files, gitHubFile{
+			Filename: fmt.Sprintf("f%03d.go", i),
+			Status:   "modified",
+			Patch:    "@@ -1,1 +1,1 @@\n-var X = 1\n+var X = 2",
+		})
+	}
+
+	return append(files,
+		gitHubFile{Filename: "new.go", Status: "added", Patch: "@@ -0,0 +1,1 @@\n+var Y = 1\n"},
+		gitHubFile{Filename: "to.go", PreviousFilename: "from.go", Status: "renamed", Patch: "@@ -1,1 +1,1 @@\n-var Z = 1\n+var Z = 2\n"},
+		gitHubFile{Filename: "logo.png", Status: "modified"},
+	)
+}
+
+func TestFetchGitHubDiff(t *testing.T) {
+	const token = "s3cret-token"
+	const diff = "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,1 +1,1 @@\n-var X = 1\n+var X = 2\n"
+	files := gitHubFiles()
+
+	var pages []int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/pulls/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/repos/o/r/pulls/1":
+			if r.Header.Get("Accept") != gitHubDiffMediaType {
+				t.Errorf("Accept = %q, want %q", r.Header.Get("Accept"), gitHubDiffMediaType)
+			}
+
+			io.WriteString(w, diff)
+		case "/repos/o/r/pulls/2":
+			http.Error(w, `{"message":"diff too large"}`, http.StatusNotAcceptable)
+		case "/repos/o/r/pulls/2/files":
+			if r.Header.Get("Accept") != gitHubJSONMediaType || r.URL.Query().Get("per_page") != strconv.Itoa(gitHubFilesPerPage) {
+				t.Errorf("GET %s with Accept %q, want per_page=%d and %q", r.URL, r.Header.Get("Accept"), gitHubFilesPerPage, gitHubJSONMediaType)
+			}
+
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			pages = append(pages, page)
+			start := (page - 1) * gitHubFilesPerPage
+			end := start + gitHubFilesPerPage
+			if start > len(files) {
+				start = len(files)
+			}
+
+			if end > len(files) {
+				end = len(files)
+			}
+
+			json.NewEncoder(w).Encode(files[start:end])
+		default:
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fetch := func(number int, token string) (string, error) {
+		r, err := FetchGitHubDiff(context.Background(), FetchGitHubDiffOptions{
+			PullRequest: GitHubPullRequest{Owner: "o", Repo: "r", Number: number},
+			BaseURL:     server.URL + "/",
+			Token:       token,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		b, err := io.ReadAll(r)
+		return string(b), err
+	}
+
+	t.Run("diff", func(t *testing.T) {
+		got, err := fetch(1, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != diff {
+			t.Errorf("FetchGitHubDiff() = %q, want %q", got, diff)
+		}
+	})
+
+	t.Run("paginated files", func(t *testing.T) {
+		pages = nil
+		got, err := fetch(2, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(pages) != 2 || pages[0] != 1 || pages[1] != 2 {
+			t.Errorf("FetchGitHubDiff() fetched pages %v, want [1 2]", pages)
+		}
+
+		hunks, err := ParseHunks(strings.NewReader(got), nil, nil, nil, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		changed := make(map[string]bool)
+		for _, hunk := range hunks {
+			changed[hunk.File] = true
+		}
+
+		for _, file := range []string{"f000.go", "f099.go", "new.go", "to.go"} {
+			if !changed[file] {
+				t.Errorf("ParseHunks() of FetchGitHubDiff() has no hunk of %s", file)
+			}
+		}
+
+		if !strings.Contains(got, "diff --git a/from.go b/to.go\n") || !strings.Contains(got, "Binary files a/logo.png and b/logo.png differ\n") {
+			t.Errorf("FetchGitHubDiff() = %q, want the rename and the binary file", got)
+		}
+	})
+
+	t.Run("auth failure", func(t *testing.T) {
+		_, err := fetch(1, "wrong-"+token)
+		if err == nil {
+			t.Fatal("FetchGitHubDiff() succeeded, want an error")
+		}
+
+		if !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "Bad credentials") {
+			t.Errorf("FetchGitHubDiff() error = %q, want the status and message", err)
+		}
+
+		if strings.Contains(err.Error(), token) {
+			t.Errorf("FetchGitHubDiff() error = %q, which leaks the token", err)
+		}
+	})
+
+	t.Run("missing pull request", func(t *testing.T) {
+		_, err := fetch(3, token)
+		if err == nil {
+			t.Fatal("FetchGitHubDiff() succeeded, want an error")
+		}
+
+		if !strings.Contains(err.Error(), "/repos/o/r/pulls/3") || !strings.Contains(err.Error(), "404") {
+			t.Errorf("FetchGitHubDiff() error = %q, want the URL and status", err)
+		}
+	})
+}
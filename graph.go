@@ -0,0 +1,184 @@
+package difflint
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// GraphNode is a file or a block with an ID in the rule dependency graph.
+type GraphNode struct {
+	// Key is the target key of the node, e.g. "file.go" or "file.go:id".
+	Key string `json:"key"`
+
+	// File is the file name, or glob pattern, of the node.
+	File string `json:"file"`
+
+	// ID is the ID of the block, if the node is a block.
+	ID string `json:"id,omitempty"`
+}
+
+// GraphEdge is a target of a rule: the rule's block depends on the target.
+type GraphEdge struct {
+	// From is the key of the rule's block.
+	From string `json:"from"`
+
+	// To is the key of the target.
+	To string `json:"to"`
+
+	// Rule is the location of the rule as "file:start-end".
+	Rule string `json:"rule"`
+}
+
+// Graph is the dependency graph of the rules of a tree.
+type Graph struct {
+	// Nodes are the nodes of the graph, ordered by key.
+	Nodes []GraphNode
+
+	// Edges are the edges of the graph, ordered by source, target, and
+	// rule.
+	Edges []GraphEdge
+}
+
+// BuildGraph returns the dependency graph of the given rules by file.
+func BuildGraph(rulesMap map[string][]Rule) *Graph {
+	nodes := make(map[string]GraphNode)
+	addNode := func(file string, id *string) string {
+		node := GraphNode{Key: TargetKey(file, Target{ID: id}), File: NormalizeKey(file)}
+		if id != nil {
+			node.ID = *id
+		}
+
+		nodes[node.Key] = node
+		return node.Key
+	}
+
+	var g Graph
+	for file, rules := range rulesMap {
+		for _, rule := range rules {
+			from := addNode(file, rule.ID)
+			location := fmt.Sprintf("%s:%d-%d", rule.Hunk.File, rule.Hunk.Range.Start, rule.Hunk.Range.End)
+			for _, target := range rule.Targets {
+				to := TargetKey(file, target)
+				targetFile := TargetKey(file, Target{File: target.File})
+				if target.ID == nil {
+					nodes[to] = GraphNode{Key: to, File: targetFile}
+				} else {
+					addNode(targetFile, target.ID)
+				}
+
+				g.Edges = append(g.Edges, GraphEdge{From: from, To: to, Rule: location})
+			}
+		}
+	}
+
+	for _, node := range nodes {
+		g.Nodes = append(g.Nodes, node)
+	}
+
+	g.sort()
+	return &g
+}
+
+// sort orders the nodes and edges of the graph.
+func (g *Graph) sort() {
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].Key < g.Nodes[j].Key
+	})
+
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+
+		if a.To != b.To {
+			return a.To < b.To
+		}
+
+		return a.Rule < b.Rule
+	})
+}
+
+// Focus returns the subgraph of the nodes reachable from the nodes of the
+// given file, following edges from rules to their targets.
+func (g *Graph) Focus(file string) *Graph {
+	file = NormalizeKey(file)
+	reachable := make(map[string]struct{})
+	var queue []string
+	for _, node := range g.Nodes {
+		if node.File == file {
+			reachable[node.Key] = struct{}{}
+			queue = append(queue, node.Key)
+		}
+	}
+
+	adjacency := g.Adjacency()
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for _, to := range adjacency[key] {
+			if _, ok := reachable[to]; ok {
+				continue
+			}
+
+			reachable[to] = struct{}{}
+			queue = append(queue, to)
+		}
+	}
+
+	var focused Graph
+	for _, node := range g.Nodes {
+		if _, ok := reachable[node.Key]; ok {
+			focused.Nodes = append(focused.Nodes, node)
+		}
+	}
+
+	for _, edge := range g.Edges {
+		if _, ok := reachable[edge.From]; ok {
+			focused.Edges = append(focused.Edges, edge)
+		}
+	}
+
+	return &focused
+}
+
+// Adjacency returns the keys of the targets of each node that has any, in
+// order and without duplicates.
+func (g *Graph) Adjacency() map[string][]string {
+	adjacency := make(map[string][]string)
+	for _, edge := range g.Edges {
+		targets := adjacency[edge.From]
+		if n := len(targets); n > 0 && targets[n-1] == edge.To {
+			continue
+		}
+
+		adjacency[edge.From] = append(targets, edge.To)
+	}
+
+	return adjacency
+}
+
+// WriteDOT writes the graph to w in the Graphviz DOT language. Nodes are
+// labeled with their file and ID, and edges with the rule's location.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph difflint {\n")
+	for _, node := range g.Nodes {
+		label := node.File
+		if node.ID != "" {
+			label += "\n" + node.ID
+		}
+
+		fmt.Fprintf(&b, "  %q [label=%q];\n", node.Key, label)
+	}
+
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", edge.From, edge.To, edge.Rule)
+	}
+
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,93 @@
+package difflint
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// hiddenTree holds rules targeting docs/ci.md in a CI workflow, in a
+// hidden directory that is not allowed by default, and in .git.
+var hiddenTree = map[string]string{
+	".github/workflows/ci.yml": "#LINT.IF docs/ci.md\njobs: {}\n#LINT.END workflow\n",
+	".config/tool.yml":         "#LINT.IF docs/ci.md\ntool: {}\n#LINT.END tool\n",
+	".git/hooks.yml":           "#LINT.IF docs/ci.md\nhooks: {}\n#LINT.END git\n",
+	"docs/ci.md":               "# CI\n",
+}
+
+// ciDiff changes docs/ci.md and nothing else.
+const ciDiff = "diff --git a/docs/ci.md b/docs/ci.md\n--- a/docs/ci.md\n+++ b/docs/ci.md\n@@ -1,1 +1,1 @@\n-# Old CI\n+# CI\n"
+
+func TestLintHidden(t *testing.T) {
+	root := writeTree(t, hiddenTree)
+	tests := []struct {
+		name        string
+		allowHidden []string
+		include     []string
+		want        []string
+	}{
+		{name: "default", want: []string{"workflow"}},
+		{name: "allowed", allowHidden: []string{".config/*.yml"}, want: []string{"tool"}},
+		{name: "included", include: []string{".config/*", "docs/*"}, want: []string{"tool", "workflow"}},
+		{name: "everything", allowHidden: []string{"**"}, want: []string{"tool", "workflow"}},
+		{name: "nothing", allowHidden: []string{}, want: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Lint(context.Background(), LintOptions{
+				Root:        root,
+				Reader:      strings.NewReader(ciDiff),
+				Templates:   DefaultTemplates,
+				FileExtMap:  DefaultFileExtMap,
+				AllowHidden: test.allowHidden,
+				Include:     test.include,
+				FilterScope: FilterScopeChanges,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, rule := range result.UnsatisfiedRules {
+				got = append(got, *rule.ID)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Lint() unsatisfied rules = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSkipHidden(t *testing.T) {
+	tests := []struct {
+		pathname string
+		dir      bool
+		allow    []string
+		want     bool
+	}{
+		{pathname: ".", dir: true, want: false},
+		{pathname: "src/a.go", want: false},
+		{pathname: ".venv", dir: true, want: true},
+		{pathname: "src/.cache/a.go", want: true},
+		{pathname: ".github", dir: true, allow: []string{".github/**"}, want: false},
+		{pathname: ".github/workflows", dir: true, allow: []string{".github/**"}, want: false},
+		{pathname: ".github/workflows/ci.yml", allow: []string{".github/**"}, want: false},
+		{pathname: ".config", dir: true, allow: []string{".config/*.yml"}, want: false},
+		{pathname: ".config/nested", dir: true, allow: []string{".config/*.yml"}, want: true},
+		{pathname: ".config/a.json", allow: []string{".config/*.yml"}, want: true},
+		{pathname: ".env", allow: []string{".env"}, want: false},
+		{pathname: ".git", dir: true, allow: []string{"**"}, want: true},
+		{pathname: "sub/.git/config", allow: []string{"**"}, want: true},
+	}
+
+	for _, test := range tests {
+		if got := skipHidden(test.pathname, test.dir, test.allow); got != test.want {
+			t.Errorf("skipHidden(%q, %t, %q) = %t, want %t", test.pathname, test.dir, test.allow, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package difflint
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// parseHunksFixture parses the diff of the given fixture.
+func parseHunksFixture(t *testing.T, name string, stripPrefixes []string) []Hunk {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	hunks, err := ParseHunks(f, nil, nil, stripPrefixes, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hunks
+}
+
+// hunkFiles returns the file of each hunk.
+func hunkFiles(hunks []Hunk) []string {
+	var files []string
+	for _, hunk := range hunks {
+		files = append(files, hunk.File)
+	}
+
+	return files
+}
+
+func TestParseHunksPrefixes(t *testing.T) {
+	tests := []struct {
+		name          string
+		fixture       string
+		stripPrefixes []string
+		want          []string
+	}{
+		{
+			name:    "no prefix",
+			fixture: "testdata/no-prefix.diff",
+			want:    []string{"a.go", "café.go", "dir with space/b.py"},
+		},
+		{
+			name:    "custom prefix left alone",
+			fixture: "testdata/custom-prefix.diff",
+			want:    []string{"new/a.go"},
+		},
+		{
+			name:          "custom prefix stripped",
+			fixture:       "testdata/custom-prefix.diff",
+			stripPrefixes: []string{"dst/", "new/"},
+			want:          []string{"a.go"},
+		},
+		{
+			name:    "quoted unicode and spaces",
+			fixture: "testdata/quoted.diff",
+			want:    []string{"café.go", "dir with space/b.py"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := hunkFiles(parseHunksFixture(t, test.fixture, test.stripPrefixes)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseHunks(%s) files = %q, want %q", test.fixture, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseHunksDeletionAndSubmodule(t *testing.T) {
+	f, err := os.Open("testdata/deletion-submodule.diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var logger recordingLogger
+	hunks, err := ParseHunks(f, nil, nil, nil, 0, &logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Hunk{{
+		File:         "old.go",
+		Range:        Range{Start: 1, End: 3},
+		Deleted:      true,
+		RemovedLines: []string{"package old", "", "var X = 1"},
+	}}
+	if !reflect.DeepEqual(hunks, want) {
+		t.Errorf("ParseHunks() = %+v, want only the deletion of old.go %+v", hunks, want)
+	}
+
+	if !reflect.DeepEqual(logger.messages, []string{"skipping submodule lib"}) {
+		t.Errorf("ParseHunks() logged %q, want the skipped submodule", logger.messages)
+	}
+}
+
+func TestLintMetadataChanges(t *testing.T) {
+	hunks := parseHunksFixture(t, "testdata/metadata.diff", nil)
+	want := []Hunk{
+		{File: "run.sh", MetadataOnly: true},
+		{File: "logo.png", MetadataOnly: true},
+	}
+	if !reflect.DeepEqual(hunks, want) {
+		t.Errorf("ParseHunks(metadata.diff) = %+v, want %+v", hunks, want)
+	}
+
+	root := writeTree(t, map[string]string{
+		"a.go":     "package a\n\n//LINT.IF run.sh logo.png\nvar X = 1\n//LINT.END\n",
+		"run.sh":   "#!/bin/sh\necho hi\n",
+		"logo.png": "\x00\x02",
+	})
+
+	for _, contentOnly := range []bool{false, true} {
+		f, err := os.Open("testdata/metadata.diff")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		result, err := Lint(context.Background(), LintOptions{
+			Root:        root,
+			Reader:      f,
+			Templates:   DefaultTemplates,
+			FileExtMap:  DefaultFileExtMap,
+			ContentOnly: contentOnly,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got int
+		for _, rule := range result.UnsatisfiedRules {
+			got += len(rule.UnsatisfiedTargets)
+		}
+
+		if want := map[bool]int{false: 2, true: 0}[contentOnly]; got != want {
+			t.Errorf("Lint() with ContentOnly %t = %d changed targets, want %d", contentOnly, got, want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package difflint
+
+import (
+	"sort"
+	"time"
+)
+
+// RuleRef refers to the rule at Rules[File][Index] of a RuleIndex.
+type RuleRef struct {
+	// File is the file of the rule.
+	File string
+
+	// Index is the index of the rule among the rules of the file.
+	Index int
+}
+
+// IndexedTarget refers to a target of a rule.
+type IndexedTarget struct {
+	RuleRef
+
+	// Target is the index of the target among the targets of the rule.
+	Target int
+}
+
+// RuleIndex indexes rules by the keys of their targets so that the rules
+// affected by a set of changed keys are found without visiting every rule.
+type RuleIndex struct {
+	// Rules is the map of rules by file name.
+	Rules map[string][]Rule
+
+	// ByTarget maps each target key to the targets that resolve to it.
+	ByTarget map[string][]IndexedTarget
+}
+
+// NewRuleIndex builds the index of the given map of rules.
+func NewRuleIndex(rulesMap map[string][]Rule) *RuleIndex {
+	index := &RuleIndex{
+		Rules:    rulesMap,
+		ByTarget: make(map[string][]IndexedTarget),
+	}
+
+	for file, rules := range rulesMap {
+		for i, rule := range rules {
+			for j, target := range rule.Targets {
+				key := TargetKey(rule.Hunk.File, target)
+				index.ByTarget[key] = append(index.ByTarget[key], IndexedTarget{
+					RuleRef: RuleRef{File: file, Index: i},
+					Target:  j,
+				})
+			}
+		}
+	}
+
+	return index
+}
+
+// EvaluatePresent returns the present rules with targets that are not
+// present, for strict presence: a rule whose block changed requires all of
+// its targets to change. Rules in the skip set and expired rules are not
+// checked.
+func (idx *RuleIndex) EvaluatePresent(targetsMap map[string]struct{}, skip map[string]struct{}) UnsatisfiedRules {
+	now := time.Now()
+	var unsatisfiedRules UnsatisfiedRules
+	for _, file := range sortedKeys(idx.Rules) {
+		for _, rule := range idx.Rules[file] {
+			if !rule.Present || rule.Expired(now) || IsSkipped(rule, skip) {
+				continue
+			}
+
+			missing := make(map[int]struct{})
+			for i, target := range rule.Targets {
+				if _, ok := targetsMap[TargetKey(rule.Hunk.File, target)]; !ok {
+					missing[i] = struct{}{}
+				}
+			}
+
+			if len(missing) == 0 {
+				continue
+			}
+
+			unsatisfiedRules = append(unsatisfiedRules, UnsatisfiedRule{
+				Rule:               rule,
+				UnsatisfiedTargets: missing,
+				TargetRanges:       targetRanges(rule, missing, idx.Rules),
+			})
+		}
+	}
+
+	return unsatisfiedRules
+}
+
+// sortedKeys returns the file names of the given rules map in order.
+func sortedKeys(rulesMap map[string][]Rule) []string {
+	files := make([]string, 0, len(rulesMap))
+	for file := range rulesMap {
+		files = append(files, file)
+	}
+
+	sort.Strings(files)
+	return files
+}
+
+// Evaluate returns the list of unsatisfied rules given the set of present
+// target keys, ordered by file and position. Rules in the skip set and
+// expired rules are not checked.
+func (idx *RuleIndex) Evaluate(targetsMap map[string]struct{}, skip map[string]struct{}) (UnsatisfiedRules, error) {
+	now := time.Now()
+	unsatisfiedTargets := make(map[RuleRef]map[int]struct{})
+	for key := range targetsMap {
+		for _, ref := range idx.ByTarget[key] {
+			rule := idx.Rules[ref.File][ref.Index]
+			if rule.Present || rule.Expired(now) || IsSkipped(rule, skip) {
+				continue
+			}
+
+			if unsatisfiedTargets[ref.RuleRef] == nil {
+				unsatisfiedTargets[ref.RuleRef] = make(map[int]struct{}, len(rule.Targets))
+			}
+
+			unsatisfiedTargets[ref.RuleRef][ref.Target] = struct{}{}
+		}
+	}
+
+	refs := make([]RuleRef, 0, len(unsatisfiedTargets))
+	for ref := range unsatisfiedTargets {
+		refs = append(refs, ref)
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].File != refs[j].File {
+			return refs[i].File < refs[j].File
+		}
+
+		return refs[i].Index < refs[j].Index
+	})
+
+	unsatisfiedRules := make(UnsatisfiedRules, 0, len(refs))
+	for _, ref := range refs {
+		rule := idx.Rules[ref.File][ref.Index]
+		unsatisfiedRules = append(unsatisfiedRules, UnsatisfiedRule{
+			Rule:               rule,
+			UnsatisfiedTargets: unsatisfiedTargets[ref],
+			TargetRanges:       targetRanges(rule, unsatisfiedTargets[ref], idx.Rules),
+		})
+	}
+
+	return unsatisfiedRules, nil
+}
@@ -0,0 +1,182 @@
+package difflint
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultIndexFile is the default name of the rule index file, relative to
+// the root.
+const DefaultIndexFile = ".difflint.index.json"
+
+// IndexFile is a committed snapshot of every rule of a tree, so that linting
+// only has to parse the files that changed.
+type IndexFile struct {
+	// Rules are the indexed rules by file name.
+	Rules map[string][]IndexFileRule `json:"rules"`
+
+	// ModTime is the modification time of the file from which the index was
+	// read, if any. Changed files with directives that are newer than the
+	// index are reported as possibly stale.
+	ModTime time.Time `json:"-"`
+}
+
+// IndexFileRule is the serialized form of a rule in an index file.
+type IndexFileRule struct {
+	// Range is the range of lines of the rule's block.
+	Range Range `json:"range"`
+
+	// Body is the range of lines guarded by the rule.
+	Body Range `json:"body"`
+
+	// Blank is true if the rule's body has only blank lines.
+	Blank bool `json:"blank,omitempty"`
+
+	// ID is the ID of the rule, if any.
+	ID *string `json:"id,omitempty"`
+
+	// Targets are the targets of the rule.
+	Targets []IndexFileTarget `json:"targets,omitempty"`
+
+	// Severity is the severity of the rule.
+	Severity Severity `json:"severity"`
+
+	// AllowSelfTarget is true if the rule may target itself.
+	AllowSelfTarget bool `json:"allow_self,omitempty"`
+
+	// Tags are the tags of the rule.
+	Tags []string `json:"tags,omitempty"`
+
+	// Expires is the expiry of the rule, if any.
+	Expires *time.Time `json:"expires,omitempty"`
+
+	// Note is the note of the rule, if any.
+	Note string `json:"note,omitempty"`
+
+	// Owner is the owner of the rule, if any.
+	Owner string `json:"owner,omitempty"`
+}
+
+// IndexFileTarget is the serialized form of a target in an index file.
+type IndexFileTarget struct {
+	// File is the target file name, if any.
+	File *string `json:"file,omitempty"`
+
+	// ID is the target ID, if any.
+	ID *string `json:"id,omitempty"`
+
+	// Range is the target line range, if any.
+	Range *Range `json:"range,omitempty"`
+
+	// Macro is the macro that expanded to the target, if any.
+	Macro string `json:"macro,omitempty"`
+
+	// Raw is the target as written in the directive.
+	Raw string `json:"raw,omitempty"`
+}
+
+// NewIndexFile returns the index of the given rules by file. Rules declared
+// in a rules file are not indexed.
+func NewIndexFile(rulesMap map[string][]Rule) *IndexFile {
+	index := &IndexFile{Rules: make(map[string][]IndexFileRule, len(rulesMap))}
+	for file, rules := range rulesMap {
+		for _, rule := range rules {
+			if rule.Config {
+				continue
+			}
+
+			indexed := IndexFileRule{
+				Range:           rule.Hunk.Range,
+				Body:            rule.Body,
+				Blank:           rule.Blank,
+				ID:              rule.ID,
+				Severity:        rule.Severity,
+				AllowSelfTarget: rule.AllowSelfTarget,
+				Tags:            rule.Tags,
+				Expires:         rule.Expires,
+				Note:            rule.Note,
+				Owner:           rule.Owner,
+			}
+
+			for _, target := range rule.Targets {
+				indexed.Targets = append(indexed.Targets, IndexFileTarget(target))
+			}
+
+			index.Rules[file] = append(index.Rules[file], indexed)
+		}
+	}
+
+	return index
+}
+
+// ReadIndexFile reads the index file at the given path.
+func ReadIndexFile(path string) (*IndexFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open index file %q", path)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to stat index file %q", path)
+	}
+
+	var index IndexFile
+	if err := json.NewDecoder(f).Decode(&index); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode index file %q", path)
+	}
+
+	index.ModTime = info.ModTime()
+	return &index, nil
+}
+
+// Write writes the index to w as JSON.
+func (index *IndexFile) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(index)
+}
+
+// files returns the indexed file names in order.
+func (index *IndexFile) files() []string {
+	files := make([]string, 0, len(index.Rules))
+	for file := range index.Rules {
+		files = append(files, file)
+	}
+
+	sort.Strings(files)
+	return files
+}
+
+// rules returns the indexed rules of the given file.
+func (index *IndexFile) rules(file string) []Rule {
+	rules := make([]Rule, 0, len(index.Rules[file]))
+	for _, indexed := range index.Rules[file] {
+		rule := Rule{
+			Hunk:            Hunk{File: file, Range: indexed.Range},
+			Body:            indexed.Body,
+			Blank:           indexed.Blank,
+			ID:              indexed.ID,
+			Severity:        indexed.Severity,
+			AllowSelfTarget: indexed.AllowSelfTarget,
+			Tags:            indexed.Tags,
+			Expires:         indexed.Expires,
+			Note:            indexed.Note,
+			Owner:           indexed.Owner,
+		}
+
+		for _, target := range indexed.Targets {
+			rule.Targets = append(rule.Targets, Target(target))
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
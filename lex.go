@@ -2,8 +2,13 @@ package difflint
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -11,164 +16,865 @@ import (
 type token struct {
 	directive directive
 	args      []string // ["IF", "test.go:ID"] or ["END", "id"]
+	note      string   // Free text after "--", "#", or "//", if any.
 
 	line int // Line number of the token.
+
+	lastNonBlank int // Line number of the last non-blank line before the token.
+
+	symbol *Range // Range of the declaration named by a FUNC directive, if found.
 }
 
 type directive string
 
 const (
-	directiveIf  directive = "IF"
-	directiveEnd directive = "END"
+	directiveIf      directive = "IF"
+	directiveEnd     directive = "END"
+	directiveExpires directive = "EXPIRES"
+	directiveThen    directive = "THEN"
+	directiveLine    directive = "LINE"
+	directiveFunc    directive = "FUNC"
+
+	// directiveEOF is not written in files; the lexer appends it to report
+	// the number of lines in the file.
+	directiveEOF directive = "EOF"
 )
 
+// directives is the list of known directives.
+var directives = []directive{directiveIf, directiveEnd, directiveExpires, directiveThen, directiveLine, directiveFunc}
+
+// expiresLayouts are the accepted layouts of the EXPIRES directive's date.
+var expiresLayouts = []string{time.RFC3339, "2006-01-02"}
+
+// utf8BOM is the byte order mark with which some editors start UTF-8 files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// maxTypoDistance is the maximum edit distance at which an unknown directive
+// is considered a typo of a known directive.
+const maxTypoDistance = 1
+
+// Warning represents a non-fatal problem found while linting.
+type Warning struct {
+	// File in which the problem was found.
+	File string
+
+	// Line number at which the problem was found.
+	Line int
+
+	// Message describes the problem.
+	Message string
+}
+
+// String returns a string representation of the warning, prefixed by its
+// location if it has one.
+func (w Warning) String() string {
+	switch {
+	case w.File == "":
+		return w.Message
+	case w.Line == 0:
+		return fmt.Sprintf("%s: %s", w.File, w.Message)
+	default:
+		return fmt.Sprintf("%s:%d: %s", w.File, w.Line, w.Message)
+	}
+}
+
 type lexOptions struct {
 	// file is specifier that is being linted.
 	file string
 
 	// templates is the list of directive templates.
 	templates []string
+
+	// strictDirectives makes unknown directives and unresolved symbols an
+	// error instead of a warning.
+	strictDirectives bool
+
+	// warnMismatchedTemplates warns about directive-like lines that do not
+	// match any of the templates.
+	warnMismatchedTemplates bool
+
+	// word is the directive word by which directive-like lines are
+	// recognized, e.g. "LINT".
+	word string
+
+	// literals, if set, follows the string literals of the file so that
+	// lines inside them are not lexed.
+	literals literalScanner
 }
 
-// lex lexes the given reader and returns the list of tokens.
-func lex(r io.Reader, options lexOptions) ([]token, error) {
+// lex lexes the given reader and returns the list of tokens along with any
+// warnings about unknown or mismatched directives and unresolved symbols. If
+// any directive is found, the tokens end with an EOF token on the last line of
+// the file.
+func lex(r io.Reader, options lexOptions) ([]token, []Warning, error) {
+	matchers, err := compileTemplates(options.templates)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "at %s", options.file)
+	}
+
+	// Skip a UTF-8 byte order mark, which would otherwise precede a
+	// directive on the first line, and skip UTF-16 files altogether.
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(len(utf8BOM))
+	if warning, ok := utf16Warning(options.file, head); ok {
+		return nil, []Warning{warning}, nil
+	}
+
+	if bytes.HasPrefix(head, utf8BOM) {
+		if _, err := br.Discard(len(utf8BOM)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// content is the content read so far, from which the symbols of FUNC
+	// directives are resolved.
+	var content bytes.Buffer
+	r = io.TeeReader(br, &content)
+
 	// tokens is the list of tokens that are found in the file.
 	var tokens []token
 
+	// warnings is the list of warnings that are found in the file.
+	var warnings []Warning
+
 	// lineCount is the current line number.
 	var lineCount int
 
+	// lastNonBlank is the number of the last non-blank line.
+	var lastNonBlank int
+
 	// Read the file line by line.
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
-		line := scanner.Text()
+		// The scanner drops the CR of CRLF line endings; trim any other
+		// trailing CR too, so that it does not defeat template suffixes.
+		line := strings.TrimSuffix(scanner.Text(), "\r")
 		lineCount++
+		previousNonBlank := lastNonBlank
+		if strings.TrimSpace(line) != "" {
+			lastNonBlank = lineCount
+		}
+
+		if options.literals != nil && options.literals.scan(line) {
+			continue
+		}
 
 		// Check if the line is a directive.
-		token, found, err := parseToken(line, lineCount, options.templates)
+		token, found, err := parseToken(line, lineCount, matchers)
 		if err != nil {
-			return nil, err
+			return nil, nil, errors.Wrapf(err, "at %s:%d", options.file, lineCount)
 		}
 
 		if !found {
+			if options.warnMismatchedTemplates && strings.Contains(line, options.word+".") {
+				warnings = append(warnings, Warning{
+					File:    options.file,
+					Line:    lineCount,
+					Message: fmt.Sprintf("directive-like line does not match any template (tried %q)", options.templates),
+				})
+			}
+
 			continue
 		}
 
+		// Unknown directives are warnings unless they are strict. Likely
+		// typos name the directive they were probably meant to be.
+		if _, err := parseDirective(string(token.directive)); err != nil {
+			if options.strictDirectives {
+				return nil, nil, errors.Wrapf(err, "at %s:%d", options.file, token.line)
+			}
+
+			message := fmt.Sprintf("ignoring unknown directive %q", token.directive)
+			if typo, ok := closestDirective(token.directive); ok {
+				message += fmt.Sprintf(" (did you mean %q?)", typo)
+			}
+
+			warnings = append(warnings, Warning{
+				File:    options.file,
+				Line:    token.line,
+				Message: message,
+			})
+			continue
+		}
+
+		token.lastNonBlank = previousNonBlank
 		tokens = append(tokens, *token)
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	// Resolve the declarations named by FUNC directives.
+	for i := range tokens {
+		if tokens[i].directive != directiveFunc {
+			continue
+		}
+
+		if len(tokens[i].args) == 0 {
+			return nil, nil, errors.Errorf("FUNC directive without a symbol at %s:%d", options.file, tokens[i].line)
+		}
+
+		symbol := tokens[i].args[0]
+		rng, ok, err := resolveSymbol(options.file, content.Bytes(), symbol)
+		if err != nil || !ok {
+			message := fmt.Sprintf("symbol %q of FUNC directive not found", symbol)
+			if err != nil {
+				message = fmt.Sprintf("symbol %q of FUNC directive not resolved: %v", symbol, err)
+			}
+
+			if options.strictDirectives {
+				return nil, nil, errors.Errorf("%s at %s:%d", message, options.file, tokens[i].line)
+			}
+
+			warnings = append(warnings, Warning{File: options.file, Line: tokens[i].line, Message: message})
+			continue
+		}
+
+		tokens[i].symbol = &rng
 	}
 
-	return tokens, nil
+	if len(tokens) > 0 {
+		tokens = append(tokens, token{directive: directiveEOF, line: lineCount, lastNonBlank: lastNonBlank})
+	}
+
+	return tokens, warnings, nil
 }
 
-// parseToken parses the given line and returns the token if it is a directive.
-func parseToken(line string, lineNumber int, templates []string) (*token, bool, error) {
+// utf16Warning returns a warning that the given file is skipped if its
+// content starts with a UTF-16 byte order mark, since directives are only
+// recognized in UTF-8.
+func utf16Warning(file string, content []byte) (Warning, bool) {
+	if !bytes.HasPrefix(content, []byte{0xFF, 0xFE}) && !bytes.HasPrefix(content, []byte{0xFE, 0xFF}) {
+		return Warning{}, false
+	}
+
+	return Warning{
+		File:    file,
+		Line:    1,
+		Message: "skipping UTF-16 encoded file; directives are only recognized in UTF-8",
+	}, true
+}
+
+// mayContainDirectives returns false if the given content cannot contain a
+// directive matching any of the templates, judging by the templates'
+// prefixes. If warnMismatched is set, content mentioning the directive word,
+// e.g. "LINT.", may also contain directives.
+func mayContainDirectives(content []byte, templates []string, word string, warnMismatched bool) bool {
+	if warnMismatched && bytes.Contains(content, []byte(word+".")) {
+		return true
+	}
+
 	for _, template := range templates {
-		prefix, suffix, found := strings.Cut(template, "?")
-		if !found {
-			return nil, false, errors.New("template is missing ?")
+		// Invalid templates are left to the lexer to report.
+		m, err := compileTemplate(template)
+		if err != nil || m.prefix == "" || bytes.Contains(content, []byte(m.prefix)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseToken parses the given line and returns the token if it is a directive.
+// If several templates match the line, the one with the longest prefix and
+// suffix wins, and templates of the same length must parse the line the same
+// way.
+func parseToken(line string, lineNumber int, matchers []templateMatcher) (*token, bool, error) {
+	// Find the length of the longest matching templates first, so that only
+	// those parse the line.
+	bestLength := -1
+	for _, m := range matchers {
+		if length := m.length(); length > bestLength {
+			if _, ok := m.match(line); ok {
+				bestLength = length
+			}
+		}
+	}
+
+	var best *token
+	var bestTemplate string
+	for _, m := range matchers {
+		if m.length() != bestLength {
+			continue
 		}
 
-		if !strings.HasPrefix(line, prefix) || !strings.HasSuffix(line, suffix) {
+		s, ok := m.match(line)
+		if !ok {
 			continue
 		}
 
-		// Remove the prefix and suffix.
-		s := strings.TrimSuffix(strings.TrimPrefix(line, prefix), suffix)
-		args := strings.Split(s, " ")
-		d, err := parseDirective(args[0])
+		args, err := splitArgs(s)
 		if err != nil {
 			return nil, false, err
 		}
 
-		return &token{
-			directive: d,
+		// A directive-less line is reported as an unknown empty directive.
+		if len(args) == 0 {
+			args = []string{""}
+		}
+
+		args, note := splitNote(args)
+		t := &token{
+			directive: directive(args[0]),
 			args:      args[1:],
+			note:      note,
 			line:      lineNumber,
-		}, true, nil
+		}
+
+		if best != nil && m.template != bestTemplate && !sameToken(*t, *best) {
+			return nil, false, errors.Errorf("templates %q and %q parse the line differently", bestTemplate, m.template)
+		}
+
+		if best == nil {
+			best, bestTemplate = t, m.template
+		}
+	}
+
+	return best, best != nil, nil
+}
+
+// sameToken returns true if the given tokens have the same directive and
+// arguments.
+func sameToken(a, b token) bool {
+	if a.directive != b.directive || a.note != b.note || len(a.args) != len(b.args) {
+		return false
+	}
+
+	for i := range a.args {
+		if a.args[i] != b.args[i] {
+			return false
+		}
 	}
 
-	return nil, false, nil
+	return true
+}
+
+// splitArgs splits the given directive on runs of spaces and tabs. Double-
+// quoted parts may contain whitespace, and a backslash inside double quotes
+// escapes the next character, so that `IF "My Documents/a.yaml" b.go` has
+// two targets.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+
+	// inArg is true once the current argument has started, which may be
+	// with an empty pair of quotes.
+	var inArg, quoted, escaped bool
+	for _, r := range s {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quoted {
+		return nil, errors.New("unterminated quote")
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
+
+// splitNote splits the given directive arguments at the first "--", "#", or
+// "//"-prefixed argument and returns the arguments before it along with the
+// free text note after it, e.g. `END id // keep in sync` has the note
+// "keep in sync". A "#L10-L20" line range target does not start a note.
+func splitNote(args []string) ([]string, string) {
+	for i, arg := range args {
+		if i == 0 {
+			continue
+		}
+
+		var rest string
+		switch {
+		case arg == "--":
+		case strings.HasPrefix(arg, "//"):
+			rest = strings.TrimPrefix(arg, "//")
+		case strings.HasPrefix(arg, "#"):
+			if _, err := parseLineRange(arg[1:]); err == nil {
+				continue
+			}
+
+			rest = strings.TrimPrefix(arg, "#")
+		default:
+			continue
+		}
+
+		words := args[i+1:]
+		if rest != "" {
+			words = append([]string{rest}, words...)
+		}
+
+		return args[:i], strings.Join(words, " ")
+	}
+
+	return args, ""
 }
 
 // parseDirective parses the given string and returns the directive.
 func parseDirective(s string) (directive, error) {
 	d := directive(s)
 	switch d {
-	case directiveIf, directiveEnd:
+	case directiveIf, directiveEnd, directiveExpires, directiveThen, directiveLine, directiveFunc:
 		return d, nil
 	default:
 		return "", errors.Errorf("unknown directive %q", d)
 	}
 }
 
-// parseRules parses the given tokens and returns the list of rules.
-func parseRules(file string, tokens []token, ranges []Range) ([]Rule, error) {
-	// Current rule being parsed.
-	r := Rule{}
+// closestDirective returns the known directive that the given unknown
+// directive is most likely a typo of, if any.
+func closestDirective(d directive) (directive, bool) {
+	for _, known := range directives {
+		if editDistance(string(d), string(known)) <= maxTypoDistance {
+			return known, true
+		}
+	}
+
+	return "", false
+}
+
+// editDistance returns the Levenshtein distance between the given strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
 
-	var rules []Rule
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+// parseRules parses the given tokens and returns the list of rules. A THEN
+// directive starts a rule that spans the rest of the file, and a LINE
+// directive is a rule of its own that spans the next non-directive line.
+// IF blocks may be nested; each END closes the innermost open block. The
+// rules are ordered by their first line. Target macros are expanded with the
+// given macros of the file.
+//
+// An END without an IF and an IF without an END are skipped with a warning
+// rather than failing the file. The blocks next to a missing directive may
+// have been closed by the wrong END, so those blocks are dropped as well.
+func parseRules(file string, tokens []token, ranges Ranges, macros map[string]string) ([]Rule, []Warning, error) {
+	// Stack of the open IF blocks, innermost last.
+	var stack []Rule
+
+	// First lines of the IF blocks, by the line of their END.
+	closed := make(map[int]int)
+	var warnings []Warning
+
+	// Lines on which there are directives, skipped by LINE directives.
+	directiveLines := make(map[int]struct{}, len(tokens))
 	for _, token := range tokens {
+		if token.directive != directiveEOF {
+			directiveLines[token.line] = struct{}{}
+		}
+	}
+
+	// Rules started by THEN directives, which end at EOF.
+	var thenRules []Rule
+
+	var rules []Rule
+	for i, token := range tokens {
 		switch token.directive {
-		case directiveIf:
-			if r.Hunk.File != "" {
-				return nil, errors.New("unexpected IF directive at " + file + ":" + string(rune(token.line)))
+		case directiveThen:
+			if len(stack) > 0 {
+				return nil, nil, errors.Errorf("unexpected THEN directive inside IF block at %s:%d", file, token.line)
+			}
+
+			rule := Rule{Hunk: Hunk{File: file, Range: Range{Start: token.line}}, Note: token.note}
+			if err := parseInlineRuleArgs(&rule, token.args, macros); err != nil {
+				return nil, nil, errors.Wrapf(err, "at %s:%d", file, token.line)
+			}
+
+			thenRules = append(thenRules, rule)
+
+		case directiveLine:
+			rule, err := parseLineRule(file, token, directiveLines, tokens[len(tokens)-1].line, macros)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "at %s:%d", file, token.line)
+			}
+
+			rule.Present = ranges.AnyIntersects(rule.Hunk.Range)
+			rules = append(rules, *rule)
+
+		case directiveFunc:
+			// Unresolved symbols were reported by the lexer.
+			if token.symbol == nil {
+				continue
+			}
+
+			rule := Rule{Hunk: Hunk{File: file, Range: *token.symbol}, Body: *token.symbol, Note: token.note}
+			id := token.args[0]
+			rule.ID = &id
+			if err := parseInlineRuleArgs(&rule, token.args[1:], macros); err != nil {
+				return nil, nil, errors.Wrapf(err, "at %s:%d", file, token.line)
 			}
 
+			rule.Present = ranges.AnyIntersects(rule.Hunk.Range)
+			rules = append(rules, rule)
+
+		case directiveEOF:
+			for _, then := range thenRules {
+				then.Hunk.Range.End = token.line
+				then.Body = Range{Start: then.Hunk.Range.Start + 1, End: token.line}
+				then.Blank = token.lastNonBlank <= then.Hunk.Range.Start
+				then.Present = ranges.AnyIntersects(then.Hunk.Range)
+				rules = append(rules, then)
+			}
+
+		case directiveIf:
 			targets, err := parseTargets(parseTargetsOptions{
 				args:           token.args,
 				allowEmptyArgs: true,
+				macros:         macros,
 			})
 			if err != nil {
-				return nil, err
+				return nil, nil, errors.Wrapf(err, "at %s:%d", file, token.line)
+			}
+
+			stack = append(stack, Rule{
+				Hunk:    Hunk{File: file, Range: Range{Start: token.line}},
+				Targets: targets,
+				Note:    token.note,
+			})
+
+		case directiveExpires:
+			if len(stack) == 0 {
+				return nil, nil, errors.Errorf("unexpected EXPIRES directive at %s:%d", file, token.line)
+			}
+
+			r := &stack[len(stack)-1]
+			if r.Expires != nil {
+				return nil, nil, errors.Errorf("duplicate EXPIRES directive at %s:%d", file, token.line)
 			}
 
-			r.Targets = targets
-			r.Hunk.File = file
-			r.Hunk.Range = Range{Start: token.line}
+			expires, err := parseExpires(token.args)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "at %s:%d", file, token.line)
+			}
+
+			r.Expires = expires
 
 		case directiveEnd:
-			if r.Hunk.File == "" {
-				return nil, errors.New("unexpected END directive at " + file + ":" + string(rune(token.line)))
+			if len(stack) == 0 {
+				var dropped []string
+				rules, dropped = dropBlocks(rules, strayEndBlocks(tokens[:i], closed))
+				warnings = append(warnings, Warning{
+					File:    file,
+					Line:    token.line,
+					Message: "END directive without a matching IF, skipped" + droppedBlocksMessage(dropped),
+				})
+
+				continue
+			}
+
+			r := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			args, err := parseEndOptions(&r, token.args)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "at %s:%d", file, token.line)
 			}
 
-			if len(token.args) == 1 {
-				r.ID = &(token.args[0])
+			if len(args) == 1 {
+				r.ID = &(args[0])
 			}
 
-			if len(token.args) > 1 {
-				return nil, errors.Errorf("unexpected arguments %v", token.args)
+			if len(args) > 1 {
+				return nil, nil, errors.Errorf("unexpected arguments %v", args)
 			}
 
+			if r.Severity == "" {
+				r.Severity = SeverityError
+			}
+
+			r.Note = joinNotes(r.Note, token.note)
 			r.Hunk.Range.End = token.line
-			for _, rng := range ranges {
-				if !Intersects(r.Hunk.Range, rng) {
-					continue
+			r.Body = Range{Start: r.Hunk.Range.Start + 1, End: token.line - 1}
+			r.Blank = token.lastNonBlank <= r.Hunk.Range.Start
+			r.Present = ranges.AnyIntersects(r.Hunk.Range)
+			closed[token.line] = r.Hunk.Range.Start
+			rules = append(rules, r)
+
+		default:
+			return nil, nil, errors.Errorf("unknown directive %q", token.directive)
+		}
+	}
+
+	// The blocks opened right after the outermost unclosed block may have been
+	// closed by its missing END.
+	for i, r := range stack {
+		message := "IF directive without a matching END, rule dropped"
+		if i == 0 {
+			var dropped []string
+			rules, dropped = dropBlocks(rules, unclosedIfBlocks(tokens, r.Hunk.Range.Start))
+			message += droppedBlocksMessage(dropped)
+		}
+
+		warnings = append(warnings, Warning{File: file, Line: r.Hunk.Range.Start, Message: message})
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Hunk.Range.Start < rules[j].Hunk.Range.Start
+	})
+
+	return rules, warnings, nil
+}
+
+// strayEndBlocks returns the first lines of the IF blocks closed by the run of
+// ENDs that directly precedes a stray END, given the tokens before it. Only
+// these blocks were open after the last IF, so if the missing IF followed that
+// IF, they are the blocks that may have been closed by the wrong END; blocks
+// closed before the last IF are kept.
+func strayEndBlocks(tokens []token, closed map[int]int) map[int]struct{} {
+	blocks := make(map[int]struct{})
+	for i := len(tokens) - 1; i >= 0 && tokens[i].directive != directiveIf; i-- {
+		if start, ok := closed[tokens[i].line]; ok && tokens[i].directive == directiveEnd {
+			blocks[start] = struct{}{}
+		}
+	}
+
+	return blocks
+}
+
+// unclosedIfBlocks returns the first lines of the IF blocks opened by the run
+// of IFs that directly follows the unclosed IF at the given line. Only these
+// blocks were open before the first END after it, so if the missing END
+// preceded that END, they are the blocks that may have been closed by the
+// wrong END; blocks opened after the first END are kept.
+func unclosedIfBlocks(tokens []token, line int) map[int]struct{} {
+	blocks := make(map[int]struct{})
+	for _, t := range tokens {
+		if t.line <= line || t.directive == directiveEOF {
+			continue
+		}
+
+		if t.directive == directiveEnd {
+			break
+		}
+
+		if t.directive == directiveIf {
+			blocks[t.line] = struct{}{}
+		}
+	}
+
+	return blocks
+}
+
+// dropBlocks returns the given rules without the rules of the IF blocks that
+// start at the given lines, along with the line ranges of the dropped rules.
+func dropBlocks(rules []Rule, blocks map[int]struct{}) ([]Rule, []string) {
+	var dropped []string
+	kept := rules[:0]
+	for _, r := range rules {
+		if _, ok := blocks[r.Hunk.Range.Start]; ok {
+			dropped = append(dropped, fmt.Sprintf("%d-%d", r.Hunk.Range.Start, r.Hunk.Range.End))
+			continue
+		}
+
+		kept = append(kept, r)
+	}
+
+	return kept, dropped
+}
+
+// droppedBlocksMessage returns the part of a warning that lists the blocks
+// dropped for an unbalanced directive, if any.
+func droppedBlocksMessage(dropped []string) string {
+	if len(dropped) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("; also dropped the rules at lines %s, whose IF and END may be mis-paired", strings.Join(dropped, ", "))
+}
+
+// parseLineRule parses the given LINE directive into a rule that spans the
+// next line that is not a directive, up to the last line of the file. An
+// id=ID argument names the rule and the other key=value arguments are the
+// options of an END directive.
+func parseLineRule(file string, t token, directiveLines map[int]struct{}, lastLine int, macros map[string]string) (*Rule, error) {
+	line := t.line + 1
+	for ; line <= lastLine; line++ {
+		if _, ok := directiveLines[line]; !ok {
+			break
+		}
+	}
+
+	if line > lastLine {
+		return nil, errors.New("LINE directive has no line to guard")
+	}
+
+	r := Rule{Hunk: Hunk{File: file, Range: Range{Start: line, End: line}}, Body: Range{Start: line, End: line}, Note: t.note}
+	if err := parseInlineRuleArgs(&r, t.args, macros); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// parseInlineRuleArgs parses the arguments of a directive that is a rule of
+// its own, such as THEN, LINE, or FUNC, into the given rule: an id=ID argument
+// names the rule, the other key=value arguments are the options of an END
+// directive, and the rest are targets.
+func parseInlineRuleArgs(r *Rule, args []string, macros map[string]string) error {
+	var rest []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "id=") {
+			id := strings.TrimPrefix(arg, "id=")
+			if id == "" {
+				return errors.New("empty id")
+			}
+
+			r.ID = &id
+			continue
+		}
+
+		rest = append(rest, arg)
+	}
+
+	rest, err := parseEndOptions(r, rest)
+	if err != nil {
+		return err
+	}
+
+	targets, err := parseTargets(parseTargetsOptions{args: rest, macros: macros})
+	if err != nil {
+		return err
+	}
+
+	r.Targets = targets
+	if r.Severity == "" {
+		r.Severity = SeverityError
+	}
+
+	return nil
+}
+
+// joinNotes joins the notes of a rule's IF and END directives.
+func joinNotes(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+
+	return a + "; " + b
+}
+
+// parseEndOptions applies the key=value options of an END directive to the
+// given rule and returns the remaining positional arguments.
+func parseEndOptions(r *Rule, args []string) ([]string, error) {
+	var positional []string
+	for _, arg := range args {
+		key, value, found := strings.Cut(arg, "=")
+		if !found {
+			positional = append(positional, arg)
+			continue
+		}
+
+		switch key {
+		case "severity":
+			severity, err := ParseSeverity(value)
+			if err != nil {
+				return nil, err
+			}
+
+			r.Severity = severity
+
+		case "allow-self":
+			allow, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, errors.Errorf("invalid allow-self value %q", value)
+			}
+
+			r.AllowSelfTarget = allow
+
+		case "tags":
+			for _, tag := range strings.Split(value, ",") {
+				if tag == "" {
+					return nil, errors.Errorf("empty tag in %q", arg)
 				}
 
-				r.Present = true
-				break
+				r.Tags = append(r.Tags, tag)
 			}
-			rules = append(rules, r)
 
-			// Reset the rule.
-			r = Rule{}
+		case "owner":
+			if value == "" {
+				return nil, errors.Errorf("empty owner in %q", arg)
+			}
+
+			r.Owner = value
 
 		default:
-			return nil, errors.Errorf("unknown directive %q", token.directive)
+			return nil, errors.Errorf("unknown option %q", key)
 		}
 	}
 
-	return rules, nil
+	return positional, nil
+}
+
+// parseExpires parses the date of an EXPIRES directive, either an RFC 3339
+// timestamp or a plain YYYY-MM-DD date (midnight UTC).
+func parseExpires(args []string) (*time.Time, error) {
+	if len(args) != 1 {
+		return nil, errors.Errorf("EXPIRES expects one date, got %d arguments", len(args))
+	}
+
+	for _, layout := range expiresLayouts {
+		if t, err := time.Parse(layout, args[0]); err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, errors.Errorf("invalid EXPIRES date %q, expected YYYY-MM-DD or RFC 3339", args[0])
 }
 
 // parseTargets parses the given list of targets and returns the list of targets.
 type parseTargetsOptions struct {
 	args           []string
 	allowEmptyArgs bool
+
+	// macros maps the target macros of the rule's file, e.g. "@tests", to
+	// the targets they expand to.
+	macros map[string]string
 }
 
 // parseTargets parses the given list of targets and returns the list of targets.
@@ -179,18 +885,112 @@ func parseTargets(o parseTargetsOptions) ([]Target, error) {
 
 	var targets []Target
 	for _, arg := range o.args {
+		var macro string
+		if strings.HasPrefix(arg, "@") {
+			name, expanded, err := expandMacro(arg, o.macros)
+			if err != nil {
+				return nil, err
+			}
+
+			macro, arg = name, expanded
+		}
+
 		file, id, hasID := strings.Cut(arg, ":")
-		var target Target
+		target := Target{Macro: macro, Raw: arg}
 		if file != "" {
 			target.File = &file
 		}
 
+		if isGlob(file) && (hasID || strings.Contains(file, "#L")) {
+			return nil, errors.Errorf("glob target %q cannot have an ID or a line range", arg)
+		}
+
 		if hasID {
 			target.ID = &id
 		}
 
+		if i := strings.LastIndex(file, "#L"); i >= 0 {
+			if hasID {
+				return nil, errors.Errorf("target %q has both an ID and a line range", arg)
+			}
+
+			rng, err := parseLineRange(file[i+1:])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid target %q", arg)
+			}
+
+			target.Range = rng
+			target.File = nil
+			if file := file[:i]; file != "" {
+				target.File = &file
+			}
+		}
+
 		targets = append(targets, target)
 	}
 
 	return targets, nil
 }
+
+// expandMacro expands the macro or path alias at the start of the given
+// target, e.g. "@tests" or the "@api" of "@api/schema.sql". Only the file
+// part of the target is expanded, never its ID. It returns the name of the
+// macro and the expanded target.
+func expandMacro(arg string, macros map[string]string) (string, string, error) {
+	file, id, hasID := strings.Cut(arg, ":")
+	name, rest, hasRest := strings.Cut(file, "/")
+	expanded, ok := macros[name]
+	if !ok {
+		return "", "", errors.Errorf("unknown target macro or alias %q in target %q", name, arg)
+	}
+
+	if hasRest {
+		expanded = strings.TrimSuffix(expanded, "/") + "/" + rest
+	}
+
+	if hasID {
+		expanded += ":" + id
+	}
+
+	return name, expanded, nil
+}
+
+// isGlob returns true if the given target file name is a glob pattern.
+func isGlob(file string) bool {
+	return strings.ContainsAny(file, "*?[")
+}
+
+// parseLineRange parses a line range of the form "L10-L40", or "L10" for a
+// single line.
+func parseLineRange(s string) (*Range, error) {
+	startText, endText, found := strings.Cut(s, "-")
+	if !found {
+		endText = startText
+	}
+
+	start, err := parseLineNumber(startText)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := parseLineNumber(endText)
+	if err != nil {
+		return nil, err
+	}
+
+	if end < start {
+		return nil, errors.Errorf("line range %q ends before it starts", s)
+	}
+
+	return &Range{Start: start, End: end}, nil
+}
+
+// parseLineNumber parses a line number of the form "L10".
+func parseLineNumber(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "L"))
+	if err != nil || !strings.HasPrefix(s, "L") || n < 1 {
+		return 0, errors.Errorf("invalid line number %q, expected L1 or greater", s)
+	}
+
+	return n, nil
+}
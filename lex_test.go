@@ -0,0 +1,515 @@
+package difflint
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parserFixtures are files with every kind of directive, by path.
+var parserFixtures = map[string]string{
+	"a.go":     "package a\n\n//LINT.IF b.py:block\nvar X = 1\n//LINT.IF docs.md\nvar Y = 2\n//LINT.END inner\n//LINT.END outer severity=warn\n\n//LINT.LINE c.go id=version\nconst Version = 1\n",
+	"b.py":     "#LINT.IF a.go:outer\nx = 1\n#LINT.END block tags=api\n",
+	"docs.md":  "# Docs\n<!--LINT.IF a.go\ntext\n<!--LINT.END\n",
+	"c.go":     "package c\n\n//LINT.THEN a.go\n\nvar Z = 3\n",
+	"plain.go": "package plain\n",
+}
+
+func TestParseFileRulesIsTheLintParser(t *testing.T) {
+	root := writeTree(t, parserFixtures)
+	rulesMap, err := RulesMapFromHunks(context.Background(), nil, LintOptions{
+		Root:       root,
+		Templates:  DefaultTemplates,
+		FileExtMap: DefaultFileExtMap,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	for _, rules := range rulesMap.Rules {
+		count += len(rules)
+	}
+
+	if count != 6 {
+		t.Errorf("RulesMapFromHunks() = %d rules, want 6", count)
+	}
+
+	for file, content := range parserFixtures {
+		want, err := ParseFileRules(file, strings.NewReader(content), LintOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := rulesMap.Rules[file]; !reflect.DeepEqual(got, want) {
+			t.Errorf("RulesMapFromHunks() rules of %s = %+v, want the rules of ParseFileRules() %+v", file, got, want)
+		}
+	}
+}
+
+func TestParseFileRulesThen(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Range
+		wantErr string
+	}{
+		{
+			name:    "rest of the file",
+			content: "package c\n\n//LINT.THEN a.go\n\nvar Z = 3\n",
+			want:    []Range{{Start: 3, End: 5}},
+		},
+		{
+			name:    "after an IF block",
+			content: "//LINT.IF a.go\nvar X = 1\n//LINT.END\n//LINT.THEN b.go\nvar Y = 2\n",
+			want:    []Range{{Start: 1, End: 3}, {Start: 4, End: 5}},
+		},
+		{
+			name:    "several",
+			content: "//LINT.THEN a.go\nvar X = 1\n//LINT.THEN b.go\nvar Y = 2\n",
+			want:    []Range{{Start: 1, End: 4}, {Start: 3, End: 4}},
+		},
+		{
+			name:    "not an END",
+			content: "//LINT.IF a.go\nvar X = 1\n//LINT.THEN b.go\n",
+			wantErr: "unexpected THEN directive inside IF block at x.go:3",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := ParseFileRules("x.go", strings.NewReader(test.content), LintOptions{})
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("ParseFileRules() error = %v, want %q", err, test.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []Range
+			for _, rule := range rules {
+				got = append(got, rule.Hunk.Range)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseFileRules() ranges = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseFileRulesThenOptions(t *testing.T) {
+	content := "package c\n//LINT.THEN b.go severity=warn id=rest tags=api,docs owner=@team\nvar X = 1\n"
+	rules, err := ParseFileRules("x.go", strings.NewReader(content), LintOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rules) != 1 {
+		t.Fatalf("ParseFileRules() = %+v, want one rule", rules)
+	}
+
+	rule := rules[0]
+	if len(rule.Targets) != 1 || rule.Targets[0].Raw != "b.go" {
+		t.Errorf("ParseFileRules() targets = %+v, want only b.go", rule.Targets)
+	}
+
+	if rule.Severity != SeverityWarn || rule.ID == nil || *rule.ID != "rest" || rule.Owner != "@team" || !reflect.DeepEqual(rule.Tags, []string{"api", "docs"}) {
+		t.Errorf("ParseFileRules() = %+v, want the options of the THEN directive", rule)
+	}
+
+	if _, err := ParseFileRules("x.go", strings.NewReader("//LINT.THEN b.go severity=bad\n"), LintOptions{}); err == nil {
+		t.Error("ParseFileRules() accepted an invalid severity")
+	}
+}
+
+func TestLintThen(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go": "package a\n\nvar X = 1\n\n//LINT.THEN b.go\n\nvar Y = 2\n",
+		"b.go": "package b\n",
+	})
+
+	tests := []struct {
+		name string
+		diff string
+		want int
+	}{
+		{
+			name: "below the directive",
+			diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -7,1 +7,1 @@\n-var Y = 1\n+var Y = 2\n",
+			want: 1,
+		},
+		{
+			name: "above the directive",
+			diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -3,1 +3,1 @@\n-var X = 0\n+var X = 1\n",
+			want: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Lint(context.Background(), LintOptions{
+				Root:           root,
+				Reader:         strings.NewReader(test.diff),
+				Templates:      DefaultTemplates,
+				FileExtMap:     DefaultFileExtMap,
+				StrictPresence: true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result.UnsatisfiedRules) != test.want {
+				t.Errorf("Lint() = %v, want %d unsatisfied rules", result.UnsatisfiedRules, test.want)
+			}
+		})
+	}
+}
+
+func TestParseRulesUnbalanced(t *testing.T) {
+	type warning struct {
+		line    int
+		dropped string // Line ranges of the dropped rules named by the warning.
+	}
+
+	tests := []struct {
+		name         string
+		content      string
+		want         []Range
+		wantWarnings []warning
+	}{
+		{
+			name:         "siblings before a stray END",
+			content:      "//LINT.IF a.go\n//LINT.END\n//LINT.IF b.go\n//LINT.END\n//LINT.END\n",
+			want:         []Range{{Start: 1, End: 2}},
+			wantWarnings: []warning{{line: 5, dropped: "3-4"}},
+		},
+		{
+			name:         "missing inner IF",
+			content:      "//LINT.IF a.go\nvar X = 1\n//LINT.END\n//LINT.END\n//LINT.IF c.go\nvar Y = 2\n//LINT.END\n",
+			want:         []Range{{Start: 5, End: 7}},
+			wantWarnings: []warning{{line: 4, dropped: "1-3"}},
+		},
+		{
+			name:         "nested blocks before a stray END",
+			content:      "//LINT.IF a.go\n//LINT.IF b.go\n//LINT.END\n//LINT.END\n//LINT.IF c.go\n//LINT.END\n//LINT.END\n",
+			want:         []Range{{Start: 1, End: 4}, {Start: 2, End: 3}},
+			wantWarnings: []warning{{line: 7, dropped: "5-6"}},
+		},
+		{
+			name:         "run of ENDs before a stray END",
+			content:      "//LINT.IF a.go\n//LINT.END\n//LINT.IF b.go\n//LINT.IF c.go\n//LINT.END\n//LINT.END\n//LINT.END\n",
+			want:         []Range{{Start: 1, End: 2}},
+			wantWarnings: []warning{{line: 7, dropped: "4-5, 3-6"}},
+		},
+		{
+			name:         "missing inner END",
+			content:      "//LINT.IF a.go\n//LINT.END\n//LINT.IF b.go\n//LINT.IF c.go\nx\n//LINT.END\n//LINT.IF d.go\ny\n//LINT.END\n",
+			want:         []Range{{Start: 1, End: 2}, {Start: 7, End: 9}},
+			wantWarnings: []warning{{line: 3, dropped: "4-6"}},
+		},
+		{
+			name:         "nested unclosed IFs",
+			content:      "//LINT.IF a.go\n//LINT.IF b.go\n//LINT.END\n//LINT.IF c.go\n",
+			wantWarnings: []warning{{line: 1, dropped: "2-3"}, {line: 4}},
+		},
+		{
+			name:         "several stray ENDs",
+			content:      "//LINT.IF a.go\n//LINT.END\n//LINT.END\n//LINT.IF b.go\n//LINT.END\n//LINT.IF c.go\n//LINT.END\n//LINT.END\n",
+			want:         []Range{{Start: 4, End: 5}},
+			wantWarnings: []warning{{line: 3, dropped: "1-2"}, {line: 8, dropped: "6-7"}},
+		},
+		{
+			name:         "stray END and unclosed IF between valid blocks",
+			content:      "//LINT.IF a.go\n//LINT.END\n//LINT.END\n//LINT.IF b.go\n//LINT.END\n//LINT.IF d.go\n//LINT.IF e.go\n//LINT.END\n//LINT.IF f.go\n//LINT.END\n",
+			want:         []Range{{Start: 4, End: 5}, {Start: 9, End: 10}},
+			wantWarnings: []warning{{line: 3, dropped: "1-2"}, {line: 6, dropped: "7-8"}},
+		},
+		{
+			name:         "LINE directives in a run of ENDs",
+			content:      "//LINT.IF a.go\n//LINT.END\n//LINT.LINE c.go\nvar X = 1\n//LINT.END\n",
+			want:         []Range{{Start: 4, End: 4}},
+			wantWarnings: []warning{{line: 5, dropped: "1-2"}},
+		},
+	}
+
+	options := &LintOptions{Templates: DefaultTemplates, FileExtMap: DefaultFileExtMap}
+	templates, err := options.TemplatesFromFile("x.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, _, err := lex(strings.NewReader(test.content), lexOptions{file: "x.go", templates: templates, word: "LINT"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rules, warnings, err := parseRules("x.go", tokens, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []Range
+			for _, rule := range rules {
+				got = append(got, rule.Hunk.Range)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseRules() ranges = %v, want %v", got, test.want)
+			}
+
+			if len(warnings) != len(test.wantWarnings) {
+				t.Fatalf("parseRules() warnings = %v, want %d", warnings, len(test.wantWarnings))
+			}
+
+			for i, want := range test.wantWarnings {
+				got := warnings[i]
+				wantMessage := "also dropped the rules at lines " + want.dropped + ","
+				if want.dropped == "" {
+					wantMessage = "without a matching"
+				}
+
+				if got.Line != want.line || !strings.Contains(got.Message, wantMessage) || want.dropped == "" && strings.Contains(got.Message, "also dropped") {
+					t.Errorf("parseRules() warning %d = %v, want line %d dropping %q", i, got, want.line, want.dropped)
+				}
+			}
+		})
+	}
+}
+
+func TestMayContainDirectivesWord(t *testing.T) {
+	templates := DefaultTemplatesFor("DIFF")
+	tests := []struct {
+		name           string
+		content        string
+		warnMismatched bool
+		want           bool
+	}{
+		{name: "custom word", content: "package a\n//DIFF.IF b.go\n", want: true},
+		{name: "custom word in a block comment", content: "/*DIFF.IF b.go */\n", want: true},
+		{name: "default word", content: "package a\n//LINT.IF b.go\n"},
+		{name: "default word with warnings", content: "package a\n//LINT.IF b.go\n", warnMismatched: true},
+		{name: "mismatched custom word", content: "package a\n// DIFF.IF b.go\n", warnMismatched: true, want: true},
+		{name: "no directive", content: "package a\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := mayContainDirectives([]byte(test.content), templates, "DIFF", test.warnMismatched); got != test.want {
+				t.Errorf("mayContainDirectives(%q) = %t, want %t", test.content, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLintDirectiveWordPrefilter(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go": "package a\n//DIFF.IF b.go\nvar X = 1\n//DIFF.END\n",
+		"b.go": "package b\n",
+	})
+
+	diff := "diff --git a/b.go b/b.go\n--- a/b.go\n+++ b/b.go\n@@ -1,1 +1,1 @@\n-package a\n+package b\n"
+	result, err := Lint(context.Background(), LintOptions{
+		Root:          root,
+		Reader:        strings.NewReader(diff),
+		Templates:     DefaultTemplatesFor("DIFF"),
+		FileExtMap:    DefaultFileExtMap,
+		DirectiveWord: "DIFF",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.UnsatisfiedRules) != 1 || result.UnsatisfiedRules[0].Hunk.File != "a.go" {
+		t.Errorf("Lint() = %v, want the DIFF rule of a.go unsatisfied", result.UnsatisfiedRules)
+	}
+}
+
+func TestParseFileRulesBOMAndCRLF(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		options LintOptions
+	}{
+		{
+			name:    "line comment",
+			file:    "a.go",
+			content: "//LINT.IF b.go\nvar X = 1\n//LINT.END\n",
+		},
+		{
+			name:    "template with a suffix",
+			file:    "a.html",
+			content: "<!-- LINT.IF b.go -->\n<p>X</p>\n<!-- LINT.END -->\n",
+			options: LintOptions{
+				Templates:  []string{"<!-- LINT.? -->"},
+				FileExtMap: map[string][]int{"html": {0}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want, err := ParseFileRules(test.file, strings.NewReader(test.content), test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(want) != 1 {
+				t.Fatalf("ParseFileRules() = %+v, want 1 rule", want)
+			}
+
+			windows := "\ufeff" + strings.ReplaceAll(test.content, "\n", "\r\n")
+			got, err := ParseFileRules(test.file, strings.NewReader(windows), test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseFileRules() with a BOM and CRLF = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLintSkipsUTF16(t *testing.T) {
+	// utf16 is a rule encoded as UTF-16LE with a byte order mark.
+	utf16 := []byte{0xFF, 0xFE}
+	for _, r := range "//LINT.IF b.go\nvar X = 1\n//LINT.END\n" {
+		utf16 = append(utf16, byte(r), 0)
+	}
+
+	root := writeTree(t, map[string]string{
+		"a.go": string(utf16),
+		"b.go": "package b\n",
+	})
+
+	diff := "diff --git a/b.go b/b.go\n--- a/b.go\n+++ b/b.go\n@@ -1,1 +1,1 @@\n-package a\n+package b\n"
+	result, err := Lint(context.Background(), LintOptions{
+		Root:       root,
+		Reader:     strings.NewReader(diff),
+		Templates:  DefaultTemplates,
+		FileExtMap: DefaultFileExtMap,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.UnsatisfiedRules) != 0 {
+		t.Errorf("Lint() = %v, want no rules of the UTF-16 file", result.UnsatisfiedRules)
+	}
+
+	if len(result.Warnings) != 1 || result.Warnings[0].File != "a.go" || !strings.Contains(result.Warnings[0].Message, "UTF-16") {
+		t.Errorf("Lint() warnings = %v, want a.go skipped as UTF-16", result.Warnings)
+	}
+}
+
+func TestLintDirectiveTypo(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go": "package a\n//LINT.IF t.go\nvar X = 1\n//LINT.ENDD\n",
+		"b.go": "package b\n//LINT.IF t.go\nvar Y = 1\n//LINT.END\n//LINT.TODO later\n",
+		"t.go": "package t\n",
+	})
+
+	const diff = "diff --git a/t.go b/t.go\n--- a/t.go\n+++ b/t.go\n@@ -1,1 +1,1 @@\n-package a\n+package t\n"
+	lint := func(strict bool) (*LintResult, error) {
+		return Lint(context.Background(), LintOptions{
+			Root:             root,
+			Reader:           strings.NewReader(diff),
+			Templates:        DefaultTemplates,
+			FileExtMap:       DefaultFileExtMap,
+			StrictDirectives: strict,
+		})
+	}
+
+	result, err := lint(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.UnsatisfiedRules) != 1 || result.UnsatisfiedRules[0].Hunk.File != "b.go" {
+		t.Errorf("Lint() = %v, want the rule of b.go unsatisfied", result.UnsatisfiedRules)
+	}
+
+	var warnings []string
+	for _, warning := range result.Warnings {
+		warnings = append(warnings, warning.String())
+	}
+
+	for _, want := range []string{
+		`a.go:4: ignoring unknown directive "ENDD" (did you mean "END"?)`,
+		`b.go:5: ignoring unknown directive "TODO"`,
+	} {
+		found := false
+		for _, warning := range warnings {
+			found = found || warning == want
+		}
+
+		if !found {
+			t.Errorf("Lint() warnings = %q, want %q", warnings, want)
+		}
+	}
+
+	if _, err := lint(true); err == nil || !strings.Contains(err.Error(), "a.go:4") {
+		t.Errorf("Lint() with StrictDirectives error = %v, want the typo of a.go:4", err)
+	}
+}
+
+func TestParseTokenLongestMatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates []string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "shorter tie before the longest",
+			templates: []string{"//L?", "? */", "//LINT.? */"},
+			want:      "IF",
+		},
+		{
+			name:      "shorter tie after the longest",
+			templates: []string{"//LINT.? */", "//L?", "? */"},
+			want:      "IF",
+		},
+		{
+			name:      "tie at the longest",
+			templates: []string{"//L?", "? */"},
+			wantErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matchers, err := compileTemplates(test.templates)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, found, err := parseToken("//LINT.IF b.go */", 1, matchers)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("parseToken() = %+v, want an error", token)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !found || string(token.directive) != test.want || !reflect.DeepEqual(token.args, []string{"b.go"}) {
+				t.Errorf("parseToken() = %+v, want %s b.go", token, test.want)
+			}
+		})
+	}
+}
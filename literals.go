@@ -0,0 +1,153 @@
+package difflint
+
+import (
+	"strings"
+)
+
+// literalScanner follows the multi-line string literals, or code blocks, of
+// a file line by line so that directive-like lines inside them are ignored.
+type literalScanner interface {
+	// scan advances over the given line and returns true if the line
+	// starts inside a literal.
+	scan(line string) bool
+}
+
+// newLiteralScanner returns the literal scanner of the given file extension
+// if there is one.
+func newLiteralScanner(ext string) (literalScanner, bool) {
+	switch ext {
+	case "go":
+		return &cLikeScanner{}, true
+	case "js", "jsx", "mjs", "ts", "tsx":
+		return &cLikeScanner{escapedRaw: true}, true
+	case "py":
+		return &pythonScanner{}, true
+	case "md", "markdown":
+		return &markdownScanner{}, true
+	default:
+		return nil, false
+	}
+}
+
+// cLikeScanner follows the raw strings, or template literals, delimited by
+// backticks in Go and JavaScript, skipping comments and single-line
+// strings.
+type cLikeScanner struct {
+	// escapedRaw is true if backslashes escape characters in raw strings,
+	// as in JavaScript template literals.
+	escapedRaw bool
+
+	// inRaw is true inside a raw string.
+	inRaw bool
+
+	// inComment is true inside a block comment.
+	inComment bool
+}
+
+// scan implements literalScanner.
+func (s *cLikeScanner) scan(line string) bool {
+	inside := s.inRaw
+	for i := 0; i < len(line); i++ {
+		switch {
+		case s.inRaw:
+			if line[i] == '\\' && s.escapedRaw {
+				i++
+			} else if line[i] == '`' {
+				s.inRaw = false
+			}
+		case s.inComment:
+			if strings.HasPrefix(line[i:], "*/") {
+				s.inComment = false
+				i++
+			}
+		case strings.HasPrefix(line[i:], "//"):
+			return inside
+		case strings.HasPrefix(line[i:], "/*"):
+			s.inComment = true
+			i++
+		case line[i] == '`':
+			s.inRaw = true
+		case line[i] == '"' || line[i] == '\'':
+			i = skipQuoted(line, i)
+		}
+	}
+
+	return inside
+}
+
+// pythonScanner follows the triple-quoted strings of Python, skipping
+// comments and single-line strings.
+type pythonScanner struct {
+	// quote is the delimiter of the triple-quoted string that the scanner
+	// is in, if any.
+	quote string
+}
+
+// scan implements literalScanner.
+func (s *pythonScanner) scan(line string) bool {
+	inside := s.quote != ""
+	for i := 0; i < len(line); i++ {
+		switch {
+		case s.quote != "":
+			if line[i] == '\\' {
+				i++
+			} else if strings.HasPrefix(line[i:], s.quote) {
+				i += len(s.quote) - 1
+				s.quote = ""
+			}
+		case line[i] == '#':
+			return inside
+		case strings.HasPrefix(line[i:], `"""`) || strings.HasPrefix(line[i:], "'''"):
+			s.quote = line[i : i+3]
+			i += 2
+		case line[i] == '"' || line[i] == '\'':
+			i = skipQuoted(line, i)
+		}
+	}
+
+	return inside
+}
+
+// markdownScanner follows the fenced code blocks of Markdown.
+type markdownScanner struct {
+	// fence is the fence of the code block that the scanner is in, if any.
+	fence string
+}
+
+// scan implements literalScanner. Fence lines count as inside the block.
+func (s *markdownScanner) scan(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if s.fence != "" {
+		if strings.HasPrefix(trimmed, s.fence) && strings.Trim(trimmed, s.fence[:1]+" \t") == "" {
+			s.fence = ""
+		}
+
+		return true
+	}
+
+	for _, fence := range []string{"```", "~~~"} {
+		if strings.HasPrefix(trimmed, fence) {
+			s.fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, fence[:1]))]
+			return true
+		}
+	}
+
+	return false
+}
+
+// skipQuoted returns the index of the quote that closes the single-line
+// string opened by the quote at index i of line, or the last index of line
+// if the string is not closed.
+func skipQuoted(line string, i int) int {
+	quote := line[i]
+	for i++; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		}
+	}
+
+	return len(line) - 1
+}
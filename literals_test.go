@@ -0,0 +1,61 @@
+package difflint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseFileRulesCommentsOnly(t *testing.T) {
+	tests := []struct {
+		ext         string
+		wantTextual []string
+	}{
+		{ext: "go", wantTextual: []string{"real", "phantom", "second"}},
+		{ext: "js", wantTextual: []string{"real", "phantom", "second"}},
+		{ext: "ts", wantTextual: []string{"real", "phantom", "second"}},
+		{ext: "py", wantTextual: []string{"real", "phantom", "raw_phantom", "second"}},
+		{ext: "md", wantTextual: []string{"real", "phantom", "second"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ext, func(t *testing.T) {
+			file := filepath.Join("testdata", "literals", "fixture."+test.ext)
+			if got := parseFixtureIDs(t, file, nil); !reflect.DeepEqual(got, test.wantTextual) {
+				t.Errorf("ParseFileRules(%s) = %q, want %q", file, got, test.wantTextual)
+			}
+
+			want := []string{"real", "second"}
+			if got := parseFixtureIDs(t, file, []string{test.ext}); !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseFileRules(%s) with --comments-only = %q, want %q", file, got, want)
+			}
+		})
+	}
+}
+
+// parseFixtureIDs returns the IDs of the rules of the given file, in order.
+func parseFixtureIDs(t *testing.T, file string, commentsOnly []string) []string {
+	t.Helper()
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	rules, err := ParseFileRules(filepath.ToSlash(file), f, LintOptions{CommentsOnly: commentsOnly})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		if rule.ID == nil {
+			t.Fatalf("ParseFileRules(%s) rule at line %d has no ID", file, rule.Hunk.Range.Start)
+		}
+
+		ids = append(ids, *rule.ID)
+	}
+
+	return ids
+}
@@ -0,0 +1,22 @@
+package difflint
+
+// Logger is the interface through which progress is reported while linting.
+// *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// nopLogger is a Logger that discards all output.
+type nopLogger struct{}
+
+// Printf discards the given message.
+func (nopLogger) Printf(string, ...any) {}
+
+// loggerOrNop returns the given logger, or a no-op logger if it is nil.
+func loggerOrNop(logger Logger) Logger {
+	if logger == nil {
+		return nopLogger{}
+	}
+
+	return logger
+}
@@ -0,0 +1,109 @@
+package difflint
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordingLogger records the messages logged to it.
+type recordingLogger struct {
+	messages []string
+}
+
+// Printf records the given message.
+func (l *recordingLogger) Printf(format string, v ...any) {
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+// captureOutput returns what f writes to the standard log, standard output,
+// and standard error.
+func captureOutput(t *testing.T, f func()) string {
+	t.Helper()
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	func() {
+		defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+		f()
+	}()
+
+	w.Close()
+	written, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return logged.String() + string(written)
+}
+
+func TestNilLoggerIsSilent(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":     "package a\n\n//LINT.IF b.go\nvar X = 1\n//LINT.END\n",
+		"b.go":     "package b\n",
+		"bin.go":   "\x00\x01\x02",
+		"gen/c.go": "package gen\n",
+	})
+	diff := "diff --git a/b.go b/b.go\n--- a/b.go\n+++ b/b.go\n@@ -1,1 +1,1 @@\n-package a\n+package b\n" +
+		"diff --git a/gen/c.go b/gen/c.go\n--- a/gen/c.go\n+++ b/gen/c.go\n@@ -1,1 +1,1 @@\n-package a\n+package gen\n"
+
+	lint := func(logger Logger) *LintResult {
+		result, err := Lint(context.Background(), LintOptions{
+			Root:       root,
+			Reader:     strings.NewReader(diff),
+			Exclude:    []string{"gen/*"},
+			Templates:  DefaultTemplates,
+			FileExtMap: DefaultFileExtMap,
+			Logger:     logger,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return result
+	}
+
+	var recorded recordingLogger
+	if result := lint(&recorded); len(result.UnsatisfiedRules) != 1 {
+		t.Fatalf("Lint() = %v, want 1 unsatisfied rule", result.UnsatisfiedRules)
+	}
+
+	if len(recorded.messages) == 0 {
+		t.Fatal("Lint() logged nothing, want progress messages")
+	}
+
+	if out := captureOutput(t, func() { lint(nil) }); out != "" {
+		t.Errorf("Lint() with a nil logger wrote %q, want no output", out)
+	}
+}
+
+func TestNewExtMapInvalidIsAnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ext.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	if out := captureOutput(t, func() { _, err = NewExtMap(path) }); out != "" {
+		t.Errorf("NewExtMap() wrote %q, want no output", out)
+	}
+
+	if err == nil {
+		t.Error("NewExtMap() of an invalid file succeeded, want an error")
+	}
+}
@@ -0,0 +1,63 @@
+package difflint
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares got to the golden file at the given path, or rewrites
+// the file with -update.
+func checkGolden(t *testing.T, path, got string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != string(want) {
+		t.Errorf("output differs from %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestUnsatisfiedRulesString(t *testing.T) {
+	str := func(s string) *string { return &s }
+	rules := UnsatisfiedRules{
+		{
+			Rule: Rule{
+				Hunk:     Hunk{File: "a.go", Range: Range{Start: 2, End: 4}},
+				ID:       str("a_block"),
+				Severity: SeverityError,
+				Targets: []Target{
+					{File: str("b.go"), ID: str("present")},
+					{File: str("b.go"), ID: str("missing")},
+					{File: str("c.go")},
+					{File: str("d.go"), ID: str("*")},
+				},
+			},
+			UnsatisfiedTargets: map[int]struct{}{0: {}, 1: {}, 2: {}, 3: {}},
+			TargetRanges:       map[int]Range{0: {Start: 3, End: 5}},
+		},
+		{
+			Rule: Rule{
+				Hunk:     Hunk{File: "dir/e.go", Range: Range{Start: 10, End: 12}},
+				Severity: SeverityWarn,
+				Targets: []Target{
+					{File: str("f.go")},
+					{File: str("g.go")},
+				},
+			},
+			UnsatisfiedTargets: map[int]struct{}{1: {}},
+		},
+	}
+
+	checkGolden(t, "testdata/unsatisfied.golden", rules.String())
+}
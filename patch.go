@@ -0,0 +1,196 @@
+package difflint
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// hunkHeaderPattern matches a unified diff hunk header and captures the
+// optional old and new line counts.
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+
+// commitHeaderPattern matches the line that starts a commit in the output of
+// git log -p, git show, and git format-patch, capturing the commit hash.
+var commitHeaderPattern = regexp.MustCompile(`^(?:commit|From) ([0-9a-f]{40})\b`)
+
+// maxPatchLineSize is the maximum length of a single line in a patch.
+const maxPatchLineSize = 16 * 1024 * 1024
+
+// DiffSegment is one of the diffs in a concatenation of diffs.
+type DiffSegment struct {
+	// Commit is the hash of the commit the diff belongs to, if known.
+	Commit string
+
+	// Diff is the content of the diff.
+	Diff []byte
+}
+
+// SplitDiffs splits a concatenation of diffs, such as the output of
+// git log -p, into one segment per commit. Input without commit headers is
+// returned as a single segment.
+func SplitDiffs(r io.Reader) ([]DiffSegment, error) {
+	var segments []DiffSegment
+	var current DiffSegment
+	var b bytes.Buffer
+
+	// oldLines and newLines are the remaining line counts of the current hunk.
+	var oldLines, newLines int
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxPatchLineSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if oldLines > 0 || newLines > 0 {
+			oldLines, newLines = consumeHunkLine(line, oldLines, newLines)
+		} else if m := commitHeaderPattern.FindStringSubmatch(line); m != nil {
+			if b.Len() > 0 {
+				current.Diff = append([]byte(nil), b.Bytes()...)
+				segments = append(segments, current)
+			}
+
+			current = DiffSegment{Commit: m[1]}
+			b.Reset()
+		} else if m := hunkHeaderPattern.FindStringSubmatch(line); m != nil {
+			oldLines = hunkLineCount(m[1])
+			newLines = hunkLineCount(m[2])
+		}
+
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if b.Len() > 0 {
+		current.Diff = b.Bytes()
+		segments = append(segments, current)
+	}
+
+	return segments, nil
+}
+
+// OpenPatches reads the given patch files and returns a reader over their
+// concatenated diffs.
+func OpenPatches(paths []string) (io.Reader, error) {
+	readers := make([]io.Reader, 0, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open patch file %s", path)
+		}
+
+		r, err := StripPatchMail(f)
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read patch file %s", path)
+		}
+
+		readers = append(readers, r)
+	}
+
+	return io.MultiReader(readers...), nil
+}
+
+// StripPatchMail returns the diff contained in the given patch with the mail
+// headers, commit messages, and signatures emitted by git format-patch
+// removed; each mail header is replaced by a "commit <hash>" line. Plain
+// diffs are returned unchanged.
+func StripPatchMail(r io.Reader) (io.Reader, error) {
+	var b bytes.Buffer
+
+	// inDiff is true while the current line belongs to a file diff.
+	var inDiff bool
+
+	// inMail is true once a mail header is found, after which only "diff"
+	// lines start file diffs, so that commit messages are never taken for
+	// diffs.
+	var inMail bool
+
+	// oldLines and newLines are the remaining line counts of the current hunk.
+	var oldLines, newLines int
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxPatchLineSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case oldLines > 0 || newLines > 0:
+			oldLines, newLines = consumeHunkLine(line, oldLines, newLines)
+
+		case strings.HasPrefix(line, "diff "):
+			inDiff = true
+
+		case line == "-- " || strings.HasPrefix(line, "From "):
+			// The signature or the start of the next commit. The commit hash
+			// is kept so that the diff can be split by commit.
+			inDiff = false
+			if m := commitHeaderPattern.FindStringSubmatch(line); m != nil {
+				inMail = true
+				b.WriteString("commit " + m[1] + "\n")
+			}
+
+		case !inDiff && !inMail && strings.HasPrefix(line, "--- "):
+			// A diff without a "diff" header line.
+			inDiff = true
+
+		case inDiff:
+			if m := hunkHeaderPattern.FindStringSubmatch(line); m != nil {
+				oldLines = hunkLineCount(m[1])
+				newLines = hunkLineCount(m[2])
+			}
+		}
+
+		if inDiff {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// consumeHunkLine consumes a line of the body of a hunk and returns the
+// remaining old and new line counts.
+func consumeHunkLine(line string, oldLines, newLines int) (int, int) {
+	switch {
+	case strings.HasPrefix(line, "-"):
+		oldLines--
+	case strings.HasPrefix(line, "+"):
+		newLines--
+	case strings.HasPrefix(line, `\`):
+		// "\ No newline at end of file" does not count.
+	default:
+		oldLines--
+		newLines--
+	}
+
+	return oldLines, newLines
+}
+
+// hunkLineCount parses a line count captured from a hunk header. An omitted
+// count means one line.
+func hunkLineCount(s string) int {
+	if s == "" {
+		return 1
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
@@ -0,0 +1,76 @@
+package difflint
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStripPatchMail(t *testing.T) {
+	f, err := os.Open("testdata/two-commits.patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	r, err := StripPatchMail(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stripped, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mail := range []string{"From:", "Subject:", "Bump X", "not a diff line", "nor this one", "a.go | 2 +-", "2.39.5"} {
+		if strings.Contains(string(stripped), mail) {
+			t.Errorf("StripPatchMail() = %q, which keeps %q of the mail", stripped, mail)
+		}
+	}
+
+	hunks, err := ParseHunks(strings.NewReader(string(stripped)), nil, nil, nil, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var files []string
+	for _, hunk := range hunks {
+		files = append(files, hunk.File)
+	}
+
+	if want := []string{"a.go", "b.go"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("ParseHunks() files = %v, want %v of both commits", files, want)
+	}
+
+	segments, err := SplitDiffs(strings.NewReader(string(stripped)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(segments) != 2 || segments[0].Commit != "e644794836c23a1ea4067950cca48b7d6745e47b" || segments[1].Commit != "e5bd962b9362ae2314330b9fd4b79ab5ef54b5b9" {
+		t.Fatalf("SplitDiffs() = %d segments, want one per commit", len(segments))
+	}
+
+	if !strings.Contains(string(segments[0].Diff), "a/a.go") || strings.Contains(string(segments[0].Diff), "a/b.go") {
+		t.Errorf("SplitDiffs() first segment = %q, want only the diff of a.go", segments[0].Diff)
+	}
+}
+
+func TestStripPatchMailPlainDiff(t *testing.T) {
+	for _, diff := range []string{
+		"diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,1 +1,1 @@\n-var X = 1\n+var X = 2\n",
+		"--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,2 @@\n--- old\n+++ new\n",
+	} {
+		r, err := StripPatchMail(strings.NewReader(diff))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, _ := io.ReadAll(r); string(got) != diff {
+			t.Errorf("StripPatchMail(%q) = %q, want it unchanged", diff, got)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package difflint
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLintStrictPresence(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.py": "#LINT.IF ./foo.py:bar\nx = 1\n#LINT.END\n",
+		"foo.py":  "#LINT.IF\ny = 1\n#LINT.END bar\n",
+	})
+
+	const (
+		mainDiff = "diff --git a/main.py b/main.py\n--- a/main.py\n+++ b/main.py\n@@ -2,1 +2,1 @@\n-x = 0\n+x = 1\n"
+		fooDiff  = "diff --git a/foo.py b/foo.py\n--- a/foo.py\n+++ b/foo.py\n@@ -2,1 +2,1 @@\n-y = 0\n+y = 1\n"
+	)
+
+	// The cases are the examples of the README.
+	tests := []struct {
+		name       string
+		diff       string
+		want       bool
+		wantStrict bool
+	}{
+		{name: "both", diff: mainDiff + fooDiff},
+		{name: "target only", diff: fooDiff, want: true, wantStrict: true},
+		{name: "block only", diff: mainDiff, wantStrict: true},
+	}
+
+	for _, test := range tests {
+		for _, strict := range []bool{false, true} {
+			result, err := Lint(context.Background(), LintOptions{
+				Root:           root,
+				Reader:         strings.NewReader(test.diff),
+				Templates:      DefaultTemplates,
+				FileExtMap:     DefaultFileExtMap,
+				StrictPresence: strict,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got bool
+			for _, rule := range result.UnsatisfiedRules {
+				got = got || rule.Hunk.File == "main.py"
+			}
+
+			want := test.want
+			if strict {
+				want = test.wantStrict
+			}
+
+			if got != want {
+				t.Errorf("%s with StrictPresence %t: main.py unsatisfied = %t, want %t", test.name, strict, got, want)
+			}
+		}
+	}
+}
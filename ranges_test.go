@@ -0,0 +1,70 @@
+package difflint
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestMergeRanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges []Range
+		want   []Range
+	}{
+		{name: "none"},
+		{name: "disjoint", ranges: []Range{{7, 9}, {1, 3}}, want: []Range{{1, 3}, {7, 9}}},
+		{name: "touching", ranges: []Range{{4, 6}, {1, 3}}, want: []Range{{1, 6}}},
+		{name: "overlapping", ranges: []Range{{1, 5}, {3, 8}}, want: []Range{{1, 8}}},
+		{name: "contained", ranges: []Range{{1, 9}, {3, 4}}, want: []Range{{1, 9}}},
+		{name: "empty between lines", ranges: []Range{{5, 4}, {9, 9}}, want: []Range{{5, 4}, {9, 9}}},
+		{name: "empty after range", ranges: []Range{{1, 3}, {4, 3}}, want: []Range{{1, 3}}},
+		{name: "empty before range", ranges: []Range{{5, 4}, {5, 7}}, want: []Range{{5, 7}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := MergeRanges(test.ranges); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("MergeRanges(%v) = %v, want %v", test.ranges, got, test.want)
+			}
+		})
+	}
+}
+
+// TestMergeRangesIntersects checks over random ranges, including the empty
+// ranges of deleted lines, that a rule's range intersects the merged ranges
+// exactly when it intersects one of the unmerged ranges. Like the ranges of
+// rules, the checked ranges are never empty.
+func TestMergeRangesIntersects(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomRange := func(minLen int) Range {
+		start := 1 + rng.Intn(60)
+		return Range{Start: start, End: start + minLen - 1 + rng.Intn(6)}
+	}
+
+	for i := 0; i < 2000; i++ {
+		ranges := make(Ranges, rng.Intn(12))
+		for j := range ranges {
+			ranges[j] = randomRange(0)
+		}
+
+		merged := ranges.Merge()
+		for j := 1; j < len(merged); j++ {
+			if merged[j-1].End >= merged[j].Start-1 || merged[j-1].End > merged[j].End {
+				t.Fatalf("Merge(%v) = %v, which is not sorted and disjoint", ranges, merged)
+			}
+		}
+
+		for j := 0; j < 20; j++ {
+			rule := randomRange(1)
+			var want bool
+			for _, r := range ranges {
+				want = want || Intersects(rule, r)
+			}
+
+			if got := merged.AnyIntersects(rule); got != want {
+				t.Fatalf("Merge(%v).AnyIntersects(%v) = %t, want %t as for the unmerged ranges", ranges, rule, got, want)
+			}
+		}
+	}
+}
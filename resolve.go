@@ -0,0 +1,66 @@
+package difflint
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TargetResolver resolves a custom target of a rule in the given file to
+// the keys, e.g. "gen/user.pb.go" or "api.go:users", of the files or blocks
+// it stands for. The target is satisfied if any of them changed. A resolver
+// returns false if the target is not one it handles, in which case the next
+// resolver or the default path logic is used.
+type TargetResolver func(ruleFile string, target Target) (keys []string, ok bool, err error)
+
+// resolveTarget returns the keys of the given target according to the
+// first resolver that handles it, normalized relative to the rule's file.
+func (o *LintOptions) resolveTarget(ruleFile string, target Target) ([]string, bool, error) {
+	for _, resolve := range o.TargetResolvers {
+		keys, ok, err := resolve(ruleFile, target)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		resolved := make([]string, len(keys))
+		for i, key := range keys {
+			file, id, hasID := strings.Cut(key, ":")
+			candidate := Target{File: &file}
+			if hasID {
+				candidate.ID = &id
+			}
+
+			resolved[i] = TargetKey(ruleFile, candidate)
+		}
+
+		return resolved, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// EnvTargetResolver returns a resolver of targets that start with an
+// environment variable, e.g. "$SRCROOT/api/schema.go", which it expands
+// with lookup, e.g. os.LookupEnv. The expanded path is relative to the
+// root. Undefined variables are an error.
+func EnvTargetResolver(lookup func(name string) (string, bool)) TargetResolver {
+	return func(ruleFile string, target Target) ([]string, bool, error) {
+		if !strings.HasPrefix(target.Raw, "$") {
+			return nil, false, nil
+		}
+
+		name, rest, _ := strings.Cut(strings.TrimPrefix(target.Raw, "$"), "/")
+		name = strings.TrimSuffix(strings.TrimPrefix(name, "{"), "}")
+		value, ok := lookup(name)
+		if !ok {
+			return nil, false, errors.Errorf("undefined environment variable %q", name)
+		}
+
+		return []string{path.Join(value, rest)}, true, nil
+	}
+}
@@ -1,8 +1,17 @@
 package difflint
 
 import (
-	"log"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -15,13 +24,54 @@ type Target struct {
 
 	// ID is the ID of the range of code in which a diff hunk intersects.
 	ID *string
+
+	// Range is the range of lines of the file in which a diff hunk
+	// intersects, for files without IDs, e.g. "client.go#L1-L50".
+	Range *Range
+
+	// Macro is the target macro, e.g. "@tests", that expanded to the
+	// target, if any.
+	Macro string
+
+	// Raw is the target as written in the directive, after macro
+	// expansion, e.g. "proto:UserService".
+	Raw string
+}
+
+// Wildcard returns true if the target matches any block with an ID in its
+// file.
+func (t Target) Wildcard() bool {
+	return t.ID != nil && *t.ID == WildcardID
+}
+
+// checkedRange returns the range of lines whose change makes the rule
+// present: its whole block, or only its body if exclusive is set. It
+// returns false if the range has no lines.
+func (r Rule) checkedRange(exclusive bool) (Range, bool) {
+	if !exclusive {
+		return r.Hunk.Range, true
+	}
+
+	return r.Body, r.Body.Start <= r.Body.End
 }
 
+// WildcardID is the target ID that matches any block with an ID in the
+// target file, e.g. "handlers.go:*".
+const WildcardID = "*"
+
 // A rule says that file or range of code must be present in the diff if another range is present.
 type Rule struct {
 	// Hunk is the diff hunk that must be present in the diff.
 	Hunk Hunk
 
+	// Body is the range of lines guarded by the rule without its directive
+	// lines, which ends before it starts if the block is empty.
+	Body Range
+
+	// Blank is true if the body of an IF or THEN block has no lines other
+	// than blank ones.
+	Blank bool
+
 	// Targets are the files or ranges of code that must be present in the diff if the hunk is present.
 	Targets []Target
 
@@ -30,71 +80,553 @@ type Rule struct {
 
 	// ID is an optional, unique identifier for the rule.
 	ID *string
+
+	// Severity is the severity of the rule when it is not satisfied.
+	Severity Severity
+
+	// AllowSelfTarget silences the warning about targets that reference the
+	// rule's own file or block.
+	AllowSelfTarget bool
+
+	// Tags categorize the rule so that subsets of rules can be run.
+	Tags []string
+
+	// Owner is the team or person responsible for the rule, e.g.
+	// "@platform-team". difflint does not interpret it.
+	Owner string
+
+	// Expires is the time after which the rule is no longer checked, if any.
+	Expires *time.Time
+
+	// Note is the free text written after the arguments of the rule's
+	// directives, e.g. "// why: schema and client must move together".
+	Note string
+
+	// Config is true if the rule was declared in a rules file rather than
+	// by directives. Its hunk is the file name or glob pattern of the files
+	// that must change, with no line range.
+	Config bool
+}
+
+// Empty returns true if the rule's IF or THEN block guards no lines, or
+// only blank lines if blank is set.
+func (r Rule) Empty(blank bool) bool {
+	if blank {
+		return r.Blank
+	}
+
+	return r.Body.End < r.Body.Start
+}
+
+// Expired returns true if the rule has an expiry at or before the given time.
+func (r Rule) Expired(now time.Time) bool {
+	return r.Expires != nil && !now.Before(*r.Expires)
+}
+
+// SelfTargets returns the indices of the targets that reference the rule's
+// own file or its own block, which is almost certainly a mistake.
+func (r Rule) SelfTargets() []int {
+	var indices []int
+	for i, target := range r.Targets {
+		key := TargetKey(r.Hunk.File, target)
+		if key == TargetKey(r.Hunk.File, Target{}) {
+			indices = append(indices, i)
+			continue
+		}
+
+		if r.ID != nil && key == TargetKey(r.Hunk.File, Target{ID: r.ID}) {
+			indices = append(indices, i)
+		}
+	}
+
+	return indices
+}
+
+// Severity represents how serious it is for a rule to be unsatisfied.
+type Severity string
+
+const (
+	// SeverityInfo is for rules that are informational only.
+	SeverityInfo Severity = "info"
+
+	// SeverityWarn is for rules that are gentle reminders.
+	SeverityWarn Severity = "warn"
+
+	// SeverityError is for rules that are hard requirements. It is the default.
+	SeverityError Severity = "error"
+)
+
+// ParseSeverity parses the given string and returns the severity.
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(s)
+	switch severity {
+	case SeverityInfo, SeverityWarn, SeverityError:
+		return severity, nil
+	default:
+		return "", errors.Errorf("unknown severity %q, expected %q, %q, or %q", s, SeverityInfo, SeverityWarn, SeverityError)
+	}
+}
+
+// rank returns the rank of the severity, with higher ranks being more severe.
+func (s Severity) rank() int {
+	switch s {
+	case SeverityInfo:
+		return 1
+	case SeverityWarn:
+		return 2
+	case SeverityError:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// AtLeast returns true if the severity is at least as severe as t.
+func (s Severity) AtLeast(t Severity) bool {
+	return s.rank() >= t.rank()
+}
+
+// RulesMap is the result of parsing rules from the files of a diff.
+type RulesMap struct {
+	// Rules is the map of rules by file name.
+	Rules map[string][]Rule
+
+	// PresentTargets is the set of all the target keys that are present.
+	PresentTargets map[string]struct{}
+
+	// TargetSources maps each present target key to the hunks that made it
+	// present.
+	TargetSources map[string][]Hunk
+
+	// Warnings is the list of warnings found while lexing.
+	Warnings []Warning
+
+	// FilesScanned is the number of files that were walked.
+	FilesScanned int
+}
+
+// isBareTarget returns true if the given target file name is neither
+// absolute nor explicitly relative, e.g. "other.go" or "dir/other.go".
+func isBareTarget(file string) bool {
+	return file != "" && !strings.HasPrefix(file, "/") && !isRelativeToCurrentDirectory(filepath.ToSlash(file))
+}
+
+// fileExists returns true if the given file exists in fsys.
+func fileExists(fsys fs.FS, name string) bool {
+	_, err := fs.Stat(fsys, name)
+	return err == nil
+}
+
+// ParseFileRules parses the rules of the file at the given path from its
+// content, selecting the directive templates by the file's extension. The
+// default templates are used if options has none. Rules are not compared
+// against any diff, so none of them is present.
+func ParseFileRules(path string, content io.Reader, options LintOptions) ([]Rule, error) {
+	if len(options.Templates) == 0 {
+		options.Templates, options.FileExtMap = DefaultTemplatesFor(options.directiveWord()), DefaultFileExtMap
+	}
+
+	templates, err := options.TemplatesFromFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse templates for file %s", path)
+	}
+
+	tokens, _, err := lex(content, lexOptions{
+		file:                    path,
+		templates:               templates,
+		strictDirectives:        options.StrictDirectives,
+		warnMismatchedTemplates: options.WarnMismatchedTemplates,
+		word:                    options.directiveWord(),
+		literals:                options.literalScanner(path),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to lex file %s", path)
+	}
+
+	rules, _, err := parseRules(path, tokens, nil, options.MacrosFromFile(path))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse rules for file %s", path)
+	}
+
+	return rules, nil
+}
+
+// lexedFile is the result of lexing a file.
+type lexedFile struct {
+	// tokens are the directives of the file.
+	tokens []token
+
+	// warnings are the problems found while lexing the file.
+	warnings []Warning
+
+	// mentions is true if the file mentions the directive word, e.g.
+	// "LINT.", whether or not its directives match its templates.
+	mentions bool
+}
+
+// lexFile reads and lexes the given file, consulting the cache by content
+// hash, and stores the result in the cache.
+func lexFile(fsys fs.FS, file string, info fs.FileInfo, templates []string, config string, cache *ruleCache, options LintOptions) (lexedFile, error) {
+	content, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return lexedFile{}, errors.Wrapf(err, "failed to read file %s", file)
+	}
+
+	hash := contentHash(content)
+	if lexed, ok := cache.lookupHash(file, info, hash, config); ok {
+		return lexed, nil
+	}
+
+	// UTF-16 files look binary, but may well have directives.
+	if warning, ok := utf16Warning(file, content); ok {
+		lexed := lexedFile{warnings: []Warning{warning}}
+		cache.store(file, info, hash, config, lexed)
+		return lexed, nil
+	}
+
+	// Binary files have no directives, even if their extension is allowed.
+	if isBinary(content) {
+		loggerOrNop(options.Logger).Printf("skipping binary file %s", file)
+		cache.store(file, info, hash, config, lexedFile{})
+		return lexedFile{}, nil
+	}
+
+	// Most files have no directives; skip lexing them.
+	mentions := bytes.Contains(content, []byte(options.directiveWord()+"."))
+	if !mayContainDirectives(content, templates, options.directiveWord(), options.WarnMismatchedTemplates) {
+		lexed := lexedFile{mentions: mentions}
+		cache.store(file, info, hash, config, lexed)
+		return lexed, nil
+	}
+
+	tokens, warnings, err := lex(bytes.NewReader(content), lexOptions{
+		file:                    file,
+		templates:               templates,
+		strictDirectives:        options.StrictDirectives,
+		warnMismatchedTemplates: options.WarnMismatchedTemplates,
+		word:                    options.directiveWord(),
+		literals:                options.literalScanner(file),
+	})
+	if err != nil {
+		return lexedFile{}, errors.Wrapf(err, "failed to lex file %s", file)
+	}
+
+	lexed := lexedFile{tokens: tokens, warnings: warnings, mentions: mentions}
+	cache.store(file, info, hash, config, lexed)
+	return lexed, nil
+}
+
+// foldTargetCase rewrites the file of each target that names a changed file
+// or a file with rules only when ignoring case to the case of that file, and
+// returns a warning for each, since the rule only works on case-insensitive
+// file systems.
+func foldTargetCase(rulesMap map[string][]Rule, hunksMap map[string][]Hunk) []Warning {
+	canonical := make(map[string]string, len(rulesMap)+len(hunksMap))
+	for file := range rulesMap {
+		canonical[strings.ToLower(file)] = file
+	}
+
+	// The case of the diff wins over that of the walk.
+	for file := range hunksMap {
+		canonical[strings.ToLower(file)] = file
+	}
+
+	files := make([]string, 0, len(rulesMap))
+	for file := range rulesMap {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var warnings []Warning
+	for _, file := range files {
+		for _, rule := range rulesMap[file] {
+			for _, target := range rule.Targets {
+				if target.File == nil || *target.File == "" || isGlob(*target.File) {
+					continue
+				}
+
+				key := TargetKey(file, Target{File: target.File})
+				actual, ok := canonical[strings.ToLower(key)]
+				if !ok || actual == key {
+					continue
+				}
+
+				warnings = append(warnings, Warning{
+					File:    file,
+					Line:    rule.Hunk.Range.Start,
+					Message: fmt.Sprintf("target %q matches %q only when ignoring case; fix its case so that the rule also works on case-sensitive file systems", *target.File, actual),
+				})
+				*target.File = actual
+			}
+		}
+	}
+
+	return warnings
 }
 
 // RulesMapFromHunks parses rules from the given hunks by file name and
-// returns the map of rules and the set of all the target keys that are present.
-func RulesMapFromHunks(hunks []Hunk, options LintOptions) (map[string][]Rule, map[string]struct{}, error) {
+// returns the map of rules along with the set of all the target keys that
+// are present.
+func RulesMapFromHunks(ctx context.Context, hunks []Hunk, options LintOptions) (*RulesMap, error) {
 	targetsMap := make(map[string]struct{}, len(hunks))
-	rangesMap := make(map[string][]Range, len(hunks))
+	sources := make(map[string][]Hunk, len(hunks))
+	hunksMap := make(map[string][]Hunk, len(hunks))
+	rangesMap := make(map[string]Ranges, len(hunks))
+	addedFiles := make(map[string]struct{})
 	for _, hunk := range hunks {
-		targetsMap[TargetKey(hunk.File, Target{})] = struct{}{}
-		if _, ok := rangesMap[hunk.File]; ok {
-			rangesMap[hunk.File] = append(rangesMap[hunk.File], hunk.Range)
-			continue
+		file := NormalizeKey(hunk.File)
+		key := TargetKey(file, Target{})
+		targetsMap[key] = struct{}{}
+		sources[key] = append(sources[key], hunk)
+		hunksMap[file] = append(hunksMap[file], hunk)
+		if hunk.Added {
+			addedFiles[file] = struct{}{}
+		}
+
+		// Changes of mode and binary files have no lines.
+		if !hunk.MetadataOnly {
+			rangesMap[file] = append(rangesMap[file], hunk.Range)
 		}
+	}
+
+	// Merge the overlapping hunks of files that appear in several
+	// concatenated diffs.
+	for file, ranges := range rangesMap {
+		rangesMap[file] = ranges.Merge()
+	}
+
+	root := options.Root
+	if root == "" {
+		root = "."
+	}
 
-		rangesMap[hunk.File] = []Range{hunk.Range}
+	fsys := options.FS
+	if fsys == nil {
+		fsys = os.DirFS(root)
 	}
 
+	// Only cache the rules of directories on disk.
+	var cache *ruleCache
+	if options.FS == nil {
+		cache = loadRuleCache(options.CacheDir, options.Root)
+	}
+
+	logger := loggerOrNop(options.Logger)
 	rulesMap := make(map[string][]Rule, len(hunks))
-	err := Walk(".", nil, nil, func(file string, info os.FileInfo, err error) error {
+	var warnings []Warning
+	var filesScanned int
+	unknownExts := make(map[string]int)
+	maxFileSize := options.MaxFileSize
+	if maxFileSize == 0 {
+		maxFileSize = DefaultMaxFileSize
+	}
+
+	hidden := options.hiddenPatterns()
+	walk := func(callback fs.WalkDirFunc) error {
+		return walkFS(ctx, fsys, nil, nil, hidden, callback)
+	}
+
+	if options.FollowSymlinks && options.FS == nil {
+		walk = func(callback fs.WalkDirFunc) error {
+			return walkFollow(ctx, root, nil, nil, hidden, callback)
+		}
+	}
+
+	changedFiles := make([]string, 0, len(hunksMap))
+	for file := range hunksMap {
+		changedFiles = append(changedFiles, file)
+	}
+	sort.Strings(changedFiles)
+
+	// With an index, only the changed files are parsed; the rules of the
+	// other files are read from the index after the walk.
+	if options.Index != nil {
+		var visible []string
+		for _, file := range changedFiles {
+			if !skipHidden(file, false, hidden) {
+				visible = append(visible, file)
+			}
+		}
+
+		walk = func(callback fs.WalkDirFunc) error {
+			return walkFiles(ctx, fsys, visible, callback)
+		}
+	}
+
+	err := walk(func(file string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		f, err := os.Open(file)
+		info, err := d.Info()
 		if err != nil {
-			return errors.Wrapf(err, "failed to open file %s", file)
+			return errors.Wrapf(err, "failed to stat file %s", file)
+		}
+
+		if maxFileSize > 0 && info.Size() > maxFileSize {
+			logger.Printf("skipping file %s: %d bytes exceeds the maximum file size", file, info.Size())
+			return nil
 		}
-		defer f.Close()
 
+		filesScanned++
 		templates, err := options.TemplatesFromFile(file)
 		if err != nil {
 			return errors.Wrapf(err, "failed to parse templates for file %s", file)
 		}
 
-		tokens, err := lex(f, lexOptions{file, templates})
-		if err != nil {
-			return errors.Wrapf(err, "failed to lex file %s", file)
+		config := cacheConfig(templates, options)
+		lexed, ok := cache.lookup(file, info, config)
+		if !ok {
+			lexed, err = lexFile(fsys, file, info, templates, config, cache, options)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Count the files that mention directives but have no templates of
+		// their own, whose directives are likely missed.
+		if ext := strings.TrimPrefix(path.Ext(file), "."); lexed.mentions && len(lexed.tokens) == 0 && !options.hasTemplates(ext) {
+			unknownExts[ext]++
+		}
+
+		tokens, lexWarnings := lexed.tokens, lexed.warnings
+		if len(tokens) == 0 && len(lexWarnings) == 0 {
+			return nil
 		}
 
-		rules, err := parseRules(file, tokens, rangesMap[file])
+		warnings = append(warnings, lexWarnings...)
+		if options.Index != nil && len(tokens) > 0 && !options.Index.ModTime.IsZero() && info.ModTime().After(options.Index.ModTime) {
+			warnings = append(warnings, Warning{
+				File:    file,
+				Line:    tokens[0].line,
+				Message: "file has directives and is newer than the index, which may be stale (regenerate it with difflint index)",
+			})
+		}
+
+		rules, parseWarnings, err := parseRules(file, tokens, rangesMap[file], options.MacrosFromFile(file))
 		if err != nil {
 			return errors.Wrapf(err, "failed to parse rules for file %s", file)
 		}
-		log.Printf("parsed %d rules for file %s", len(rules), file)
 
+		warnings = append(warnings, parseWarnings...)
+		logger.Printf("parsed %d rules for file %s", len(rules), file)
+
+		// Resolve bare target file names relative to the rule's directory,
+		// or warn about those that only exist there.
+		dir := path.Dir(file)
 		for _, rule := range rules {
-			if rule.Hunk.File != file {
-				continue
+			for _, target := range rule.Targets {
+				if target.File == nil || !isBareTarget(*target.File) {
+					continue
+				}
+
+				if options.RelativeTargets {
+					*target.File = "./" + *target.File
+					continue
+				}
+
+				if dir == "." || fileExists(fsys, *target.File) || !fileExists(fsys, path.Join(dir, *target.File)) {
+					continue
+				}
+
+				warnings = append(warnings, Warning{
+					File:    file,
+					Line:    rule.Hunk.Range.Start,
+					Message: fmt.Sprintf("target %q does not exist at the root but exists next to the rule; did you mean \"./%s\"?", *target.File, *target.File),
+				})
 			}
+		}
 
-			ranges, ok := rangesMap[file]
-			if !ok {
+		// Warn about rules that target themselves.
+		for _, rule := range rules {
+			if rule.AllowSelfTarget {
 				continue
 			}
 
-			for _, rng := range ranges {
-				if !Intersects(rule.Hunk.Range, rng) {
+			for _, i := range rule.SelfTargets() {
+				warnings = append(warnings, Warning{
+					File:    file,
+					Line:    rule.Hunk.Range.Start,
+					Message: fmt.Sprintf("target %q references the rule's own file or block (use allow-self=true to silence)", TargetKey(file, rule.Targets[i])),
+				})
+			}
+		}
+
+		// Warn about owned rules without IDs, which are hard to reference.
+		for _, rule := range rules {
+			if rule.Owner != "" && rule.ID == nil {
+				warnings = append(warnings, Warning{
+					File:    file,
+					Line:    rule.Hunk.Range.Start,
+					Message: fmt.Sprintf("rule has owner %q but no ID; give it an ID so that it can be referenced", rule.Owner),
+				})
+			}
+		}
+
+		// Warn about same-file targets, e.g. ":id", whose ID is not defined
+		// in the file.
+		ids := make(map[string]struct{}, len(rules))
+		for _, rule := range rules {
+			if rule.ID != nil {
+				ids[*rule.ID] = struct{}{}
+			}
+		}
+
+		for _, rule := range rules {
+			for _, target := range rule.Targets {
+				if target.ID == nil || target.Wildcard() || (target.File != nil && *target.File != "") {
 					continue
 				}
 
+				if _, ok := ids[*target.ID]; ok {
+					continue
+				}
+
+				warnings = append(warnings, Warning{
+					File:    file,
+					Line:    rule.Hunk.Range.Start,
+					Message: fmt.Sprintf("target %q references ID %q, which is not defined in this file", ":"+*target.ID, *target.ID),
+				})
+			}
+		}
+
+		// With exclusive markers, only changes between the directives of a
+		// block make it present.
+		if options.ExclusiveMarkers {
+			for i := range rules {
+				body, ok := rules[i].checkedRange(true)
+				rules[i].Present = ok && rangesMap[file].AnyIntersects(body)
+			}
+		}
+
+		// Every rule of a newly added file is present, and so are its IDs.
+		if _, ok := addedFiles[file]; ok {
+			for i := range rules {
+				rules[i].Present = true
+				key := TargetKey(file, Target{ID: rules[i].ID})
+				targetsMap[key] = struct{}{}
+				sources[key] = append(sources[key], hunksMap[file]...)
+			}
+		}
+
+		hunkIndex := newHunkIndex(hunksMap[file])
+		for _, rule := range rules {
+			if rule.Hunk.File != file {
+				continue
+			}
+
+			rng, ok := rule.checkedRange(options.ExclusiveMarkers)
+			if !ok {
+				continue
+			}
+
+			for _, hunk := range hunkIndex.intersecting(rng) {
 				key := TargetKey(file, Target{
 					File: &rule.Hunk.File,
 					ID:   rule.ID,
 				})
 				targetsMap[key] = struct{}{}
+				sources[key] = append(sources[key], hunk)
 			}
 		}
 
@@ -105,8 +637,167 @@ func RulesMapFromHunks(hunks []Hunk, options LintOptions) (map[string][]Rule, ma
 		return nil
 	})
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to walk files")
+		return nil, errors.Wrap(err, "failed to walk files")
+	}
+
+	exts := make([]string, 0, len(unknownExts))
+	for ext := range unknownExts {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+
+	for _, ext := range exts {
+		warnings = append(warnings, Warning{
+			Message: fmt.Sprintf("%d files with extension .%s contain %s directives but no template is configured; see --ext_map", unknownExts[ext], ext, options.directiveWord()),
+		})
+	}
+
+	if options.Index != nil {
+		for _, file := range options.Index.files() {
+			if _, ok := hunksMap[file]; ok {
+				continue
+			}
+
+			rulesMap[file] = options.Index.rules(file)
+		}
+	}
+
+	// Add the rules declared in the rules file.
+	for _, configRule := range options.ConfigRules {
+		for _, rule := range configRule.rules(changedFiles) {
+			rulesMap[rule.Hunk.File] = append(rulesMap[rule.Hunk.File], rule)
+		}
+	}
+
+	// Match the case of target paths to the files that they name.
+	if options.CaseInsensitivePaths {
+		warnings = append(warnings, foldTargetCase(rulesMap, hunksMap)...)
+	}
+
+	// Targets claimed by a resolver are present if any of their candidate
+	// keys is, and are left alone by the default logic below.
+	resolved := make(map[string]struct{})
+	for file, rules := range rulesMap {
+		for _, rule := range rules {
+			for _, target := range rule.Targets {
+				candidates, ok, err := options.resolveTarget(file, target)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to resolve target %q of rule %s:%d", target.Raw, file, rule.Hunk.Range.Start)
+				}
+
+				if !ok {
+					continue
+				}
+
+				key := TargetKey(file, target)
+				resolved[key] = struct{}{}
+				for _, candidate := range candidates {
+					if _, ok := targetsMap[candidate]; !ok {
+						continue
+					}
+
+					targetsMap[key] = struct{}{}
+					sources[key] = append(sources[key], sources[candidate]...)
+				}
+			}
+		}
+	}
+
+	// Wildcard targets are present if any block with an ID in their file
+	// is present.
+	for file, rules := range rulesMap {
+		for _, rule := range rules {
+			for _, target := range rule.Targets {
+				key := TargetKey(file, target)
+				if _, ok := targetsMap[key]; ok || !target.Wildcard() {
+					continue
+				}
+
+				if _, ok := resolved[key]; ok {
+					continue
+				}
+
+				targetFile := TargetKey(file, Target{File: target.File})
+				for _, block := range rulesMap[targetFile] {
+					if block.ID == nil {
+						continue
+					}
+
+					blockKey := TargetKey(targetFile, Target{ID: block.ID})
+					if _, ok := targetsMap[blockKey]; !ok {
+						continue
+					}
+
+					targetsMap[key] = struct{}{}
+					sources[key] = append(sources[key], sources[blockKey]...)
+				}
+			}
+		}
+	}
+
+	// Glob targets are present if any changed file matches them.
+	for file, rules := range rulesMap {
+		for _, rule := range rules {
+			for _, target := range rule.Targets {
+				key := TargetKey(file, target)
+				if _, ok := targetsMap[key]; ok || target.File == nil || !isGlob(*target.File) {
+					continue
+				}
+
+				if _, ok := resolved[key]; ok {
+					continue
+				}
+
+				for _, changedFile := range changedFiles {
+					if !matchTargetFile(key, changedFile) && !(options.CaseInsensitivePaths && matchTargetFile(strings.ToLower(key), strings.ToLower(changedFile))) {
+						continue
+					}
+
+					targetsMap[key] = struct{}{}
+					sources[key] = append(sources[key], hunksMap[changedFile]...)
+				}
+			}
+		}
+	}
+
+	// Line range targets are present if a hunk of their file intersects
+	// their range.
+	for file, rules := range rulesMap {
+		for _, rule := range rules {
+			for _, target := range rule.Targets {
+				key := TargetKey(file, target)
+				if _, ok := targetsMap[key]; ok || target.Range == nil {
+					continue
+				}
+
+				if _, ok := resolved[key]; ok {
+					continue
+				}
+
+				for _, hunk := range hunksMap[TargetKey(file, Target{File: target.File})] {
+					if !Intersects(*target.Range, hunk.Range) {
+						continue
+					}
+
+					targetsMap[key] = struct{}{}
+					sources[key] = append(sources[key], hunk)
+				}
+			}
+		}
+	}
+
+	if cache != nil {
+		logger.Printf("rule cache: %d hits, %d misses", cache.hits, cache.misses)
+		if err := cache.save(); err != nil {
+			logger.Printf("ignoring rule cache error: %v", err)
+		}
 	}
 
-	return rulesMap, targetsMap, nil
+	return &RulesMap{
+		Rules:          rulesMap,
+		PresentTargets: targetsMap,
+		TargetSources:  sources,
+		Warnings:       warnings,
+		FilesScanned:   filesScanned,
+	}, nil
 }
@@ -0,0 +1,72 @@
+package difflint
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLintSkipsLargeAndBinaryFiles(t *testing.T) {
+	const rule = "package a\n//LINT.IF t.go\nvar X = 1\n//LINT.END\n"
+	root := writeTree(t, map[string]string{
+		"ok.go":    rule,
+		"large.go": rule + "// " + strings.Repeat("x", 100) + "\n",
+		"bin.go":   rule + "\x00",
+		"t.go":     "package t\n",
+	})
+
+	// huge.go exceeds the default limit without taking up the disk.
+	huge := filepath.Join(root, "huge.go")
+	if err := os.WriteFile(huge, []byte(rule), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Truncate(huge, DefaultMaxFileSize+1); err != nil {
+		t.Fatal(err)
+	}
+
+	const diff = "diff --git a/t.go b/t.go\n--- a/t.go\n+++ b/t.go\n@@ -1,1 +1,1 @@\n-package a\n+package t\n"
+	tests := []struct {
+		name        string
+		maxFileSize int64
+		want        []string
+	}{
+		{name: "default", want: []string{"large.go", "ok.go"}},
+		{name: "custom", maxFileSize: int64(len(rule)) + 1, want: []string{"ok.go"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var logger recordingLogger
+			result, err := Lint(context.Background(), LintOptions{
+				Root:        root,
+				Reader:      strings.NewReader(diff),
+				Templates:   DefaultTemplates,
+				FileExtMap:  DefaultFileExtMap,
+				MaxFileSize: test.maxFileSize,
+				Logger:      &logger,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, rule := range result.UnsatisfiedRules {
+				got = append(got, rule.Hunk.File)
+			}
+			sort.Strings(got)
+
+			if strings.Join(got, " ") != strings.Join(test.want, " ") {
+				t.Errorf("Lint() unsatisfied rules of %v, want %v", got, test.want)
+			}
+
+			logged := strings.Join(logger.messages, "\n")
+			if !strings.Contains(logged, "skipping file huge.go: 10485761 bytes exceeds the maximum file size") || !strings.Contains(logged, "skipping binary file bin.go") {
+				t.Errorf("Lint() logged %q, want huge.go and bin.go skipped", logged)
+			}
+		})
+	}
+}
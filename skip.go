@@ -0,0 +1,113 @@
+package difflint
+
+import (
+	"bufio"
+	"path"
+	"strings"
+)
+
+// skipTrailer is the commit message trailer that lists rules to skip.
+const skipTrailer = "DIFFLINT-SKIP:"
+
+// IsSkipped returns true if the given rule is in the skip set. A rule is
+// skipped if the set contains its ID, its file, its "file:id" key, or a
+// glob pattern matching its ID, e.g. "sec-*".
+func IsSkipped(rule Rule, skip map[string]struct{}) bool {
+	if len(skip) == 0 {
+		return false
+	}
+
+	if _, ok := skip[rule.Hunk.File]; ok {
+		return true
+	}
+
+	if rule.ID == nil {
+		return false
+	}
+
+	if _, ok := skip[*rule.ID]; ok {
+		return true
+	}
+
+	if _, ok := skip[TargetKey(rule.Hunk.File, Target{ID: rule.ID})]; ok {
+		return true
+	}
+
+	for pattern := range skip {
+		if isGlob(pattern) && matchesID(*rule.ID, []string{pattern}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesID returns true if the given rule ID matches any of the given IDs
+// or glob patterns.
+func matchesID(id string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, id); ok && err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SelectsRule returns true if the given rule passes the rule filters: its
+// ID matches one of the only patterns, if any, and it has an ID if unnamed
+// rules are skipped.
+func SelectsRule(rule Rule, only []string, skipUnnamed bool) bool {
+	if rule.ID == nil {
+		return len(only) == 0 && !skipUnnamed
+	}
+
+	return len(only) == 0 || matchesID(*rule.ID, only)
+}
+
+// MatchesTags returns true if the given rule passes the tag filters: it has
+// at least one of the only tags, if any, and none of the skip tags.
+func MatchesTags(rule Rule, only, skip []string) bool {
+	for _, tag := range rule.Tags {
+		for _, s := range skip {
+			if tag == s {
+				return false
+			}
+		}
+	}
+
+	if len(only) == 0 {
+		return true
+	}
+
+	for _, tag := range rule.Tags {
+		for _, o := range only {
+			if tag == o {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ParseSkipTrailers returns the rules listed in the "DIFFLINT-SKIP: id1,id2"
+// trailers of the given commit message.
+func ParseSkipTrailers(message string) []string {
+	var skip []string
+	scanner := bufio.NewScanner(strings.NewReader(message))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, skipTrailer) {
+			continue
+		}
+
+		for _, id := range strings.Split(strings.TrimPrefix(line, skipTrailer), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				skip = append(skip, id)
+			}
+		}
+	}
+
+	return skip
+}
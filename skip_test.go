@@ -0,0 +1,96 @@
+package difflint
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSkipTrailers(t *testing.T) {
+	message := "Reformat with gofmt\n\nDIFFLINT-SKIP: api, docs/ci.md\nSigned-off-by: someone\n  DIFFLINT-SKIP: a.go:b,\n"
+	got := ParseSkipTrailers(message)
+	want := []string{"api", "docs/ci.md", "a.go:b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSkipTrailers() = %q, want %q", got, want)
+	}
+}
+
+func TestLintSkipRules(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":      "package a\n//LINT.IF target.go\nvar X = 1\n//LINT.END api\n",
+		"b.go":      "package b\n//LINT.IF target.go\nvar Y = 1\n//LINT.END\n",
+		"target.go": "package target\n",
+	})
+
+	const diff = "diff --git a/target.go b/target.go\n--- a/target.go\n+++ b/target.go\n@@ -1,1 +1,1 @@\n-package t\n+package target\n"
+	tests := []struct {
+		name string
+		skip []string
+		want []string
+	}{
+		{name: "none", want: []string{"a.go", "b.go"}},
+		{name: "by ID", skip: []string{"api"}, want: []string{"b.go"}},
+		{name: "by ID glob", skip: []string{"a*"}, want: []string{"b.go"}},
+		{name: "by file", skip: []string{"b.go"}, want: []string{"a.go"}},
+		{name: "by file and ID", skip: []string{"a.go:api"}, want: []string{"b.go"}},
+		{name: "by unknown ID", skip: []string{"other"}, want: []string{"a.go", "b.go"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Lint(context.Background(), LintOptions{
+				Root:       root,
+				Reader:     strings.NewReader(diff),
+				Templates:  DefaultTemplates,
+				FileExtMap: DefaultFileExtMap,
+				SkipRules:  test.skip,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, rule := range result.UnsatisfiedRules {
+				got = append(got, rule.Hunk.File)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Lint() unsatisfied rules of %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGitCommitsSkipTrailers(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := writeTree(t, map[string]string{"a.go": "package a\n"})
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "Reformat\n\nDIFFLINT-SKIP: api,b.go"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v: %s", args[0], err, out)
+		}
+	}
+
+	commits, err := GitCommits(context.Background(), dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(commits) != 1 || commits[0].Subject != "Reformat" {
+		t.Fatalf("GitCommits() = %+v, want one commit", commits)
+	}
+
+	if got, want := ParseSkipTrailers(commits[0].Message), []string{"api", "b.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSkipTrailers() = %q, want %q", got, want)
+	}
+}
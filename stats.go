@@ -0,0 +1,127 @@
+package difflint
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+)
+
+// maxTopTargets is the number of most targeted files listed in the stats.
+const maxTopTargets = 10
+
+// RuleStats summarizes the rules of a tree.
+type RuleStats struct {
+	// Rules is the number of rules.
+	Rules int `json:"rules"`
+
+	// Files is the number of files with rules.
+	Files int `json:"files"`
+
+	// WithIDs is the number of rules with an ID.
+	WithIDs int `json:"with_ids"`
+
+	// WithNotes is the number of rules with a note.
+	WithNotes int `json:"with_notes"`
+
+	// TargetsPerRule maps each number of targets to the number of rules with
+	// that many targets.
+	TargetsPerRule map[int]int `json:"targets_per_rule"`
+
+	// TopTargets are the most targeted files, most targeted first.
+	TopTargets []TargetCount `json:"top_targets"`
+
+	// DanglingRules is the number of rules with a target whose file or
+	// block no longer exists.
+	DanglingRules int `json:"dangling_rules"`
+}
+
+// TargetCount is the number of rules that target a file.
+type TargetCount struct {
+	// File is the targeted file.
+	File string `json:"file"`
+
+	// Rules is the number of rules that target the file.
+	Rules int `json:"rules"`
+}
+
+// ComputeRuleStats returns the stats of the given rules by file, checking
+// the existence of their targets in fsys.
+func ComputeRuleStats(rulesMap map[string][]Rule, fsys fs.FS) RuleStats {
+	stats := RuleStats{TargetsPerRule: make(map[int]int)}
+	counts := make(map[string]int)
+	for file, rules := range rulesMap {
+		stats.Files++
+		for _, rule := range rules {
+			stats.Rules++
+			stats.TargetsPerRule[len(rule.Targets)]++
+			if rule.ID != nil {
+				stats.WithIDs++
+			}
+
+			if rule.Note != "" {
+				stats.WithNotes++
+			}
+
+			targeted := make(map[string]struct{}, len(rule.Targets))
+			dangling := false
+			for _, target := range rule.Targets {
+				targetFile := TargetKey(file, Target{File: target.File})
+				targeted[targetFile] = struct{}{}
+				if !targetExists(rulesMap, fsys, targetFile, target) {
+					dangling = true
+				}
+			}
+
+			for targetFile := range targeted {
+				counts[targetFile]++
+			}
+
+			if dangling {
+				stats.DanglingRules++
+			}
+		}
+	}
+
+	for file, n := range counts {
+		stats.TopTargets = append(stats.TopTargets, TargetCount{File: file, Rules: n})
+	}
+
+	sort.Slice(stats.TopTargets, func(i, j int) bool {
+		a, b := stats.TopTargets[i], stats.TopTargets[j]
+		if a.Rules != b.Rules {
+			return a.Rules > b.Rules
+		}
+
+		return a.File < b.File
+	})
+
+	if len(stats.TopTargets) > maxTopTargets {
+		stats.TopTargets = stats.TopTargets[:maxTopTargets]
+	}
+
+	return stats
+}
+
+// targetExists returns true if the file of the given target exists in fsys
+// and so does its block, if it names one. Glob targets always exist.
+func targetExists(rulesMap map[string][]Rule, fsys fs.FS, file string, target Target) bool {
+	if isGlob(file) {
+		return true
+	}
+
+	if !fs.ValidPath(file) || !fileExists(fsys, path.Clean(file)) {
+		return false
+	}
+
+	if target.ID == nil {
+		return true
+	}
+
+	for _, rule := range rulesMap[file] {
+		if rule.ID != nil && (target.Wildcard() || *rule.ID == *target.ID) {
+			return true
+		}
+	}
+
+	return false
+}
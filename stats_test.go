@@ -0,0 +1,63 @@
+package difflint
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComputeRuleStats(t *testing.T) {
+	var many []string
+	files := map[string]string{
+		"api/schema.go": "package api\n\n//LINT.IF web/client.ts:user docs/api.md\ntype User struct{}\n//LINT.END schema // keep the client in sync\n",
+		"web/client.ts": "//LINT.IF api/schema.go:schema\ntype User = {}\n//LINT.END user\n",
+		"web/other.ts":  "//LINT.IF api/schema.go:missing\ntype Other = {}\n//LINT.END\n",
+		"config.go":     "package config\n\n//LINT.IF config.yaml gone.yaml\nvar Port = 8080\n//LINT.END\n",
+		"config.yaml":   "port: 8080\n",
+		"docs/api.md":   "# API\n",
+	}
+	for i := 0; i < 12; i++ {
+		target := fmt.Sprintf("t%02d.go", i)
+		files[target] = "package t\n"
+		many = append(many, target)
+	}
+	files["many.go"] = "package many\n\n//LINT.IF " + strings.Join(many, " ") + "\nvar X = 1\n//LINT.END\n"
+	root := writeTree(t, files)
+
+	rulesMap, err := RulesWith(context.Background(), DoOptions{LintOptions: LintOptions{Root: root}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := RuleStats{
+		Rules:          5,
+		Files:          5,
+		WithIDs:        2,
+		WithNotes:      1,
+		TargetsPerRule: map[int]int{1: 2, 2: 2, 12: 1},
+		TopTargets: []TargetCount{
+			{File: "api/schema.go", Rules: 2},
+			{File: "config.yaml", Rules: 1},
+			{File: "docs/api.md", Rules: 1},
+			{File: "gone.yaml", Rules: 1},
+			{File: "t00.go", Rules: 1},
+			{File: "t01.go", Rules: 1},
+			{File: "t02.go", Rules: 1},
+			{File: "t03.go", Rules: 1},
+			{File: "t04.go", Rules: 1},
+			{File: "t05.go", Rules: 1},
+		},
+		DanglingRules: 2,
+	}
+
+	// Rules are kept in maps, so compute the stats a few times to catch any
+	// dependence on iteration order.
+	for i := 0; i < 3; i++ {
+		if got := ComputeRuleStats(rulesMap.Rules, os.DirFS(root)); !reflect.DeepEqual(got, want) {
+			t.Fatalf("ComputeRuleStats() = %+v, want %+v", got, want)
+		}
+	}
+}
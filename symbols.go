@@ -0,0 +1,392 @@
+package difflint
+
+import (
+	"go/ast"
+	"go/parser"
+	gotoken "go/token"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// resolveSymbol returns the range of lines of the declaration of the given
+// symbol, e.g. "HandleLogin" or "Server.Handle", in the given file content.
+// It returns false if the symbol is not declared and an error if the file's
+// language is not supported.
+func resolveSymbol(file string, content []byte, symbol string) (Range, bool, error) {
+	switch strings.TrimPrefix(path.Ext(file), ".") {
+	case "go":
+		return resolveGoSymbol(file, content, symbol)
+	case "ts", "tsx", "js", "jsx", "mjs":
+		rng, ok := resolveScriptSymbol(string(content), symbol)
+		return rng, ok, nil
+	case "py":
+		rng, ok := resolveBlockSymbol(string(content), symbol, pythonBlocks)
+		return rng, ok, nil
+	case "rb":
+		rng, ok := resolveBlockSymbol(string(content), symbol, rubyBlocks)
+		return rng, ok, nil
+	default:
+		return Range{}, false, errors.Errorf("symbols of %s files cannot be resolved", path.Ext(file))
+	}
+}
+
+// resolveGoSymbol returns the range of the Go function, method, or type
+// declaration of the given symbol, including its doc comment. Methods are
+// named by their receiver's type, e.g. "Server.Handle".
+func resolveGoSymbol(file string, content []byte, symbol string) (Range, bool, error) {
+	fset := gotoken.NewFileSet()
+	f, err := parser.ParseFile(fset, file, content, parser.ParseComments)
+	if err != nil {
+		return Range{}, false, errors.Wrap(err, "failed to parse Go file")
+	}
+
+	rangeOf := func(doc *ast.CommentGroup, node ast.Node) Range {
+		start := node.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+
+		return Range{Start: fset.Position(start).Line, End: fset.Position(node.End()).Line}
+	}
+
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if goFuncName(decl) == symbol {
+				return rangeOf(decl.Doc, decl), true, nil
+			}
+
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				spec, ok := spec.(*ast.TypeSpec)
+				if !ok || spec.Name.Name != symbol {
+					continue
+				}
+
+				if len(decl.Specs) == 1 {
+					return rangeOf(decl.Doc, decl), true, nil
+				}
+
+				return rangeOf(spec.Doc, spec), true, nil
+			}
+		}
+	}
+
+	return Range{}, false, nil
+}
+
+// goFuncName returns the name of the given function, prefixed by the name
+// of its receiver's type if it is a method.
+func goFuncName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl.Name.Name
+	}
+
+	typ := decl.Recv.List[0].Type
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+			continue
+		case *ast.IndexExpr:
+			typ = t.X
+			continue
+		case *ast.IndexListExpr:
+			typ = t.X
+			continue
+		case *ast.Ident:
+			return t.Name + "." + decl.Name.Name
+		}
+
+		return decl.Name.Name
+	}
+}
+
+// scriptDeclaration matches the line of a top-level JavaScript or
+// TypeScript declaration, capturing its keyword and name.
+var scriptDeclaration = regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:declare\s+)?(?:abstract\s+)?(?:async\s+)?(function\*?|class|interface|enum|type|const|let|var)\s+([A-Za-z_$][\w$]*)`)
+
+// scriptMember matches the line of a class member declaration, capturing
+// its name and the character after it, "(" or "<" for a method.
+var scriptMember = regexp.MustCompile(`^\s*(?:(?:public|private|protected|static|readonly|async|override|get|set)\s+)*\*?([A-Za-z_$#][\w$]*)\s*([(<=:])`)
+
+// resolveScriptSymbol returns the range of the JavaScript or TypeScript
+// declaration of the given symbol: a function, class, interface, enum,
+// type, or variable, or a class member named by its class, e.g.
+// "Server.handle". The declaration ends at its closing brace or, if it has
+// no body, at its semicolon.
+func resolveScriptSymbol(content, symbol string) (Range, bool) {
+	lines := strings.Split(content, "\n")
+	name, member, isMember := strings.Cut(symbol, ".")
+	for i, line := range lines {
+		m := scriptDeclaration.FindStringSubmatch(line)
+		if m == nil || m[2] != name {
+			continue
+		}
+
+		bodyless := m[1] == "type" || m[1] == "const" || m[1] == "let" || m[1] == "var"
+		end := scriptDeclarationEnd(lines, i, bodyless)
+		if !isMember {
+			return Range{Start: i + 1, End: end + 1}, true
+		}
+
+		// Members are declared directly in the class body.
+		depth := braceDelta(line)
+		for j := i + 1; j < end; j++ {
+			if m := scriptMember.FindStringSubmatch(lines[j]); m != nil && m[1] == member && depth == 1 {
+				bodyless := m[2] == "=" || m[2] == ":"
+				return Range{Start: j + 1, End: scriptDeclarationEnd(lines, j, bodyless) + 1}, true
+			}
+
+			depth += braceDelta(lines[j])
+		}
+
+		return Range{}, false
+	}
+
+	return Range{}, false
+}
+
+// scriptDeclarationEnd returns the index of the line on which the
+// declaration that starts on line start ends: the line of the brace that
+// closes its body, or of the semicolon that ends it if it has no body.
+// Braces inside parentheses, such as those of parameter types, are not
+// bodies. A bodyless declaration, such as a variable, may also end at the
+// end of a line without a semicolon.
+func scriptDeclarationEnd(lines []string, start int, bodyless bool) int {
+	var braces, parens int
+	var opened bool
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		for j := 0; j < len(line); j++ {
+			switch c := line[j]; {
+			case strings.HasPrefix(line[j:], "//"):
+				j = len(line)
+			case c == '"' || c == '\'' || c == '`':
+				j = skipQuoted(line, j)
+			case c == '(':
+				parens++
+			case c == ')':
+				parens--
+			case c == '{' && parens == 0:
+				braces++
+				opened = true
+			case c == '}' && parens == 0:
+				braces--
+				if opened && braces == 0 {
+					return i
+				}
+			case c == ';' && braces == 0 && parens == 0:
+				return i
+			}
+		}
+
+		// A declaration without a body may end without a semicolon.
+		if bodyless && !opened && braces == 0 && parens == 0 && !continuesDeclaration(line) {
+			return i
+		}
+	}
+
+	return len(lines) - 1
+}
+
+// continuesDeclaration returns true if the given line of a declaration
+// without a body is empty or ends with an operator, so that the
+// declaration continues on the next line.
+func continuesDeclaration(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return true
+	}
+
+	for _, suffix := range []string{"=", "=>", ",", "|", "&", "?", ":", "+", "-", "."} {
+		if strings.HasSuffix(line, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// braceDelta returns the number of braces that the given line opens minus
+// the number it closes, ignoring strings and comments.
+func braceDelta(line string) int {
+	var delta int
+	for j := 0; j < len(line); j++ {
+		switch c := line[j]; {
+		case strings.HasPrefix(line[j:], "//"):
+			return delta
+		case c == '"' || c == '\'' || c == '`':
+			j = skipQuoted(line, j)
+		case c == '{':
+			delta++
+		case c == '}':
+			delta--
+		}
+	}
+
+	return delta
+}
+
+// blockLanguage describes the declarations of a language whose blocks are
+// delimited by indentation or keywords rather than braces.
+type blockLanguage struct {
+	// declaration matches the line of a declaration, capturing its
+	// indentation and name.
+	declaration *regexp.Regexp
+
+	// prefix matches the lines before a declaration that belong to it, such
+	// as decorators or doc comments.
+	prefix *regexp.Regexp
+
+	// end returns the index of the line on which the declaration that
+	// starts on line start ends.
+	end func(lines []string, start int) int
+}
+
+// pythonBlocks finds Python functions and classes, with their decorators
+// and the comments above them. Their blocks end before the first line that
+// is indented no deeper than the declaration.
+var pythonBlocks = blockLanguage{
+	declaration: regexp.MustCompile(`^(\s*)(?:async\s+def|def|class)\s+([A-Za-z_]\w*)`),
+	prefix:      regexp.MustCompile(`^\s*[@#]`),
+	end:         pythonDeclarationEnd,
+}
+
+// rubyBlocks finds Ruby methods, classes, and modules, with the comments
+// above them. Their blocks end at the end keyword indented like the
+// declaration.
+var rubyBlocks = blockLanguage{
+	declaration: regexp.MustCompile(`^(\s*)(?:def\s+(?:self\.)?|class\s+|module\s+)([A-Za-z_]\w*[?!]?)`),
+	prefix:      regexp.MustCompile(`^\s*#`),
+	end:         rubyDeclarationEnd,
+}
+
+// resolveBlockSymbol returns the range of the declaration of the given
+// symbol in the given content of a file of the given language. Members
+// are named by their class, e.g. "Server.handle", and must be declared
+// directly in its body.
+func resolveBlockSymbol(content, symbol string, lang blockLanguage) (Range, bool) {
+	lines := strings.Split(content, "\n")
+	lo, hi := 0, len(lines)
+	parentIndent := -1
+	var start, end int
+	for _, name := range strings.Split(symbol, ".") {
+		// The declarations directly in a body are the least indented ones.
+		bodyIndent := -1
+		for i := lo; i < hi; i++ {
+			m := lang.declaration.FindStringSubmatch(lines[i])
+			if m != nil && len(m[1]) > parentIndent && (bodyIndent < 0 || len(m[1]) < bodyIndent) {
+				bodyIndent = len(m[1])
+			}
+		}
+
+		found := false
+		for i := lo; i < hi; i++ {
+			m := lang.declaration.FindStringSubmatch(lines[i])
+			if m == nil || m[2] != name || len(m[1]) != bodyIndent {
+				continue
+			}
+
+			start, end, found = i, lang.end(lines, i), true
+			break
+		}
+
+		if !found {
+			return Range{}, false
+		}
+
+		lo, hi, parentIndent = start+1, end+1, bodyIndent
+	}
+
+	indent := leadingSpace(lines[start])
+	for start > 0 && lang.prefix.MatchString(lines[start-1]) && leadingSpace(lines[start-1]) == indent {
+		start--
+	}
+
+	return Range{Start: start + 1, End: end + 1}, true
+}
+
+// pythonDeclarationEnd returns the index of the last line of the Python
+// declaration that starts on line start: the last line before the first
+// statement that is indented no deeper than the declaration. Lines inside
+// brackets, such as those of a long signature, and inside triple-quoted
+// strings do not end it.
+func pythonDeclarationEnd(lines []string, start int) int {
+	indent := len(leadingSpace(lines[start]))
+	var literals pythonScanner
+	var depth int
+	end := start
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		inLiteral := literals.scan(line)
+		trimmed := strings.TrimSpace(line)
+		nested := i == start || inLiteral || depth > 0 || len(leadingSpace(line)) > indent
+		if !nested && trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+
+		if !inLiteral {
+			depth += pythonBracketDelta(line)
+		}
+
+		if nested && trimmed != "" {
+			end = i
+		}
+	}
+
+	return end
+}
+
+// pythonBracketDelta returns the number of brackets that the given line of
+// Python opens minus the number it closes, ignoring strings and comments.
+func pythonBracketDelta(line string) int {
+	var delta int
+	for j := 0; j < len(line); j++ {
+		switch c := line[j]; c {
+		case '#':
+			return delta
+		case '"', '\'':
+			j = skipQuoted(line, j)
+		case '(', '[', '{':
+			delta++
+		case ')', ']', '}':
+			delta--
+		}
+	}
+
+	return delta
+}
+
+// rubyOneLiner matches the line of a Ruby declaration that ends on the same
+// line, such as an endless method or one closed by end.
+var rubyOneLiner = regexp.MustCompile(`^\s*def\s+[\w.?!]+(?:\(.*\))?\s*=[^=~>]|\bend\s*$`)
+
+// rubyEnd matches the end keyword that closes a Ruby block.
+var rubyEnd = regexp.MustCompile(`^end\b`)
+
+// rubyDeclarationEnd returns the index of the line of the end keyword that
+// closes the Ruby declaration that starts on line start, which is indented
+// like the declaration.
+func rubyDeclarationEnd(lines []string, start int) int {
+	if rubyOneLiner.MatchString(lines[start]) {
+		return start
+	}
+
+	indent := leadingSpace(lines[start])
+	for i := start + 1; i < len(lines); i++ {
+		if leadingSpace(lines[i]) == indent && rubyEnd.MatchString(strings.TrimSpace(lines[i])) {
+			return i
+		}
+	}
+
+	return len(lines) - 1
+}
+
+// leadingSpace returns the indentation of the given line.
+func leadingSpace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
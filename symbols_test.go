@@ -0,0 +1,81 @@
+package difflint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveSymbol(t *testing.T) {
+	tests := []struct {
+		file   string
+		symbol string
+		want   Range
+		absent bool
+	}{
+		{file: "fixture.go", symbol: "HandleLogin", want: Range{3, 6}},
+		{file: "fixture.go", symbol: "Server", want: Range{8, 9}},
+		{file: "fixture.go", symbol: "Server.Handle", want: Range{11, 14}},
+		{file: "fixture.go", symbol: "Missing", absent: true},
+
+		{file: "fixture.ts", symbol: "handleLogin", want: Range{2, 7}},
+		{file: "fixture.ts", symbol: "Server", want: Range{9, 15}},
+		{file: "fixture.ts", symbol: "Server.handle", want: Range{12, 14}},
+		{file: "fixture.ts", symbol: "Handler", want: Range{17, 17}},
+		{file: "fixture.ts", symbol: "Server.missing", absent: true},
+
+		{file: "fixture.py", symbol: "handle_login", want: Range{4, 20}},
+		{file: "fixture.py", symbol: "Server", want: Range{23, 33}},
+		{file: "fixture.py", symbol: "Server.handle", want: Range{26, 30}},
+		{file: "fixture.py", symbol: "Server.close", want: Range{32, 33}},
+		{file: "fixture.py", symbol: "last", want: Range{37, 38}},
+		{file: "fixture.py", symbol: "handle", absent: true},
+		{file: "fixture.py", symbol: "Server.inner", absent: true},
+
+		{file: "fixture.rb", symbol: "handle_login", want: Range{1, 8}},
+		{file: "fixture.rb", symbol: "Auth", want: Range{10, 23}},
+		{file: "fixture.rb", symbol: "Auth.Server", want: Range{11, 22}},
+		{file: "fixture.rb", symbol: "Auth.Server.handle", want: Range{12, 17}},
+		{file: "fixture.rb", symbol: "Auth.Server.build", want: Range{19, 19}},
+		{file: "fixture.rb", symbol: "Auth.Server.close", want: Range{21, 21}},
+		{file: "fixture.rb", symbol: "valid?", want: Range{25, 27}},
+		{file: "fixture.rb", symbol: "Server", absent: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file+"/"+test.symbol, func(t *testing.T) {
+			content, err := os.ReadFile(filepath.Join("testdata", "symbols", test.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, ok, err := resolveSymbol(test.file, content, test.symbol)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ok == test.absent || got != test.want {
+				t.Errorf("resolveSymbol(%s, %q) = %v, %t, want %v, %t", test.file, test.symbol, got, ok, test.want, !test.absent)
+			}
+		})
+	}
+}
+
+func TestParseFileRulesUnresolvedFunc(t *testing.T) {
+	content := "package p\n\n//LINT.FUNC Missing docs.md\n\nfunc Present() {}\n"
+	rules, err := ParseFileRules("p.go", strings.NewReader(content), LintOptions{})
+	if err != nil || len(rules) != 0 {
+		t.Errorf("ParseFileRules() = %v, %v, want no rules and no error", rules, err)
+	}
+
+	_, err = ParseFileRules("p.go", strings.NewReader(content), LintOptions{StrictDirectives: true})
+	if err == nil || !strings.Contains(err.Error(), `symbol "Missing" of FUNC directive not found at p.go:3`) {
+		t.Errorf("ParseFileRules() with strict directives error = %v, want the unresolved symbol", err)
+	}
+
+	_, err = ParseFileRules("P.java", strings.NewReader("//LINT.FUNC Missing docs.md\n"), LintOptions{StrictDirectives: true})
+	if err == nil || !strings.Contains(err.Error(), "cannot be resolved") {
+		t.Errorf("ParseFileRules() of an unsupported language with strict directives error = %v, want the unsupported language", err)
+	}
+}
@@ -0,0 +1,144 @@
+package difflint
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// walkJob is a directory to walk and its real path on disk.
+type walkJob struct {
+	// start is the slash-separated path of the directory relative to the
+	// root of the walk.
+	start string
+
+	// real is the absolute path of the directory with symbolic links
+	// resolved.
+	real string
+}
+
+// WalkFollow walks the file tree rooted at root like WalkFS, but follows
+// symbolic links. Files are reported under the path of the link, never the
+// path they resolve to. Each real file and directory is visited at most once,
+// preferring its path without links, which also breaks symbolic link loops.
+func WalkFollow(ctx context.Context, root string, include []string, exclude []string, callback fs.WalkDirFunc) error {
+	return walkFollow(ctx, root, include, exclude, include, callback)
+}
+
+// walkFollow walks root like WalkFollow, but walks the hidden paths that
+// match the given allowHidden patterns instead of the include patterns.
+func walkFollow(ctx context.Context, root string, include, exclude, allowHidden []string, callback fs.WalkDirFunc) error {
+	realRoot, err := realPath(root)
+	if err != nil {
+		return err
+	}
+
+	fsys := os.DirFS(root)
+	visitedDirs := map[string]struct{}{realRoot: {}}
+	visitedFiles := make(map[string]struct{})
+
+	// visit reports a file unless its real path was already visited.
+	visit := func(pathname, real string, d fs.DirEntry) error {
+		if _, ok := visitedFiles[real]; ok {
+			return nil
+		}
+		visitedFiles[real] = struct{}{}
+
+		included, err := Include(pathname, include, exclude)
+		if err != nil {
+			return err
+		}
+
+		if included {
+			return callback(pathname, d, nil)
+		}
+
+		return nil
+	}
+
+	// Links are followed after the walk of each directory so that files are
+	// reported under their path without links when possible.
+	queue := []walkJob{{start: ".", real: realRoot}}
+	for len(queue) > 0 {
+		job := queue[0]
+		queue = queue[1:]
+
+		var links []string
+		err := fs.WalkDir(fsys, job.start, func(pathname string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			rel := pathname
+			if job.start != "." {
+				rel = path.Join(".", pathname[len(job.start):])
+			}
+			real := filepath.Join(job.real, filepath.FromSlash(rel))
+
+			switch {
+			case skipHidden(pathname, d.IsDir(), allowHidden) && d.IsDir():
+				return fs.SkipDir
+			case skipHidden(pathname, d.IsDir(), allowHidden):
+				return nil
+			case d.IsDir():
+				visitedDirs[real] = struct{}{}
+				return nil
+			case d.Type()&fs.ModeSymlink != 0:
+				links = append(links, pathname)
+				return nil
+			default:
+				return visit(pathname, real, d)
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, link := range links {
+			real, err := realPath(filepath.Join(root, filepath.FromSlash(link)))
+			if err != nil {
+				// Skip dangling links.
+				continue
+			}
+
+			info, err := os.Stat(real)
+			if err != nil {
+				continue
+			}
+
+			if !info.IsDir() {
+				if err := visit(link, real, fs.FileInfoToDirEntry(info)); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			if _, ok := visitedDirs[real]; ok {
+				continue
+			}
+
+			visitedDirs[real] = struct{}{}
+			queue = append(queue, walkJob{start: link, real: real})
+		}
+	}
+
+	return nil
+}
+
+// realPath returns the absolute path of the given path with symbolic links
+// resolved.
+func realPath(pathname string) (string, error) {
+	abs, err := filepath.Abs(pathname)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.EvalSymlinks(abs)
+}
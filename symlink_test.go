@@ -0,0 +1,122 @@
+package difflint
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// symlinkTree returns a tree with a rule in a.go and sub/b.go, an internal
+// link to a file and one to a directory, links to a directory and a file
+// outside the tree, and a link loop.
+func symlinkTree(t *testing.T) string {
+	t.Helper()
+	rule := "package p\n//LINT.IF target.go\nvar X = 1\n//LINT.END\n"
+	root := writeTree(t, map[string]string{
+		"a.go":      rule,
+		"sub/b.go":  rule,
+		"target.go": "package target\n",
+	})
+	outside := writeTree(t, map[string]string{"c.go": rule})
+
+	for link, target := range map[string]string{
+		"a_link.go":   "a.go",
+		"sub_link":    "sub",
+		"ext":         outside,
+		"ext_file.go": filepath.Join(outside, "c.go"),
+		"sub/loop":    "..",
+	} {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(link))); err != nil {
+			t.Skipf("symbolic links are not supported: %v", err)
+		}
+	}
+
+	return root
+}
+
+// walkedFiles returns the sorted files reported by the given walk.
+func walkedFiles(t *testing.T, walk func(fs.WalkDirFunc) error) []string {
+	t.Helper()
+	var files []string
+	err := walk(func(pathname string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		files = append(files, pathname)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(files)
+	return files
+}
+
+func TestWalkFSSkipsSymlinks(t *testing.T) {
+	root := symlinkTree(t)
+	got := walkedFiles(t, func(callback fs.WalkDirFunc) error {
+		return WalkFS(context.Background(), os.DirFS(root), nil, nil, callback)
+	})
+
+	if want := []string{"a.go", "sub/b.go", "target.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkFS() = %q, want %q", got, want)
+	}
+}
+
+func TestWalkFollow(t *testing.T) {
+	root := symlinkTree(t)
+	got := walkedFiles(t, func(callback fs.WalkDirFunc) error {
+		return WalkFollow(context.Background(), root, nil, nil, callback)
+	})
+
+	// Each real file is reported once, under its path without links when it
+	// has one. The file outside the tree is reported under the first link to
+	// it, and the loop back to the root adds nothing.
+	if want := []string{"a.go", "ext_file.go", "sub/b.go", "target.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkFollow() = %q, want %q", got, want)
+	}
+
+	for _, file := range got {
+		if filepath.IsAbs(file) || strings.HasPrefix(file, "..") {
+			t.Errorf("WalkFollow() reported %q outside the root", file)
+		}
+	}
+}
+
+func TestLintFollowSymlinks(t *testing.T) {
+	root := symlinkTree(t)
+	for _, follow := range []bool{false, true} {
+		result, err := Lint(context.Background(), LintOptions{
+			Root:           root,
+			Reader:         strings.NewReader("diff --git a/target.go b/target.go\n--- a/target.go\n+++ b/target.go\n@@ -1,1 +1,1 @@\n-package t\n+package target\n"),
+			Templates:      DefaultTemplates,
+			FileExtMap:     DefaultFileExtMap,
+			FollowSymlinks: follow,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, rule := range result.UnsatisfiedRules {
+			got = append(got, rule.Hunk.File)
+		}
+		sort.Strings(got)
+
+		want := []string{"a.go", "sub/b.go"}
+		if follow {
+			want = []string{"a.go", "ext_file.go", "sub/b.go"}
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Lint(FollowSymlinks: %v) unsatisfied rules of %q, want %q", follow, got, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package difflint
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Placeholders of templates that separate a directive from its arguments,
+// e.g. "<!--LINT.{directive} {args}-->".
+const (
+	directivePlaceholder = "{directive}"
+	argsPlaceholder      = "{args}"
+)
+
+// templateMatcher matches the directive lines of a template. It is compiled
+// once from the template so that lines are not matched by cutting the
+// template over and over.
+type templateMatcher struct {
+	// template is the template from which the matcher was compiled.
+	template string
+
+	// prefix is the text that starts a directive line.
+	prefix string
+
+	// separator is the text between the directive and its arguments in a
+	// template with placeholders.
+	separator string
+
+	// suffix is the text after the arguments.
+	suffix string
+
+	// placeholders is true if the template uses {directive} and {args}
+	// rather than "?". The suffix of such a template ends the directive, so
+	// that any content after it is ignored; the suffix of a "?" template
+	// must end the line.
+	placeholders bool
+}
+
+// compileTemplate returns the matcher of the given template, which is either
+// a "prefix?suffix" template or has {directive} followed by {args}.
+func compileTemplate(template string) (templateMatcher, error) {
+	d := strings.Index(template, directivePlaceholder)
+	a := strings.Index(template, argsPlaceholder)
+	switch {
+	case d < 0 && a < 0:
+		prefix, suffix, found := strings.Cut(template, "?")
+		if !found {
+			return templateMatcher{}, errors.Errorf("template %q is missing ? or {directive} and {args}", template)
+		}
+
+		return templateMatcher{template: template, prefix: prefix, suffix: suffix}, nil
+
+	case d < 0:
+		return templateMatcher{}, errors.Errorf("template %q is missing %s", template, directivePlaceholder)
+
+	case a < 0:
+		return templateMatcher{}, errors.Errorf("template %q is missing %s", template, argsPlaceholder)
+
+	case a < d:
+		return templateMatcher{}, errors.Errorf("template %q has %s before %s", template, argsPlaceholder, directivePlaceholder)
+	}
+
+	m := templateMatcher{
+		template:     template,
+		prefix:       template[:d],
+		separator:    template[d+len(directivePlaceholder) : a],
+		suffix:       template[a+len(argsPlaceholder):],
+		placeholders: true,
+	}
+
+	if m.separator == "" {
+		return templateMatcher{}, errors.Errorf("template %q has no separator between %s and %s", template, directivePlaceholder, argsPlaceholder)
+	}
+
+	if rest := m.separator + m.suffix; strings.Contains(rest, directivePlaceholder) || strings.Contains(rest, argsPlaceholder) {
+		return templateMatcher{}, errors.Errorf("template %q repeats a placeholder", template)
+	}
+
+	return m, nil
+}
+
+// compileTemplates returns the matchers of the given templates.
+func compileTemplates(templates []string) ([]templateMatcher, error) {
+	matchers := make([]templateMatcher, len(templates))
+	for i, template := range templates {
+		m, err := compileTemplate(template)
+		if err != nil {
+			return nil, err
+		}
+
+		matchers[i] = m
+	}
+
+	return matchers, nil
+}
+
+// length returns the length of the template without its placeholders, by
+// which the longest matching template is chosen.
+func (m templateMatcher) length() int {
+	return len(m.prefix) + len(m.separator) + len(m.suffix)
+}
+
+// match returns the directive and arguments of the given line, separated by
+// a space, if the line matches the template.
+func (m templateMatcher) match(line string) (string, bool) {
+	if !strings.HasPrefix(line, m.prefix) {
+		return "", false
+	}
+
+	rest := line[len(m.prefix):]
+	if !m.placeholders {
+		if len(rest) < len(m.suffix) || !strings.HasSuffix(rest, m.suffix) {
+			return "", false
+		}
+
+		return rest[:len(rest)-len(m.suffix)], true
+	}
+
+	if m.suffix != "" {
+		end := strings.Index(rest, m.suffix)
+		if end < 0 {
+			return "", false
+		}
+
+		rest = rest[:end]
+	}
+
+	// A directive without arguments may omit the separator.
+	directive, args, _ := strings.Cut(rest, m.separator)
+	return directive + " " + args, true
+}
@@ -0,0 +1,25 @@
+package fixture
+
+//LINT.IF target.md
+
+var Real = 1
+
+//LINT.END real
+
+var fixture = `
+//LINT.IF phantom.md
+var Phantom = 1
+//LINT.END phantom
+`
+
+var backtick = '`' // not a raw string
+var quoted = "` // not a raw string either"
+
+/* A block comment with a ` backtick.
+ */
+
+//LINT.IF target.md
+
+var Second = 2
+
+//LINT.END second
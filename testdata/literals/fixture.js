@@ -0,0 +1,15 @@
+//LINT.IF target.md
+const real = 1;
+//LINT.END real
+
+const fixture = `
+//LINT.IF phantom.md
+const phantom = \`escaped\`;
+//LINT.END phantom
+`;
+
+const url = "https://example.com/`"; // not a template literal
+
+//LINT.IF target.md
+const second = 2;
+//LINT.END second
@@ -0,0 +1,20 @@
+#LINT.IF target.md
+REAL = 1
+#LINT.END real
+
+FIXTURE = """
+#LINT.IF phantom.md
+PHANTOM = 1
+#LINT.END phantom
+"""
+
+QUOTES = '"""'  # a comment with ''' quotes
+
+RAW = r'''
+#LINT.IF phantom.md
+#LINT.END raw_phantom
+'''
+
+#LINT.IF target.md
+SECOND = 2
+#LINT.END second
@@ -0,0 +1,17 @@
+//LINT.IF target.md
+export const real: number = 1;
+//LINT.END real
+
+export function fixture(name: string): string {
+  return `
+//LINT.IF phantom.md
+${name}
+//LINT.END phantom
+`;
+}
+
+const quote: string = '`'; /* not a template literal */
+
+//LINT.IF target.md
+export const second: number = 2;
+//LINT.END second
@@ -0,0 +1,14 @@
+package fixture
+
+// HandleLogin logs the user in.
+func HandleLogin(user string) bool {
+	return user != ""
+}
+
+// Server serves requests.
+type Server struct{}
+
+// Handle handles a request.
+func (s *Server) Handle(request string) string {
+	return request
+}
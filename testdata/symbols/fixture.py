@@ -0,0 +1,38 @@
+import functools
+
+
+# Logs the user in.
+@functools.cache
+def handle_login(
+    user,
+    password,
+):
+    """Logs in.
+
+Dedented docstring line.
+    """
+    if user:
+        return {
+    "user": user,
+        }
+
+    # A comment in the body.
+    return None
+
+
+class Server:
+    name = "server"
+
+    def handle(self, request):
+        def handle(inner):
+            return inner
+
+        return handle(request)
+
+    async def close(self):
+        pass
+# A comment after the class.
+
+
+def last():
+    pass
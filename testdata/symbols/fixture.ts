@@ -0,0 +1,17 @@
+// Logs the user in.
+export async function handleLogin(user: { name: string }): Promise<boolean> {
+  if (user.name) {
+    return true;
+  }
+  return false;
+}
+
+export class Server {
+  private name = "server";
+
+  handle(request: string): string {
+    return `${request}}`;
+  }
+}
+
+export type Handler = (request: string) => string;
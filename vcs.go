@@ -0,0 +1,148 @@
+package difflint
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// VCS is the version control system flavor of a diff.
+type VCS string
+
+const (
+	// VCSAuto detects the flavor from the first "diff" header line.
+	VCSAuto VCS = "auto"
+
+	// VCSGit is the flavor of git diff, and of jj diff --git.
+	VCSGit VCS = "git"
+
+	// VCSHg is the flavor of hg diff, whose file headers carry timestamps.
+	VCSHg VCS = "hg"
+
+	// VCSJJ is the flavor of jj diff --git, which is the same as git's.
+	VCSJJ VCS = "jj"
+)
+
+// ParseVCS parses the given string and returns the VCS flavor.
+func ParseVCS(s string) (VCS, error) {
+	vcs := VCS(s)
+	switch vcs {
+	case VCSAuto, VCSGit, VCSHg, VCSJJ:
+		return vcs, nil
+	default:
+		return "", errors.Errorf("unknown vcs %q, expected %q, %q, %q, or %q", s, VCSAuto, VCSGit, VCSHg, VCSJJ)
+	}
+}
+
+// NormalizeDiff returns the diff read from r rewritten so that it can be
+// parsed as a git diff. The flavor is detected if vcs is VCSAuto or empty.
+// Mercurial file headers have their timestamps removed, and its "% "
+// property lines are dropped. The diff is rewritten as it is read, so only
+// the lines before the first "diff" header line are buffered.
+func NormalizeDiff(r io.Reader, vcs VCS) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	var head []byte
+	if vcs == VCSAuto || vcs == "" {
+		var err error
+		vcs, head, err = detectVCS(br)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read diff")
+		}
+	}
+
+	r = io.MultiReader(bytes.NewReader(head), br)
+	if vcs != VCSHg {
+		return r, nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxPatchLineSize)
+	return &hgReader{scanner: scanner}, nil
+}
+
+// hgReader rewrites a Mercurial diff as a git diff line by line as it is
+// read.
+type hgReader struct {
+	// scanner reads the lines of the Mercurial diff.
+	scanner *bufio.Scanner
+
+	// pending is the rewritten text that has not been read yet.
+	pending []byte
+
+	// oldLines and newLines are the remaining line counts of the current
+	// hunk.
+	oldLines, newLines int
+}
+
+// Read implements io.Reader.
+func (h *hgReader) Read(p []byte) (int, error) {
+	for len(h.pending) == 0 {
+		if !h.scanner.Scan() {
+			if err := h.scanner.Err(); err != nil {
+				return 0, errors.Wrap(err, "failed to read diff")
+			}
+
+			return 0, io.EOF
+		}
+
+		if line, ok := h.rewrite(h.scanner.Text()); ok {
+			h.pending = append(append(h.pending[:0], line...), '\n')
+		}
+	}
+
+	n := copy(p, h.pending)
+	h.pending = h.pending[n:]
+	return n, nil
+}
+
+// rewrite returns the git form of the given line of a Mercurial diff, or
+// false if the line is dropped.
+func (h *hgReader) rewrite(line string) (string, bool) {
+	switch {
+	case h.oldLines > 0 || h.newLines > 0:
+		h.oldLines, h.newLines = consumeHunkLine(line, h.oldLines, h.newLines)
+
+	case strings.HasPrefix(line, "% "):
+		return "", false
+
+	case strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ "):
+		line, _, _ = strings.Cut(line, "\t")
+
+	default:
+		if m := hunkHeaderPattern.FindStringSubmatch(line); m != nil {
+			h.oldLines = hunkLineCount(m[1])
+			h.newLines = hunkLineCount(m[2])
+		}
+	}
+
+	return line, true
+}
+
+// detectVCS reads the lines of the given diff up to its first "diff" header
+// line and returns the flavor based on that line, defaulting to git, along
+// with the lines that were read.
+func detectVCS(r *bufio.Reader) (VCS, []byte, error) {
+	var head []byte
+	for {
+		line, err := r.ReadBytes('\n')
+		head = append(head, line...)
+		if bytes.HasPrefix(line, []byte("diff -r ")) {
+			return VCSHg, head, nil
+		}
+
+		if bytes.HasPrefix(line, []byte("diff ")) {
+			return VCSGit, head, nil
+		}
+
+		if err == io.EOF {
+			return VCSGit, head, nil
+		}
+
+		if err != nil {
+			return "", nil, err
+		}
+	}
+}
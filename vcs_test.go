@@ -0,0 +1,153 @@
+package difflint
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// hunkSummary is the file, range, and changed lines of a hunk.
+type hunkSummary struct {
+	File    string
+	Range   Range
+	Added   []string
+	Removed []string
+}
+
+// parseFixture normalizes and parses the diff of the given fixture.
+func parseFixture(t *testing.T, name string, vcs VCS) []hunkSummary {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	r, err := NormalizeDiff(f, vcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hunks, err := ParseHunks(r, nil, nil, nil, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []hunkSummary
+	for _, hunk := range hunks {
+		got = append(got, hunkSummary{File: hunk.File, Range: hunk.Range, Added: hunk.AddedLines, Removed: hunk.RemovedLines})
+	}
+
+	return got
+}
+
+func TestNormalizeDiffHg(t *testing.T) {
+	want := []hunkSummary{
+		{File: "src/a.go", Range: Range{Start: 1, End: 3}, Added: []string{"++ a line that starts with pluses"}, Removed: []string{"// LINT.IF b.go", "-- a line that starts with dashes"}},
+		{File: "src/new.go", Range: Range{Start: 1, End: 2}, Added: []string{"package src", "var Y = 2"}},
+	}
+
+	for _, vcs := range []VCS{VCSAuto, VCSHg} {
+		if got := parseFixture(t, "testdata/hg.diff", vcs); !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseHunks(NormalizeDiff(hg.diff, %q)) = %+v, want %+v", vcs, got, want)
+		}
+	}
+}
+
+func TestNormalizeDiffJJ(t *testing.T) {
+	got := parseFixture(t, "testdata/jj.diff", VCSAuto)
+	if len(got) != 2 {
+		t.Fatalf("ParseHunks(NormalizeDiff(jj.diff)) = %+v, want 2 hunks", got)
+	}
+
+	if want := (hunkSummary{File: "src/b.go", Range: Range{Start: 2, End: 3}, Added: []string{"var W = 4"}}); !reflect.DeepEqual(got[0], want) {
+		t.Errorf("ParseHunks(NormalizeDiff(jj.diff))[0] = %+v, want %+v", got[0], want)
+	}
+
+	if want := (hunkSummary{File: "old.go", Range: Range{Start: 1, End: 1}, Removed: []string{"package old"}}); !reflect.DeepEqual(got[1], want) {
+		t.Errorf("ParseHunks(NormalizeDiff(jj.diff))[1] = %+v, want %+v", got[1], want)
+	}
+
+	if forced := parseFixture(t, "testdata/jj.diff", VCSJJ); !reflect.DeepEqual(forced, got) {
+		t.Errorf("ParseHunks(NormalizeDiff(jj.diff, jj)) = %+v, want %+v", forced, got)
+	}
+}
+
+func TestDetectVCS(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want VCS
+	}{
+		{name: "hg", file: "testdata/hg.diff", want: VCSHg},
+		{name: "jj", file: "testdata/jj.diff", want: VCSGit},
+		{name: "git", file: "testdata/fetch.diff", want: VCSGit},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := os.ReadFile(test.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, _, err := detectVCS(bufio.NewReader(strings.NewReader(string(data))))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.want {
+				t.Errorf("detectVCS(%s) = %q, want %q", test.file, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLintHgDiff(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"src/a.go":   "package a\n++ a line that starts with pluses\nvar X = 1\n",
+		"src/new.go": "package src\nvar Y = 2\n",
+		"rule.go":    "package rule\n//LINT.IF src/new.go\nvar Z = 1\n//LINT.END\n",
+	})
+
+	f, err := os.Open("testdata/hg.diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	result, err := Lint(context.Background(), LintOptions{
+		Root:       root,
+		Reader:     f,
+		Templates:  DefaultTemplates,
+		FileExtMap: DefaultFileExtMap,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.UnsatisfiedRules) != 1 || result.UnsatisfiedRules[0].Hunk.File != "rule.go" {
+		t.Errorf("Lint() = %v, want the rule of rule.go to be unsatisfied by src/new.go", result.UnsatisfiedRules)
+	}
+}
+
+func TestNormalizeDiffForcedHg(t *testing.T) {
+	// Without a "diff -r" header, the flavor can only be forced.
+	const diff = "--- a/x.go\tThu Jan 01 00:00:00 1970 +0000\n+++ b/x.go\tFri Oct 16 00:00:00 2026 +0000\n@@ -1,1 +1,1 @@\n-a\n+b\n"
+	r, err := NormalizeDiff(strings.NewReader(diff), VCSHg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hunks, err := ParseHunks(r, nil, nil, nil, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hunks) != 1 || hunks[0].File != "x.go" {
+		t.Errorf("ParseHunks(NormalizeDiff(diff, hg)) = %+v, want one hunk of x.go", hunks)
+	}
+}